@@ -0,0 +1,37 @@
+package appinfo
+
+import (
+	"github.com/mikeschinkel/go-dt"
+)
+
+// PathVars returns the values of dt.BuiltinPathVars for ai, to render a
+// dt.PathTemplate: config, cache and home are the user's directories, tmp is
+// the temp directory and app is ai.AppSlug(). Add further variables to the
+// returned map before rendering.
+func PathVars(ai AppInfo) (vars dt.PathVars, err error) {
+	var config, cache, home dt.DirPath
+
+	config, err = dt.UserConfigDir()
+	if err != nil {
+		err = dt.NewErr(dt.ErrAccessingUserConfigDir, err)
+		goto end
+	}
+	cache, err = dt.UserCacheDir()
+	if err != nil {
+		err = dt.NewErr(dt.ErrAccessingUserCacheDir, err)
+		goto end
+	}
+	home, err = dt.UserHomeDir()
+	if err != nil {
+		goto end
+	}
+	vars = dt.PathVars{
+		"config": config,
+		"cache":  cache,
+		"home":   home,
+		"tmp":    dt.TempDir(),
+		"app":    ai.AppSlug(),
+	}
+end:
+	return vars, err
+}
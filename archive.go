@@ -0,0 +1,260 @@
+package dt
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ArchiveFormat identifies the container and compression of an archive file.
+type ArchiveFormat uint8
+
+const (
+	UnknownArchive ArchiveFormat = iota
+	TarArchive
+	TarGzArchive
+	TarZstArchive
+	ZipArchive
+)
+
+func (f ArchiveFormat) String() (s string) {
+	switch f {
+	case TarArchive:
+		s = "tar"
+	case TarGzArchive:
+		s = "tar.gz"
+	case TarZstArchive:
+		s = "tar.zst"
+	case ZipArchive:
+		s = "zip"
+	default:
+		s = "unknown"
+	}
+	return s
+}
+
+// ArchiveFormat returns the archive format implied by fp's extension, or
+// UnknownArchive.
+func (fp Filepath) ArchiveFormat() (f ArchiveFormat) {
+	name := strings.ToLower(string(fp.Base()))
+	switch {
+	case strings.HasSuffix(name, ".tar"):
+		f = TarArchive
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		f = TarGzArchive
+	case strings.HasSuffix(name, ".tar.zst"), strings.HasSuffix(name, ".tzst"):
+		f = TarZstArchive
+	case strings.HasSuffix(name, ".zip"):
+		f = ZipArchive
+	}
+	return f
+}
+
+// Decompressor returns a reader that decompresses r.
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+var (
+	decompressorsMu sync.RWMutex
+	decompressors   = map[ArchiveFormat]Decompressor{
+		TarGzArchive: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+	}
+)
+
+// RegisterDecompressor registers d for a compressed tar format. Gzip is built
+// in; formats that need a third-party codec, such as TarZstArchive, are
+// registered by importing a package like go-dt/dtzstd.
+func RegisterDecompressor(f ArchiveFormat, d Decompressor) {
+	decompressorsMu.Lock()
+	decompressors[f] = d
+	decompressorsMu.Unlock()
+}
+
+// decompress wraps r for reading the tar stream of format f.
+func (f ArchiveFormat) decompress(r io.Reader) (rc io.ReadCloser, err error) {
+	var d Decompressor
+	var ok bool
+
+	if f == TarArchive {
+		rc = io.NopCloser(r)
+		goto end
+	}
+	decompressorsMu.RLock()
+	d, ok = decompressors[f]
+	decompressorsMu.RUnlock()
+	if !ok {
+		err = NewErr(ErrUnsupportedArchiveFormat, "archive_format", f.String())
+		goto end
+	}
+	rc, err = d(r)
+end:
+	return rc, err
+}
+
+// maxSymlinkTargetSize bounds how much of a zip member is read as a symlink
+// target.
+const maxSymlinkTargetSize = 4096
+
+// archiveEntry is a format-independent view of one archive member.
+type archiveEntry struct {
+	Name     string
+	Mode     fs.FileMode
+	ModTime  time.Time
+	Size     int64
+	Linkname string
+	Hardlink bool
+
+	// open returns the contents of a regular file. For tar it is only valid
+	// until the next entry is visited.
+	open func() (io.ReadCloser, error)
+}
+
+// cleanArchiveName converts an archive member name into a clean, slash
+// separated name relative to the archive root. Names that are absolute or
+// climb out of the root fail with ErrArchiveEntryEscapes.
+func cleanArchiveName(raw string) (name string, err error) {
+	name = strings.ReplaceAll(raw, `\`, "/")
+	if path.IsAbs(name) || filepath.VolumeName(name) != "" {
+		err = NewErr(ErrArchiveEntryEscapes)
+		goto end
+	}
+	name = path.Clean(name)
+	if name == ".." || strings.HasPrefix(name, "../") {
+		err = NewErr(ErrArchiveEntryEscapes)
+	}
+end:
+	return name, err
+}
+
+// walkArchive calls fn for each member of the archive at fp. When format is
+// UnknownArchive it is taken from fp's extension.
+func (fp Filepath) walkArchive(format ArchiveFormat, fn func(*archiveEntry) error) (err error) {
+	var zr *zip.ReadCloser
+	var f *os.File
+	var rc io.ReadCloser
+
+	if format == UnknownArchive {
+		format = fp.ArchiveFormat()
+	}
+	switch format {
+	case ZipArchive:
+		zr, err = openZip(fp)
+		if err != nil {
+			goto end
+		}
+		defer CloseOrLog(zr)
+		err = walkZip(&zr.Reader, fn)
+	case TarArchive, TarGzArchive, TarZstArchive:
+		f, err = os.Open(string(fp))
+		if err != nil {
+			goto end
+		}
+		defer CloseOrLog(f)
+		rc, err = format.decompress(f)
+		if err != nil {
+			goto end
+		}
+		defer CloseOrLog(rc)
+		err = walkTar(rc, fn)
+	default:
+		err = NewErr(ErrUnsupportedArchiveFormat, "archive_format", format.String())
+	}
+end:
+	return err
+}
+
+// openZip opens the zip archive at fp. Member names are validated by the
+// callers, so zip.ErrInsecurePath is not treated as an error.
+func openZip(fp Filepath) (zr *zip.ReadCloser, err error) {
+	zr, err = zip.OpenReader(string(fp))
+	if errors.Is(err, zip.ErrInsecurePath) {
+		err = nil
+	}
+	return zr, err
+}
+
+func walkTar(r io.Reader, fn func(*archiveEntry) error) (err error) {
+	var hdr *tar.Header
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err = tr.Next()
+		if err == io.EOF {
+			err = nil
+			break
+		}
+		if err != nil {
+			break
+		}
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		e := &archiveEntry{
+			Name:     hdr.Name,
+			Mode:     hdr.FileInfo().Mode(),
+			ModTime:  hdr.ModTime,
+			Size:     hdr.Size,
+			Linkname: hdr.Linkname,
+			Hardlink: hdr.Typeflag == tar.TypeLink,
+			open: func() (io.ReadCloser, error) {
+				return io.NopCloser(tr), nil
+			},
+		}
+		err = fn(e)
+		if err != nil {
+			break
+		}
+	}
+	return err
+}
+
+func walkZip(zr *zip.Reader, fn func(*archiveEntry) error) (err error) {
+	for _, f := range zr.File {
+		e := &archiveEntry{
+			Name:    f.Name,
+			Mode:    f.Mode(),
+			ModTime: f.Modified,
+			Size:    int64(f.UncompressedSize64),
+			open:    f.Open,
+		}
+		if e.Mode&fs.ModeSymlink != 0 {
+			e.Linkname, err = readZipSymlink(f)
+			if err != nil {
+				err = WithErr(err, "archive_entry", f.Name)
+				break
+			}
+		}
+		err = fn(e)
+		if err != nil {
+			break
+		}
+	}
+	return err
+}
+
+// readZipSymlink returns the target of a zip symlink, which is stored as the
+// member's contents.
+func readZipSymlink(f *zip.File) (target string, err error) {
+	var rc io.ReadCloser
+	var data []byte
+
+	rc, err = f.Open()
+	if err != nil {
+		goto end
+	}
+	data, err = io.ReadAll(io.LimitReader(rc, maxSymlinkTargetSize))
+	err = CombineErrs([]error{err, rc.Close()})
+	target = string(data)
+end:
+	return target, err
+}
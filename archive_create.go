@@ -0,0 +1,370 @@
+package dt
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// DefaultArchiveModTime is the modification time stored for every member of a
+// created archive when ArchiveOptions.ModTime is zero. It is the earliest time
+// a zip archive can represent.
+var DefaultArchiveModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ArchiveOptions controls the creation of archives by DirPath.Archive and
+// ArchiveBuilder.
+type ArchiveOptions struct {
+	// Prefix is a directory that every member is placed under, such as
+	// "app-1.0".
+	Prefix PathSegments
+
+	// ModTime is stored as the modification time of every member. Zero means
+	// DefaultArchiveModTime.
+	ModTime time.Time
+
+	// Ignore lists path.Match patterns for members to leave out. A pattern
+	// matches a member's slash-separated name, before Prefix is applied, or
+	// its base name. An ignored directory is left out with its contents.
+	Ignore []string
+
+	// IgnoreFunc, when set, leaves out the members for which it returns true.
+	IgnoreFunc func(name string, isDir bool) bool
+}
+
+// ArchiveBuilder collects the members of an archive and writes them to a tar,
+// tar.gz or zip file.
+//
+// The output is reproducible: members are written sorted by name, missing
+// parent directories are added, and every member gets the same modification
+// time, uid and gid 0, no owner names, and a fixed mode of 0755 for
+// directories and executable files and 0644 for other files. Symlinks are
+// stored as symlinks; other special files are skipped.
+type ArchiveBuilder struct {
+	dest    Filepath
+	format  ArchiveFormat
+	opts    *ArchiveOptions
+	members map[string]archiveSource
+}
+
+// archiveSource is where the contents of an archive member come from. A nil
+// fsys marks a directory added to complete the tree.
+type archiveSource struct {
+	fsys fs.FS
+	name string
+}
+
+// NewArchiveBuilder returns an ArchiveBuilder that writes to fp. The format is
+// taken from fp's extension when format is UnknownArchive.
+func (fp Filepath) NewArchiveBuilder(format ArchiveFormat, opts *ArchiveOptions) *ArchiveBuilder {
+	if opts == nil {
+		opts = &ArchiveOptions{}
+	}
+	if format == UnknownArchive {
+		format = fp.ArchiveFormat()
+	}
+	return &ArchiveBuilder{
+		dest:    fp,
+		format:  format,
+		opts:    opts,
+		members: make(map[string]archiveSource),
+	}
+}
+
+// Ignored reports whether the member name is excluded by the Ignore patterns
+// or IgnoreFunc.
+func (ab *ArchiveBuilder) Ignored(name string, isDir bool) (ignored bool) {
+	var base = path.Base(name)
+
+	for _, pattern := range ab.opts.Ignore {
+		if ok, _ := path.Match(pattern, name); ok {
+			ignored = true
+			goto end
+		}
+		if ok, _ := path.Match(pattern, base); ok {
+			ignored = true
+			goto end
+		}
+	}
+	if ab.opts.IgnoreFunc != nil {
+		ignored = ab.opts.IgnoreFunc(name, isDir)
+	}
+end:
+	return ignored
+}
+
+// Add records src in fsys as the member name, a slash-separated path relative
+// to the archive root. Adding a name again replaces the earlier source.
+// Ignored names are not added.
+func (ab *ArchiveBuilder) Add(fsys fs.FS, src string, name string) (err error) {
+	var info fs.FileInfo
+
+	name, err = cleanArchiveName(name)
+	if err == nil && name == "." {
+		err = NewErr(ErrArchiveEntryEscapes)
+	}
+	if err != nil {
+		err = WithErr(err, "archive_entry", name)
+		goto end
+	}
+	info, err = fs.Lstat(fsys, src)
+	if err != nil {
+		goto end
+	}
+	if ab.Ignored(name, info.IsDir()) {
+		goto end
+	}
+	ab.members[name] = archiveSource{fsys: fsys, name: src}
+end:
+	return err
+}
+
+// Write writes the archive, replacing any existing file at the destination.
+// On failure the partial file is removed. Write may be called again after
+// further calls to Add.
+func (ab *ArchiveBuilder) Write() (err error) {
+	var f *os.File
+	var members map[string]archiveSource
+	var names []string
+
+	switch ab.format {
+	case TarArchive, TarGzArchive, ZipArchive:
+	default:
+		err = NewErr(ErrUnsupportedArchiveFormat, "archive_format", ab.format.String())
+		goto end
+	}
+	members, names = ab.layout()
+	f, err = os.Create(string(ab.dest))
+	if err != nil {
+		goto end
+	}
+	if ab.format == ZipArchive {
+		err = ab.writeZip(f, members, names)
+	} else {
+		err = ab.writeTar(f, members, names)
+	}
+	err = CombineErrs([]error{err, f.Close()})
+	if err != nil {
+		err = CombineErrs([]error{err, os.Remove(string(ab.dest))})
+	}
+end:
+	if err != nil {
+		err = NewErr(ErrFailedToCreateArchive, ab.dest.ErrKV(), err)
+	}
+	return err
+}
+
+// layout returns the members to write, keyed by their final names with the
+// prefix applied and any missing parent directories added, and the sorted
+// names.
+func (ab *ArchiveBuilder) layout() (members map[string]archiveSource, names []string) {
+	var prefix = path.Clean(filepath.ToSlash(string(ab.opts.Prefix)))
+
+	members = make(map[string]archiveSource, len(ab.members))
+	for name, src := range ab.members {
+		if prefix != "." {
+			name = path.Join(prefix, name)
+		}
+		members[name] = src
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if _, ok := members[dir]; !ok {
+				members[dir] = archiveSource{}
+			}
+		}
+	}
+	names = slices.Sorted(maps.Keys(members))
+	return members, names
+}
+
+// member describes the normalized header of name.
+func (ab *ArchiveBuilder) member(name string, src archiveSource) (info fs.FileInfo, mode fs.FileMode, link string, err error) {
+	if src.fsys == nil {
+		mode = fs.ModeDir | 0o755
+		goto end
+	}
+	info, err = fs.Lstat(src.fsys, src.name)
+	if err != nil {
+		goto end
+	}
+	switch {
+	case info.IsDir():
+		mode = fs.ModeDir | 0o755
+	case info.Mode()&fs.ModeSymlink != 0:
+		mode = fs.ModeSymlink | 0o777
+		link, err = fs.ReadLink(src.fsys, src.name)
+	case info.Mode().IsRegular() && info.Mode()&0o111 != 0:
+		mode = 0o755
+	case info.Mode().IsRegular():
+		mode = 0o644
+	default:
+		mode = info.Mode()
+	}
+end:
+	if err != nil {
+		err = WithErr(err, "archive_entry", name)
+	}
+	return info, mode, link, err
+}
+
+func (ab *ArchiveBuilder) modTime() time.Time {
+	if ab.opts.ModTime.IsZero() {
+		return DefaultArchiveModTime
+	}
+	return ab.opts.ModTime.UTC().Truncate(time.Second)
+}
+
+// copyMember copies the contents of the regular file name to w.
+func (ab *ArchiveBuilder) copyMember(w io.Writer, name string, src archiveSource) (err error) {
+	var f fs.File
+
+	f, err = src.fsys.Open(src.name)
+	if err != nil {
+		goto end
+	}
+	_, err = io.Copy(w, f)
+	err = CombineErrs([]error{err, f.Close()})
+end:
+	if err != nil {
+		err = WithErr(err, "archive_entry", name)
+	}
+	return err
+}
+
+func (ab *ArchiveBuilder) writeTar(w io.Writer, members map[string]archiveSource, names []string) (err error) {
+	var zw *gzip.Writer
+	var tw *tar.Writer
+	var info fs.FileInfo
+	var mode fs.FileMode
+	var link string
+
+	if ab.format == TarGzArchive {
+		// The gzip header carries no name or time, keeping output stable.
+		zw = gzip.NewWriter(w)
+		w = zw
+	}
+	tw = tar.NewWriter(w)
+	for _, name := range names {
+		info, mode, link, err = ab.member(name, members[name])
+		if err != nil {
+			goto end
+		}
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    int64(mode.Perm()),
+			ModTime: ab.modTime(),
+			Format:  tar.FormatPAX,
+		}
+		switch {
+		case mode.IsDir():
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+		case mode&fs.ModeSymlink != 0:
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = link
+		case mode.IsRegular():
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = info.Size()
+		default:
+			continue
+		}
+		err = tw.WriteHeader(hdr)
+		if err == nil && hdr.Typeflag == tar.TypeReg {
+			err = ab.copyMember(tw, name, members[name])
+		}
+		if err != nil {
+			goto end
+		}
+	}
+	err = tw.Close()
+	if zw != nil {
+		err = CombineErrs([]error{err, zw.Close()})
+	}
+end:
+	return err
+}
+
+func (ab *ArchiveBuilder) writeZip(w io.Writer, members map[string]archiveSource, names []string) (err error) {
+	var zw *zip.Writer
+	var fw io.Writer
+	var mode fs.FileMode
+	var link string
+
+	zw = zip.NewWriter(w)
+	for _, name := range names {
+		_, mode, link, err = ab.member(name, members[name])
+		if err != nil {
+			goto end
+		}
+		hdr := &zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: ab.modTime(),
+		}
+		hdr.SetMode(mode)
+		switch {
+		case mode.IsDir():
+			hdr.Name += "/"
+			hdr.Method = zip.Store
+		case mode&fs.ModeSymlink != 0, mode.IsRegular():
+		default:
+			continue
+		}
+		fw, err = zw.CreateHeader(hdr)
+		switch {
+		case err != nil:
+		case mode&fs.ModeSymlink != 0:
+			_, err = io.WriteString(fw, link)
+		case mode.IsRegular():
+			err = ab.copyMember(fw, name, members[name])
+		}
+		if err != nil {
+			goto end
+		}
+	}
+	err = zw.Close()
+end:
+	return err
+}
+
+// Archive writes the contents of dp to a reproducible tar, tar.gz or zip
+// archive at dest; see ArchiveBuilder for what is stored. The format is taken
+// from dest's extension when format is UnknownArchive. When dest is inside dp
+// it is left out of the archive.
+func (dp DirPath) Archive(dest Filepath, format ArchiveFormat, opts *ArchiveOptions) (err error) {
+	var ab *ArchiveBuilder
+	var fsys fs.FS
+	var self string
+
+	ab = dest.NewArchiveBuilder(format, opts)
+	fsys = dp.DirFS()
+	self, err = filepath.Rel(string(dp), string(dest))
+	if err != nil || !filepath.IsLocal(self) {
+		self, err = "", nil
+	}
+	self = filepath.ToSlash(self)
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+		case name == "." || name == self:
+		case ab.Ignored(name, d.IsDir()) && d.IsDir():
+			err = fs.SkipDir
+		default:
+			err = ab.Add(fsys, name, name)
+		}
+		return err
+	})
+	if err != nil {
+		err = NewErr(ErrFailedToCreateArchive, dest.ErrKV(), dp.ErrKV(), err)
+		goto end
+	}
+	err = ab.Write()
+end:
+	return err
+}
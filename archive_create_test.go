@@ -0,0 +1,130 @@
+package dt_test
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+func TestDirPath_Archive(t *testing.T) {
+	if err := dt.EnsureUserHomeDir(); err != nil {
+		t.Fatalf("EnsureUserHomeDir() error = %v", err)
+	}
+	src := dt.DirPath(t.TempDir())
+	files := map[string]os.FileMode{
+		"README.md":      0o600,
+		"bin/run":        0o700,
+		"docs/guide.md":  0o664,
+		"docs/draft.tmp": 0o644,
+		".git/HEAD":      0o644,
+	}
+	for name, mode := range files {
+		fp := dt.FilepathJoin(src, name)
+		if err := fp.Dir().MkdirAll(0o755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := fp.WriteFile([]byte(name), mode); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		if err := os.Chmod(string(fp), mode); err != nil {
+			t.Fatalf("Chmod() error = %v", err)
+		}
+	}
+	if runtime.GOOS != "windows" {
+		if err := os.Symlink("../README.md", string(dt.FilepathJoin(src, "docs/readme.md"))); err != nil {
+			t.Fatalf("Symlink() error = %v", err)
+		}
+	}
+	opts := &dt.ArchiveOptions{
+		Prefix: "app-1.0",
+		Ignore: []string{".git", "*.tmp"},
+	}
+
+	for _, format := range []dt.ArchiveFormat{dt.TarGzArchive, dt.ZipArchive} {
+		t.Run(format.String(), func(t *testing.T) {
+			out := dt.DirPath(t.TempDir())
+			first := dt.FilepathJoin(out, dt.Filename("first."+format.String()))
+			second := dt.FilepathJoin(out, dt.Filename("second."+format.String()))
+			if err := src.Archive(first, dt.UnknownArchive, opts); err != nil {
+				t.Fatalf("Archive() error = %v", err)
+			}
+			later := time.Now().Add(time.Hour)
+			if err := os.Chtimes(string(dt.FilepathJoin(src, "README.md")), later, later); err != nil {
+				t.Fatalf("Chtimes() error = %v", err)
+			}
+			if err := src.Archive(second, format, opts); err != nil {
+				t.Fatalf("Archive() error = %v", err)
+			}
+			a, _ := first.ReadFile()
+			b, _ := second.ReadFile()
+			if !bytes.Equal(a, b) {
+				t.Error("Archive() output differs between runs")
+			}
+
+			afs, err := first.OpenArchiveFS(nil)
+			if err != nil {
+				t.Fatalf("OpenArchiveFS() error = %v", err)
+			}
+			defer afs.Close()
+			want := map[string]fs.FileMode{
+				"app-1.0":               fs.ModeDir | 0o755,
+				"app-1.0/README.md":     0o644,
+				"app-1.0/bin":           fs.ModeDir | 0o755,
+				"app-1.0/bin/run":       0o755,
+				"app-1.0/docs/guide.md": 0o644,
+			}
+			if runtime.GOOS == "windows" {
+				delete(want, "app-1.0/bin/run")
+			} else {
+				want["app-1.0/docs/readme.md"] = fs.ModeSymlink | 0o777
+			}
+			for name, mode := range want {
+				info, err := afs.Lstat(name)
+				if err != nil {
+					t.Errorf("Lstat(%s) error = %v", name, err)
+					continue
+				}
+				if info.Mode() != mode {
+					t.Errorf("Lstat(%s) mode = %v, want %v", name, info.Mode(), mode)
+				}
+				if !info.ModTime().Equal(dt.DefaultArchiveModTime) {
+					t.Errorf("Lstat(%s) ModTime = %v, want %v", name, info.ModTime(), dt.DefaultArchiveModTime)
+				}
+			}
+			for _, name := range []string{"app-1.0/.git", "app-1.0/docs/draft.tmp"} {
+				if _, err := afs.Lstat(name); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("Lstat(%s) error = %v, want %v", name, err, fs.ErrNotExist)
+				}
+			}
+		})
+	}
+
+	t.Run("dest inside the directory", func(t *testing.T) {
+		dest := dt.FilepathJoin(src, "self.zip")
+		if err := src.Archive(dest, dt.UnknownArchive, nil); err != nil {
+			t.Fatalf("Archive() error = %v", err)
+		}
+		defer dest.Remove()
+		afs, err := dest.OpenArchiveFS(nil)
+		if err != nil {
+			t.Fatalf("OpenArchiveFS() error = %v", err)
+		}
+		defer afs.Close()
+		if _, err = afs.Stat("self.zip"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Stat(self.zip) error = %v, want %v", err, fs.ErrNotExist)
+		}
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		err := src.Archive(dt.FilepathJoin(t.TempDir(), "out.rar"), dt.UnknownArchive, nil)
+		if !errors.Is(err, dt.ErrUnsupportedArchiveFormat) {
+			t.Errorf("Archive() error = %v, want %v", err, dt.ErrUnsupportedArchiveFormat)
+		}
+	})
+}
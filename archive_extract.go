@@ -0,0 +1,301 @@
+package dt
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	// DefaultMaxExtractSize is the default limit on the total number of bytes
+	// ExtractTo will write.
+	DefaultMaxExtractSize int64 = 1 << 30
+
+	// DefaultMaxExtractEntries is the default limit on the number of archive
+	// members ExtractTo will process.
+	DefaultMaxExtractEntries = 100_000
+)
+
+// ExtractOptions controls Filepath.ExtractTo.
+type ExtractOptions struct {
+	// Format overrides detection of the archive format from the extension.
+	Format ArchiveFormat
+
+	// StripComponents removes this many leading path components from every
+	// member name, like tar --strip-components. Members with no components
+	// left are skipped.
+	StripComponents int
+
+	// MaxSize limits the total bytes written, guarding against decompression
+	// bombs. Zero means DefaultMaxExtractSize and a negative value disables
+	// the limit.
+	MaxSize int64
+
+	// MaxEntries limits the number of archive members processed. Zero means
+	// DefaultMaxExtractEntries and a negative value disables the limit.
+	MaxEntries int
+
+	// CopyOptions.Overwrite replaces existing files, and DestModeFunc, when
+	// set, chooses the mode of each extracted file and directory in place of
+	// the mode stored in the archive.
+	CopyOptions
+}
+
+// ExtractTo extracts the tar, tar.gz, tar.zst or zip archive at fp into dest,
+// creating dest if needed.
+//
+// All writes go through dest.OpenRoot, so members cannot escape dest by way
+// of "..", absolute names or symlinks; such members, and symlinks whose
+// targets would point outside dest, fail with ErrArchiveEntryEscapes. Device
+// files, FIFOs and other special members are skipped. Errors include the
+// offending member's name under the "archive_entry" key.
+func (fp Filepath) ExtractTo(dest DirPath, opts *ExtractOptions) (err error) {
+	var x *extractor
+
+	if opts == nil {
+		opts = &ExtractOptions{}
+	}
+	err = dest.MkdirAll(0o755)
+	if err != nil {
+		goto end
+	}
+	x = &extractor{dest: dest, opts: opts}
+	x.root, err = dest.OpenRoot()
+	if err != nil {
+		goto end
+	}
+	defer CloseOrLog(x.root)
+
+	err = fp.walkArchive(opts.Format, x.extract)
+	if err != nil {
+		goto end
+	}
+	err = x.finish()
+end:
+	if err != nil {
+		err = NewErr(ErrFailedToExtractArchive, fp.ErrKV(), dest.ErrKV(), err)
+	}
+	return err
+}
+
+// extractor holds the state of one ExtractTo call.
+type extractor struct {
+	root    *DirRoot
+	dest    DirPath
+	opts    *ExtractOptions
+	entries int
+	written int64
+
+	// dirs records directories and their archive modes, which are applied in
+	// finish so that read-only directories can still be filled.
+	dirs []extractedDir
+}
+
+type extractedDir struct {
+	name string
+	mode fs.FileMode
+}
+
+func (x *extractor) maxSize() int64 {
+	if x.opts.MaxSize == 0 {
+		return DefaultMaxExtractSize
+	}
+	return x.opts.MaxSize
+}
+
+func (x *extractor) maxEntries() int {
+	if x.opts.MaxEntries == 0 {
+		return DefaultMaxExtractEntries
+	}
+	return x.opts.MaxEntries
+}
+
+// entryName converts an archive member name into a name relative to the
+// root, applying StripComponents. It returns "" for members to skip.
+func (x *extractor) entryName(raw string) (name string, err error) {
+	var parts []string
+
+	name, err = cleanArchiveName(raw)
+	if err != nil || name == "." {
+		name = ""
+		goto end
+	}
+	parts = strings.Split(name, "/")
+	if len(parts) <= x.opts.StripComponents {
+		name = ""
+		goto end
+	}
+	name = path.Join(parts[x.opts.StripComponents:]...)
+end:
+	return name, err
+}
+
+// modeFor returns the mode to give the extracted name.
+func (x *extractor) modeFor(name string, mode fs.FileMode) fs.FileMode {
+	if x.opts.DestModeFunc != nil {
+		m := x.opts.DestModeFunc(EntryPath(filepath.Join(string(x.dest), filepath.FromSlash(name))))
+		if m != 0 {
+			mode = m
+		}
+	}
+	return mode.Perm()
+}
+
+// extract writes a single archive member.
+func (x *extractor) extract(e *archiveEntry) (err error) {
+	var name string
+
+	x.entries++
+	if x.maxEntries() > 0 && x.entries > x.maxEntries() {
+		err = NewErr(ErrArchiveTooManyEntries, "max_entries", x.maxEntries())
+		goto end
+	}
+	name, err = x.entryName(e.Name)
+	if err != nil || name == "" {
+		goto end
+	}
+	switch {
+	case e.Mode.IsDir():
+		err = x.root.MkdirAll(PathSegments(name), 0o755)
+		x.dirs = append(x.dirs, extractedDir{name: name, mode: e.Mode})
+	case e.Mode&fs.ModeSymlink != 0:
+		err = x.symlink(name, e.Linkname)
+	case e.Hardlink:
+		err = x.hardlink(name, e.Linkname)
+	case e.Mode.IsRegular():
+		err = x.writeFile(name, e)
+	}
+end:
+	if err != nil {
+		err = WithErr(err, "archive_entry", e.Name)
+	}
+	return err
+}
+
+// prepare creates the parent of name and, with Overwrite, removes an existing
+// non-directory at name.
+func (x *extractor) prepare(name string) (err error) {
+	err = x.root.MkdirAll(PathSegments(path.Dir(name)), 0o755)
+	if err != nil || !x.opts.Overwrite {
+		goto end
+	}
+	err = x.root.Remove(RelPath(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		err = nil
+	}
+end:
+	return err
+}
+
+func (x *extractor) symlink(name, target string) (err error) {
+	var resolved string
+
+	target = strings.ReplaceAll(target, `\`, "/")
+	resolved = path.Join(path.Dir(name), target)
+	if path.IsAbs(target) || resolved == ".." || strings.HasPrefix(resolved, "../") {
+		err = NewErr(ErrArchiveEntryEscapes, "link_target", target)
+		goto end
+	}
+	err = x.prepare(name)
+	if err != nil {
+		goto end
+	}
+	err = x.root.Symlink(filepath.FromSlash(target), RelPath(name))
+end:
+	return err
+}
+
+func (x *extractor) hardlink(name, target string) (err error) {
+	var oldname string
+
+	oldname, err = x.entryName(target)
+	if err == nil && oldname == "" {
+		err = NewErr(ErrArchiveEntryEscapes)
+	}
+	if err != nil {
+		err = WithErr(err, "link_target", target)
+		goto end
+	}
+	err = x.prepare(name)
+	if err != nil {
+		goto end
+	}
+	err = x.root.root.Link(oldname, name)
+end:
+	return err
+}
+
+func (x *extractor) writeFile(name string, e *archiveEntry) (err error) {
+	var f *os.File
+	var rc io.ReadCloser
+	var n int64
+	var mode fs.FileMode
+	var limit = x.maxSize()
+
+	if limit > 0 && x.written+e.Size > limit {
+		err = NewErr(ErrArchiveTooLarge, "max_size", limit)
+		goto end
+	}
+	err = x.prepare(name)
+	if err != nil {
+		goto end
+	}
+	mode = x.modeFor(name, e.Mode)
+	f, err = x.root.OpenFile(RelFilepath(name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		goto end
+	}
+	rc, err = e.open()
+	if err != nil {
+		err = CombineErrs([]error{err, f.Close()})
+		goto end
+	}
+	if limit > 0 {
+		// Never trust the size in the header; read at most one byte past the
+		// remaining budget to detect overruns.
+		n, err = io.Copy(f, io.LimitReader(rc, limit-x.written+1))
+	} else {
+		n, err = io.Copy(f, rc)
+	}
+	x.written += n
+	err = CombineErrs([]error{err, rc.Close(), f.Close()})
+	if err != nil {
+		goto end
+	}
+	if limit > 0 && x.written > limit {
+		err = NewErr(ErrArchiveTooLarge, "max_size", limit)
+		goto end
+	}
+	// The mode given to OpenFile is subject to the umask.
+	err = x.root.Chmod(RelPath(name), mode)
+	if err != nil {
+		goto end
+	}
+	if !e.ModTime.IsZero() {
+		err = x.root.Chtimes(RelPath(name), e.ModTime, e.ModTime)
+	}
+end:
+	return err
+}
+
+// finish applies directory modes, deepest first so that a read-only parent
+// does not block changes to its children.
+func (x *extractor) finish() (err error) {
+	slices.SortFunc(x.dirs, func(a, b extractedDir) int {
+		return strings.Compare(b.name, a.name)
+	})
+	for _, d := range x.dirs {
+		err = x.root.Chmod(RelPath(d.name), x.modeFor(d.name, d.mode))
+		if err != nil {
+			err = WithErr(err, "archive_entry", d.name)
+			goto end
+		}
+	}
+end:
+	return err
+}
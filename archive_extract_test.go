@@ -0,0 +1,278 @@
+package dt_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+// testArchiveEntry describes one member of an archive built by a test.
+type testArchiveEntry struct {
+	name   string
+	body   string
+	mode   int64
+	link   string
+	isDir  bool
+	isLink bool
+}
+
+func writeTestTar(t *testing.T, fp dt.Filepath, gz bool, entries []testArchiveEntry) {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: e.mode, ModTime: mtime, Typeflag: tar.TypeReg, Size: int64(len(e.body))}
+		switch {
+		case e.isDir:
+			hdr.Typeflag, hdr.Size = tar.TypeDir, 0
+		case e.isLink:
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, e.link, 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("WriteHeader() error = %v", err)
+		}
+		if hdr.Size > 0 {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	data := buf.Bytes()
+	if gz {
+		var zbuf bytes.Buffer
+		zw := gzip.NewWriter(&zbuf)
+		if _, err := zw.Write(data); err != nil {
+			t.Fatalf("gzip Write() error = %v", err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("gzip Close() error = %v", err)
+		}
+		data = zbuf.Bytes()
+	}
+	if err := fp.WriteFile(data, 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}
+
+func writeTestZip(t *testing.T, fp dt.Filepath, entries []testArchiveEntry) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		hdr.SetMode(os.FileMode(e.mode))
+		body := e.body
+		if e.isLink {
+			hdr.SetMode(os.ModeSymlink | 0o777)
+			body = e.link
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatalf("CreateHeader() error = %v", err)
+		}
+		if _, err = w.Write([]byte(body)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := fp.WriteFile(buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}
+
+func TestFilepath_ArchiveFormat(t *testing.T) {
+	tests := map[dt.Filepath]dt.ArchiveFormat{
+		"a.tar":      dt.TarArchive,
+		"a.TAR.GZ":   dt.TarGzArchive,
+		"a.tgz":      dt.TarGzArchive,
+		"a.tar.zst":  dt.TarZstArchive,
+		"dir/a.zip":  dt.ZipArchive,
+		"a.gz":       dt.UnknownArchive,
+		"a.tar.bz2x": dt.UnknownArchive,
+	}
+	for fp, want := range tests {
+		if got := fp.ArchiveFormat(); got != want {
+			t.Errorf("%s.ArchiveFormat() = %v, want %v", fp, got, want)
+		}
+	}
+}
+
+func TestFilepath_ExtractTo(t *testing.T) {
+	if err := dt.EnsureUserHomeDir(); err != nil {
+		t.Fatalf("EnsureUserHomeDir() error = %v", err)
+	}
+	entries := []testArchiveEntry{
+		{name: "app-1.0/", isDir: true, mode: 0o755},
+		{name: "app-1.0/bin/run", body: "#!/bin/sh\n", mode: 0o755},
+		{name: "app-1.0/README.md", body: "readme", mode: 0o644},
+		{name: "app-1.0/docs/link.md", isLink: true, link: "../README.md"},
+	}
+	src := dt.DirPath(t.TempDir())
+	archives := map[string]dt.Filepath{
+		"tar":    dt.FilepathJoin(src, "app.tar"),
+		"tar.gz": dt.FilepathJoin(src, "app.tar.gz"),
+		"zip":    dt.FilepathJoin(src, "app.zip"),
+	}
+	writeTestTar(t, archives["tar"], false, entries)
+	writeTestTar(t, archives["tar.gz"], true, entries)
+	writeTestZip(t, archives["zip"], entries)
+
+	for format, fp := range archives {
+		t.Run(format, func(t *testing.T) {
+			dest := dt.DirPathJoin(t.TempDir(), "out")
+			err := fp.ExtractTo(dest, &dt.ExtractOptions{StripComponents: 1})
+			if err != nil {
+				t.Fatalf("ExtractTo() error = %v", err)
+			}
+			got, err := dt.FilepathJoin(dest, "README.md").ReadFile()
+			if err != nil || string(got) != "readme" {
+				t.Errorf("ReadFile(README.md) = %q, %v; want %q", got, err, "readme")
+			}
+			info, err := dt.FilepathJoin(dest, "bin/run").Stat()
+			if err != nil {
+				t.Fatalf("Stat(bin/run) error = %v", err)
+			}
+			if runtime.GOOS != "windows" && info.Mode().Perm() != 0o755 {
+				t.Errorf("bin/run mode = %v, want %v", info.Mode().Perm(), os.FileMode(0o755))
+			}
+			if runtime.GOOS == "windows" {
+				return
+			}
+			got, err = dt.FilepathJoin(dest, "docs/link.md").ReadFile()
+			if err != nil || string(got) != "readme" {
+				t.Errorf("ReadFile(docs/link.md) = %q, %v; want %q", got, err, "readme")
+			}
+		})
+	}
+
+	t.Run("DestModeFunc and Overwrite", func(t *testing.T) {
+		dest := dt.DirPath(t.TempDir())
+		opts := &dt.ExtractOptions{
+			CopyOptions: dt.CopyOptions{
+				DestModeFunc: func(dt.EntryPath) os.FileMode { return 0o600 },
+			},
+		}
+		if err := archives["tar"].ExtractTo(dest, opts); err != nil {
+			t.Fatalf("ExtractTo() error = %v", err)
+		}
+		info, err := dt.FilepathJoin(dest, "app-1.0/bin/run").Stat()
+		if err != nil {
+			t.Fatalf("Stat() error = %v", err)
+		}
+		if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+			t.Errorf("mode = %v, want %v", info.Mode().Perm(), os.FileMode(0o600))
+		}
+		err = archives["tar"].ExtractTo(dest, nil)
+		if !errors.Is(err, fs.ErrExist) {
+			t.Errorf("ExtractTo() over existing files error = %v, want %v", err, fs.ErrExist)
+		}
+		opts.Overwrite = true
+		if err = archives["tar"].ExtractTo(dest, opts); err != nil {
+			t.Errorf("ExtractTo() with Overwrite error = %v", err)
+		}
+	})
+}
+
+func TestFilepath_ExtractTo_Unsafe(t *testing.T) {
+	if err := dt.EnsureUserHomeDir(); err != nil {
+		t.Fatalf("EnsureUserHomeDir() error = %v", err)
+	}
+	src := dt.DirPath(t.TempDir())
+	tests := []struct {
+		name    string
+		entries []testArchiveEntry
+		opts    *dt.ExtractOptions
+		want    error
+	}{
+		{
+			name:    "zip slip",
+			entries: []testArchiveEntry{{name: "ok/../../evil.txt", body: "x", mode: 0o644}},
+			want:    dt.ErrArchiveEntryEscapes,
+		},
+		{
+			name:    "absolute name",
+			entries: []testArchiveEntry{{name: "/etc/evil", body: "x", mode: 0o644}},
+			want:    dt.ErrArchiveEntryEscapes,
+		},
+		{
+			name:    "absolute symlink",
+			entries: []testArchiveEntry{{name: "etc", isLink: true, link: "/etc"}},
+			want:    dt.ErrArchiveEntryEscapes,
+		},
+		{
+			name:    "escaping symlink",
+			entries: []testArchiveEntry{{name: "a/up", isLink: true, link: "../../.."}},
+			want:    dt.ErrArchiveEntryEscapes,
+		},
+		{
+			name: "too large",
+			entries: []testArchiveEntry{
+				{name: "a", body: strings.Repeat("x", 60), mode: 0o644},
+				{name: "b", body: strings.Repeat("x", 60), mode: 0o644},
+			},
+			opts: &dt.ExtractOptions{MaxSize: 100},
+			want: dt.ErrArchiveTooLarge,
+		},
+		{
+			name: "too many entries",
+			entries: []testArchiveEntry{
+				{name: "a", body: "x", mode: 0o644},
+				{name: "b", body: "x", mode: 0o644},
+			},
+			opts: &dt.ExtractOptions{MaxEntries: 1},
+			want: dt.ErrArchiveTooManyEntries,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fp := dt.FilepathJoin(src, dt.Filename(strings.Repeat("x", i+1)+".tar"))
+			writeTestTar(t, fp, false, tt.entries)
+			parent := dt.DirPath(t.TempDir())
+			dest := dt.DirPathJoin(parent, "dest")
+			err := fp.ExtractTo(dest, tt.opts)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("ExtractTo() error = %v, want %v", err, tt.want)
+			}
+			if !errors.Is(err, dt.ErrFailedToExtractArchive) {
+				t.Errorf("ExtractTo() error = %v, want %v", err, dt.ErrFailedToExtractArchive)
+			}
+			entry, ok := dt.ErrValue[string](err, "archive_entry")
+			if !ok || entry == "" {
+				t.Errorf("ErrValue(archive_entry) = %q, %v; want the entry name", entry, ok)
+			}
+			if exists, _ := dt.FilepathJoin(parent, "evil.txt").Exists(); exists {
+				t.Error("ExtractTo() wrote outside the destination")
+			}
+		})
+	}
+}
+
+func TestFilepath_ExtractTo_UnsupportedFormat(t *testing.T) {
+	if err := dt.EnsureUserHomeDir(); err != nil {
+		t.Fatalf("EnsureUserHomeDir() error = %v", err)
+	}
+	fp := dt.FilepathJoin(t.TempDir(), "archive.rar")
+	if err := fp.WriteFile([]byte("not an archive"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	err := fp.ExtractTo(dt.DirPath(t.TempDir()), nil)
+	if !errors.Is(err, dt.ErrUnsupportedArchiveFormat) {
+		t.Errorf("ExtractTo() error = %v, want %v", err, dt.ErrUnsupportedArchiveFormat)
+	}
+}
@@ -0,0 +1,625 @@
+package dt
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ArchiveFS is a read-only fs.FS over the members of an archive file, so that
+// code written against fs.FS, such as dtglob rules, can read an archive
+// without extracting it first.
+//
+// The members are indexed when the archive is opened. Compressed tar archives
+// are first decompressed to a temporary file, subject to ArchiveFSOptions.MaxSize.
+// Members whose names escape the archive root are not visible, and symlinks
+// only resolve to targets inside the archive. Close releases the underlying
+// files.
+type ArchiveFS struct {
+	tree   *archiveTree
+	format ArchiveFormat
+}
+
+var (
+	_ fs.ReadDirFS  = (*ArchiveFS)(nil)
+	_ fs.ReadFileFS = (*ArchiveFS)(nil)
+	_ fs.StatFS     = (*ArchiveFS)(nil)
+	_ fs.ReadLinkFS = (*ArchiveFS)(nil)
+)
+
+// ArchiveFSOptions controls Filepath.OpenArchiveFS.
+type ArchiveFSOptions struct {
+	// Format overrides detection of the archive format from the extension.
+	Format ArchiveFormat
+
+	// MaxSize limits the bytes a compressed tar archive is decompressed to,
+	// and those of sparse members held in memory, guarding against
+	// decompression bombs. Zero means DefaultMaxExtractSize and a negative
+	// value disables the limit.
+	MaxSize int64
+}
+
+func (opts *ArchiveFSOptions) maxSize() int64 {
+	if opts.MaxSize == 0 {
+		return DefaultMaxExtractSize
+	}
+	return opts.MaxSize
+}
+
+// OpenArchiveFS opens the archive at fp as an ArchiveFS. The format is taken
+// from fp's extension unless opts gives one.
+func (fp Filepath) OpenArchiveFS(opts *ArchiveFSOptions) (afs *ArchiveFS, err error) {
+	var zr *zip.ReadCloser
+	var tree *archiveTree
+	var f ArchiveFormat
+
+	if opts == nil {
+		opts = &ArchiveFSOptions{}
+	}
+	f = opts.Format
+	if f == UnknownArchive {
+		f = fp.ArchiveFormat()
+	}
+	switch f {
+	case ZipArchive:
+		zr, err = openZip(fp)
+		if err != nil {
+			goto end
+		}
+		tree, err = newZipTree(&zr.Reader)
+		if err != nil {
+			err = CombineErrs([]error{err, zr.Close()})
+			goto end
+		}
+		tree.close = zr.Close
+	case TarArchive, TarGzArchive, TarZstArchive:
+		tree, err = openTarTree(fp, f, opts.maxSize())
+		if err != nil {
+			goto end
+		}
+	default:
+		err = NewErr(ErrUnsupportedArchiveFormat, "archive_format", f.String())
+		goto end
+	}
+	afs = &ArchiveFS{tree: tree, format: f}
+end:
+	if err != nil {
+		err = NewErr(ErrFailedToOpenArchive, fp.ErrKV(), err)
+	}
+	return afs, err
+}
+
+// Format returns the format of the archive.
+func (afs *ArchiveFS) Format() ArchiveFormat {
+	return afs.format
+}
+
+// Close releases the files held by afs.
+func (afs *ArchiveFS) Close() error {
+	return afs.tree.close()
+}
+
+func (afs *ArchiveFS) Open(name string) (fs.File, error) {
+	return afs.tree.Open(name)
+}
+
+func (afs *ArchiveFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(afs.tree, name)
+}
+
+func (afs *ArchiveFS) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(afs.tree, name)
+}
+
+func (afs *ArchiveFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(afs.tree, name)
+}
+
+func (afs *ArchiveFS) Lstat(name string) (fs.FileInfo, error) {
+	return afs.tree.Lstat(name)
+}
+
+func (afs *ArchiveFS) ReadLink(name string) (string, error) {
+	return afs.tree.ReadLink(name)
+}
+
+// archiveTree is an index of the members of an archive, served as an fs.FS.
+type archiveTree struct {
+	r       io.ReaderAt
+	entries map[string]*archiveNode
+	close   func() error
+}
+
+// archiveNode is one indexed member. Regular files are read from zf for zip
+// archives, and otherwise from offset in the tar file or from data for sparse
+// tar files.
+type archiveNode struct {
+	name     string
+	mode     fs.FileMode
+	modTime  time.Time
+	size     int64
+	offset   int64
+	data     []byte
+	zf       *zip.File
+	linkname string
+	children map[string]*archiveNode
+}
+
+func (e *archiveNode) info() fs.FileInfo {
+	return archiveFileInfo{name: e.name, size: e.size, mode: e.mode, modTime: e.modTime}
+}
+
+// section returns a reader over the contents of a regular file entry.
+func (e *archiveNode) section(r io.ReaderAt) *io.SectionReader {
+	if e.data != nil {
+		return io.NewSectionReader(bytes.NewReader(e.data), 0, e.size)
+	}
+	return io.NewSectionReader(r, e.offset, e.size)
+}
+
+// offsetReader tracks the position of the tar stream so member data can later
+// be read at its offset. tar.Reader uses Seek to skip unread data.
+type offsetReader struct {
+	rs  io.ReadSeeker
+	off int64
+}
+
+func (r *offsetReader) Read(p []byte) (n int, err error) {
+	n, err = r.rs.Read(p)
+	r.off += int64(n)
+	return n, err
+}
+
+func (r *offsetReader) Seek(offset int64, whence int) (pos int64, err error) {
+	pos, err = r.rs.Seek(offset, whence)
+	if err == nil {
+		r.off = pos
+	}
+	return pos, err
+}
+
+// openTarTree opens and indexes the tar archive at fp, spooling and reading
+// at most maxSize bytes, or any number when maxSize is negative.
+func openTarTree(fp Filepath, format ArchiveFormat, maxSize int64) (tree *archiveTree, err error) {
+	var f *os.File
+
+	f, err = os.Open(string(fp))
+	if err != nil {
+		goto end
+	}
+	if format != TarArchive {
+		f, err = spoolTar(f, format, maxSize)
+		if err != nil {
+			goto end
+		}
+	}
+	tree, err = newTarTree(f, maxSize)
+	if err != nil {
+		err = CombineErrs([]error{err, closeTar(f, format)})
+		goto end
+	}
+	tree.close = func() error {
+		return closeTar(f, format)
+	}
+end:
+	return tree, err
+}
+
+// spoolTar decompresses src into a temporary file, closing src.
+func spoolTar(src *os.File, format ArchiveFormat, maxSize int64) (f *os.File, err error) {
+	var rc io.ReadCloser
+	var r io.Reader
+	var n int64
+
+	defer CloseOrLog(src)
+	rc, err = format.decompress(src)
+	if err != nil {
+		goto end
+	}
+	defer CloseOrLog(rc)
+	f, err = os.CreateTemp("", "dt-archive-*.tar")
+	if err != nil {
+		goto end
+	}
+	r = rc
+	if maxSize >= 0 {
+		r = io.LimitReader(rc, maxSize+1)
+	}
+	n, err = io.Copy(f, r)
+	if err == nil && maxSize >= 0 && n > maxSize {
+		err = NewErr(ErrArchiveTooLarge, "max_size", maxSize)
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		err = CombineErrs([]error{err, closeTar(f, format)})
+		f = nil
+	}
+end:
+	return f, err
+}
+
+// closeTar closes f, removing it if it is a spooled temporary file.
+func closeTar(f *os.File, format ArchiveFormat) (err error) {
+	err = f.Close()
+	if format != TarArchive {
+		err = CombineErrs([]error{err, os.Remove(f.Name())})
+	}
+	return err
+}
+
+func newTarTree(f *os.File, maxSize int64) (tree *archiveTree, err error) {
+	var hdr *tar.Header
+	var name string
+	var data io.Reader
+
+	r := &offsetReader{rs: f}
+	tr := tar.NewReader(r)
+	tree = &archiveTree{
+		r:       f,
+		entries: map[string]*archiveNode{".": newArchiveDir(".")},
+	}
+	for {
+		hdr, err = tr.Next()
+		if err == io.EOF {
+			err = nil
+			break
+		}
+		if err != nil {
+			break
+		}
+		name, err = cleanArchiveName(hdr.Name)
+		if err != nil || name == "." {
+			// Escaping members cannot be addressed through fs.FS.
+			err = nil
+			continue
+		}
+		e := &archiveNode{
+			name:    path.Base(name),
+			mode:    hdr.FileInfo().Mode(),
+			modTime: hdr.ModTime,
+			size:    hdr.Size,
+			offset:  r.off,
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			e = newArchiveDir(e.name)
+			e.mode, e.modTime = hdr.FileInfo().Mode(), hdr.ModTime
+		case tar.TypeSymlink:
+			e.linkname = hdr.Linkname
+		case tar.TypeLink:
+			e, err = tree.hardlink(e.name, hdr.Linkname)
+			if err != nil {
+				err = nil
+				continue
+			}
+		case tar.TypeReg, tar.TypeGNUSparse:
+			if isSparseTar(hdr) {
+				data = tr
+				if maxSize >= 0 {
+					data = io.LimitReader(tr, maxSize)
+				}
+				e.data, err = io.ReadAll(data)
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			break
+		}
+		tree.add(name, e)
+	}
+	return tree, err
+}
+
+func newZipTree(zr *zip.Reader) (tree *archiveTree, err error) {
+	var name string
+
+	tree = &archiveTree{entries: map[string]*archiveNode{".": newArchiveDir(".")}}
+	for _, f := range zr.File {
+		name, err = cleanArchiveName(f.Name)
+		if err != nil || name == "." {
+			err = nil
+			continue
+		}
+		e := &archiveNode{
+			name:    path.Base(name),
+			mode:    f.Mode(),
+			modTime: f.Modified,
+			size:    int64(f.UncompressedSize64),
+		}
+		switch {
+		case e.mode.IsDir():
+			e = newArchiveDir(e.name)
+			e.mode, e.modTime = f.Mode(), f.Modified
+		case e.mode&fs.ModeSymlink != 0:
+			e.linkname, err = readZipSymlink(f)
+			if err != nil {
+				err = WithErr(err, "archive_entry", f.Name)
+				goto end
+			}
+			e.size = int64(len(e.linkname))
+		case e.mode.IsRegular():
+			e.zf = f
+		default:
+			continue
+		}
+		tree.add(name, e)
+	}
+end:
+	return tree, err
+}
+
+// isSparseTar reports whether hdr describes a sparse file, whose data is not
+// stored contiguously.
+func isSparseTar(hdr *tar.Header) (sparse bool) {
+	if hdr.Typeflag == tar.TypeGNUSparse {
+		sparse = true
+		goto end
+	}
+	for k := range hdr.PAXRecords {
+		if strings.HasPrefix(k, "GNU.sparse.") {
+			sparse = true
+			goto end
+		}
+	}
+end:
+	return sparse
+}
+
+func newArchiveDir(name string) *archiveNode {
+	return &archiveNode{
+		name:     name,
+		mode:     fs.ModeDir | 0o755,
+		children: make(map[string]*archiveNode),
+	}
+}
+
+// hardlink returns an entry named name that shares the contents of the
+// earlier member target.
+func (tree *archiveTree) hardlink(name, target string) (e *archiveNode, err error) {
+	var src *archiveNode
+
+	target, err = cleanArchiveName(target)
+	if err != nil {
+		goto end
+	}
+	src, err = tree.lookup("link", target, true, 0)
+	if err != nil {
+		goto end
+	}
+	if src.mode.IsDir() {
+		err = &fs.PathError{Op: "link", Path: target, Err: ErrIsADirectory}
+		goto end
+	}
+	e = new(archiveNode)
+	*e = *src
+	e.name = name
+end:
+	return e, err
+}
+
+// add places e at name, creating any missing parent directories. A later
+// member replaces an earlier one, as when extracting, except that a directory
+// keeps the children it already has.
+func (tree *archiveTree) add(name string, e *archiveNode) {
+	var parent *archiveNode
+	var dir string
+
+	dir = path.Dir(name)
+	parent = tree.entries[dir]
+	if parent == nil {
+		parent = newArchiveDir(path.Base(dir))
+		tree.add(dir, parent)
+	}
+	if parent.children == nil {
+		// A file or symlink occupies the parent's name; ignore the member.
+		return
+	}
+	old := tree.entries[name]
+	if old != nil && old.children != nil && e.children != nil {
+		e.children = old.children
+	}
+	tree.entries[name] = e
+	parent.children[e.name] = e
+}
+
+// lookup resolves name, following symlinks in every component and, when
+// follow is true, in the last one. Symlinks that leave the archive root do
+// not resolve.
+func (tree *archiveTree) lookup(op, name string, follow bool, depth int) (e *archiveNode, err error) {
+	var comps []string
+	var target string
+
+	if name != "." {
+		comps = strings.Split(name, "/")
+	}
+	e = tree.entries["."]
+	for i, comp := range comps {
+		if e.children == nil {
+			e, err = nil, fs.ErrNotExist
+			goto end
+		}
+		e = e.children[comp]
+		if e == nil {
+			err = fs.ErrNotExist
+			goto end
+		}
+		if e.linkname == "" || (!follow && i == len(comps)-1) {
+			continue
+		}
+		if depth >= maxArchiveSymlinks || path.IsAbs(e.linkname) {
+			e, err = nil, fs.ErrNotExist
+			goto end
+		}
+		target = path.Join(append([]string{path.Join(comps[:i]...), e.linkname}, comps[i+1:]...)...)
+		if target == ".." || strings.HasPrefix(target, "../") {
+			e, err = nil, fs.ErrNotExist
+			goto end
+		}
+		e, err = tree.lookup(op, target, follow, depth+1)
+		goto end
+	}
+end:
+	if err != nil {
+		var pe *fs.PathError
+		if !errors.As(err, &pe) {
+			err = &fs.PathError{Op: op, Path: name, Err: err}
+		}
+	}
+	return e, err
+}
+
+// maxArchiveSymlinks is the maximum number of symlinks followed when resolving
+// a name in an archive.
+const maxArchiveSymlinks = 40
+
+func (tree *archiveTree) Open(name string) (f fs.File, err error) {
+	var e *archiveNode
+	var rc io.ReadCloser
+	var entries []fs.DirEntry
+
+	if !fs.ValidPath(name) {
+		err = &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+		goto end
+	}
+	e, err = tree.lookup("open", name, true, 0)
+	if err != nil {
+		goto end
+	}
+	if e.zf != nil {
+		rc, err = e.zf.Open()
+		if err != nil {
+			goto end
+		}
+		f = &zipMemberFile{info: e.info(), rc: rc}
+		goto end
+	}
+	if e.children == nil {
+		f = &archiveFile{info: e.info(), r: e.section(tree.r)}
+		goto end
+	}
+	entries = make([]fs.DirEntry, 0, len(e.children))
+	for _, child := range e.children {
+		entries = append(entries, fs.FileInfoToDirEntry(child.info()))
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	f = &archiveDir{info: e.info(), entries: entries}
+end:
+	return f, err
+}
+
+func (tree *archiveTree) Lstat(name string) (info fs.FileInfo, err error) {
+	var e *archiveNode
+
+	if !fs.ValidPath(name) {
+		err = &fs.PathError{Op: "lstat", Path: name, Err: fs.ErrInvalid}
+		goto end
+	}
+	e, err = tree.lookup("lstat", name, false, 0)
+	if err != nil {
+		goto end
+	}
+	info = e.info()
+end:
+	return info, err
+}
+
+func (tree *archiveTree) ReadLink(name string) (target string, err error) {
+	var e *archiveNode
+
+	if !fs.ValidPath(name) {
+		err = &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+		goto end
+	}
+	e, err = tree.lookup("readlink", name, false, 0)
+	if err != nil {
+		goto end
+	}
+	if e.linkname == "" {
+		err = &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+		goto end
+	}
+	target = e.linkname
+end:
+	return target, err
+}
+
+// archiveFileInfo implements fs.FileInfo for archive members.
+type archiveFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (fi archiveFileInfo) Name() string       { return fi.name }
+func (fi archiveFileInfo) Size() int64        { return fi.size }
+func (fi archiveFileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi archiveFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi archiveFileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi archiveFileInfo) Sys() any           { return nil }
+
+// archiveFile is an open archive member.
+type archiveFile struct {
+	info fs.FileInfo
+	r    *io.SectionReader
+}
+
+func (f *archiveFile) Stat() (fs.FileInfo, error)                   { return f.info, nil }
+func (f *archiveFile) Read(p []byte) (int, error)                   { return f.r.Read(p) }
+func (f *archiveFile) ReadAt(p []byte, off int64) (int, error)      { return f.r.ReadAt(p, off) }
+func (f *archiveFile) Seek(offset int64, whence int) (int64, error) { return f.r.Seek(offset, whence) }
+func (f *archiveFile) Close() error                                 { return nil }
+
+// zipMemberFile is an open zip member, which can only be read sequentially.
+type zipMemberFile struct {
+	info fs.FileInfo
+	rc   io.ReadCloser
+}
+
+func (f *zipMemberFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *zipMemberFile) Read(p []byte) (int, error) { return f.rc.Read(p) }
+func (f *zipMemberFile) Close() error               { return f.rc.Close() }
+
+// archiveDir is an open archive directory.
+type archiveDir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *archiveDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *archiveDir) Close() error               { return nil }
+
+func (d *archiveDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: ErrIsADirectory}
+}
+
+func (d *archiveDir) ReadDir(count int) (entries []fs.DirEntry, err error) {
+	rest := d.entries[d.offset:]
+	if count <= 0 {
+		entries = rest
+		d.offset = len(d.entries)
+		goto end
+	}
+	if len(rest) == 0 {
+		err = io.EOF
+		goto end
+	}
+	entries = rest[:min(count, len(rest))]
+	d.offset += len(entries)
+end:
+	return entries, err
+}
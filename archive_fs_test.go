@@ -0,0 +1,107 @@
+package dt_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+func TestFilepath_OpenArchiveFS(t *testing.T) {
+	if err := dt.EnsureUserHomeDir(); err != nil {
+		t.Fatalf("EnsureUserHomeDir() error = %v", err)
+	}
+	entries := []testArchiveEntry{
+		{name: "app/", isDir: true, mode: 0o755},
+		{name: "app/README.md", body: "readme", mode: 0o644},
+		{name: "app/bin/run", body: "#!/bin/sh\n", mode: 0o755},
+		{name: "app/docs/link.md", isLink: true, link: "../README.md"},
+		{name: "../evil.txt", body: "x", mode: 0o644},
+	}
+	src := dt.DirPath(t.TempDir())
+	archives := map[string]dt.Filepath{
+		"tar":    dt.FilepathJoin(src, "app.tar"),
+		"tar.gz": dt.FilepathJoin(src, "app.tar.gz"),
+		"zip":    dt.FilepathJoin(src, "app.zip"),
+	}
+	writeTestTar(t, archives["tar"], false, entries)
+	writeTestTar(t, archives["tar.gz"], true, entries)
+	writeTestZip(t, archives["zip"], entries)
+
+	for format, fp := range archives {
+		t.Run(format, func(t *testing.T) {
+			afs, err := fp.OpenArchiveFS(nil)
+			if err != nil {
+				t.Fatalf("OpenArchiveFS() error = %v", err)
+			}
+			defer afs.Close()
+			if afs.Format().String() != format {
+				t.Errorf("Format() = %v, want %s", afs.Format(), format)
+			}
+			err = fstest.TestFS(afs, "app/README.md", "app/bin/run", "app/docs/link.md")
+			if err != nil {
+				t.Errorf("fstest.TestFS() error = %v", err)
+			}
+			got, err := afs.ReadFile("app/bin/run")
+			if err != nil || string(got) != "#!/bin/sh\n" {
+				t.Errorf("ReadFile(app/bin/run) = %q, %v", got, err)
+			}
+			target, err := afs.ReadLink("app/docs/link.md")
+			if err != nil || target != "../README.md" {
+				t.Errorf("ReadLink() = %q, %v; want %q", target, err, "../README.md")
+			}
+			if _, err = afs.Stat("evil.txt"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Stat(evil.txt) error = %v, want %v", err, fs.ErrNotExist)
+			}
+			matches, err := fs.Glob(afs, "app/*/*")
+			if err != nil || len(matches) != 2 {
+				t.Errorf("Glob(app/*/*) = %v, %v; want 2 matches", matches, err)
+			}
+			dest := dt.FilepathJoin(t.TempDir(), "README.md")
+			if err = dt.Filepath("app/README.md").CopyTo(dest, nil, afs); err != nil {
+				t.Fatalf("CopyTo() error = %v", err)
+			}
+			got, err = dest.ReadFile()
+			if err != nil || string(got) != "readme" {
+				t.Errorf("ReadFile() after CopyTo = %q, %v; want %q", got, err, "readme")
+			}
+		})
+	}
+
+	t.Run("symlinks", func(t *testing.T) {
+		afs, err := archives["zip"].OpenArchiveFS(nil)
+		if err != nil {
+			t.Fatalf("OpenArchiveFS() error = %v", err)
+		}
+		defer afs.Close()
+		got, err := afs.ReadFile("app/docs/link.md")
+		if err != nil || string(got) != "readme" {
+			t.Errorf("ReadFile(app/docs/link.md) = %q, %v; want %q", got, err, "readme")
+		}
+		info, err := afs.Lstat("app/docs/link.md")
+		if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+			t.Errorf("Lstat() = %v, %v; want a symlink", info, err)
+		}
+	})
+
+	t.Run("max size", func(t *testing.T) {
+		_, err := archives["tar.gz"].OpenArchiveFS(&dt.ArchiveFSOptions{MaxSize: 512})
+		if !errors.Is(err, dt.ErrArchiveTooLarge) {
+			t.Errorf("OpenArchiveFS(MaxSize: 512) error = %v, want %v", err, dt.ErrArchiveTooLarge)
+		}
+		afs, err := archives["tar.gz"].OpenArchiveFS(&dt.ArchiveFSOptions{MaxSize: -1})
+		if err != nil {
+			t.Fatalf("OpenArchiveFS(MaxSize: -1) error = %v", err)
+		}
+		defer afs.Close()
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := dt.FilepathJoin(src, "app.rar").OpenArchiveFS(nil)
+		if !errors.Is(err, dt.ErrUnsupportedArchiveFormat) {
+			t.Errorf("OpenArchiveFS() error = %v, want %v", err, dt.ErrUnsupportedArchiveFormat)
+		}
+	})
+}
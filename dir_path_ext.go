@@ -1,27 +1,39 @@
 package dt
 
 import (
+	"errors"
 	"io/fs"
 	"iter"
 	"os"
 	"path/filepath"
 )
 
-func (dp DirPath) Expand() (_ DirPath, err error) {
+func (dp DirPath) Expand(opts ...ExpandOptions) (_ DirPath, err error) {
 	var ep EntryPath
-	ep, err = EntryPath(dp).Expand()
+	ep, err = EntryPath(dp).Expand(opts...)
 	return DirPath(ep), err
 }
 
 // Normalize expands a leading "~" to the current user's home directory (when
 // it uses the correct OS path separator), then returns an absolute directory
-// path.
-func (dp DirPath) Normalize() (DirPath, error) {
-	return dp.Expand()
+// path. With ExpandOptions.ExpandEnv set, environment variables are expanded
+// first.
+func (dp DirPath) Normalize(opts ...ExpandOptions) (DirPath, error) {
+	return dp.Expand(opts...)
 }
 
-func (dp DirPath) ToTilde(opt TildeOption) (tdp TildeDirPath) {
-	return ToTilde[DirPath, TildeDirPath](dp, opt)
+func (dp DirPath) ExpandEnv(opts ExpandOptions) (_ DirPath, err error) {
+	var ep EntryPath
+	ep, err = EntryPath(dp).ExpandEnv(opts)
+	return DirPath(ep), err
+}
+
+func (dp DirPath) Collapse(names []string, opts ...ExpandOptions) DirPath {
+	return DirPath(EntryPath(dp).Collapse(names, opts...))
+}
+
+func (dp DirPath) ToTilde(opt TildeOption, hdps ...HomeDirProvider) (tdp TildeDirPath) {
+	return ToTilde[DirPath, TildeDirPath](dp, opt, hdps...)
 }
 
 func (dp DirPath) Status(flags ...EntryStatusFlags) (status EntryStatus, err error) {
@@ -239,3 +251,60 @@ func (dp DirPath) TouchFiles(files []RelFilepath, mode os.FileMode) (err error)
 	}
 	return CombineErrs(errs)
 }
+
+// MkdirAllFS creates dp and any missing parents within wfs, like MkdirAll.
+func (dp DirPath) MkdirAllFS(wfs WritableFS, mode os.FileMode) error {
+	return MkdirAllFS(wfs, fsName(dp), mode)
+}
+
+// RemoveFS removes the empty directory dp from wfs, like Remove.
+func (dp DirPath) RemoveFS(wfs WritableFS) error {
+	return wfs.Remove(fsName(dp))
+}
+
+// ChmodFS changes the mode of dp within wfs, like Chmod.
+func (dp DirPath) ChmodFS(wfs WritableFS, mode os.FileMode) error {
+	return wfs.Chmod(fsName(dp), mode)
+}
+
+// EnsureExistsFS is EnsureExists for a directory within wfs.
+func (dp DirPath) EnsureExistsFS(wfs WritableFS, mode os.FileMode) (err error) {
+	var info os.FileInfo
+	info, err = dp.Stat(wfs)
+	if errors.Is(err, fs.ErrNotExist) {
+		err = dp.MkdirAllFS(wfs, mode)
+		if err != nil {
+			goto end
+		}
+		info, err = dp.Stat(wfs)
+	}
+	if err != nil {
+		goto end
+	}
+	if !info.IsDir() {
+		err = NewErr(ErrPathIsFile)
+	}
+end:
+	if err != nil {
+		err = WithErr(err, ErrFailedToEnsureDir, dp.ErrKV())
+	}
+	return err
+}
+
+// MkSubdirsFS is MkSubdirs for directories within wfs.
+func (dp DirPath) MkSubdirsFS(wfs WritableFS, subdirs []PathSegments, mode os.FileMode) (err error) {
+	var errs []error
+	for _, dir := range subdirs {
+		errs = AppendErr(errs, DirPathJoin(dp, dir).MkdirAllFS(wfs, mode))
+	}
+	return CombineErrs(errs)
+}
+
+// TouchFilesFS is TouchFiles for files within wfs.
+func (dp DirPath) TouchFilesFS(wfs WritableFS, files []RelFilepath, mode os.FileMode) (err error) {
+	var errs []error
+	for _, file := range files {
+		errs = AppendErr(errs, FilepathJoin(dp, file).TouchFS(wfs, mode))
+	}
+	return CombineErrs(errs)
+}
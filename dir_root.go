@@ -0,0 +1,233 @@
+package dt
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DirRoot is a handle on a directory, opened with DirPath.OpenRoot, through
+// which every operation is confined to that directory. It wraps os.Root, so
+// the OS guarantees that neither ".." components nor symlinks can reach
+// outside the root; such names return an error instead.
+//
+// DirRoot is the safe alternative to DirPath.Join when a path comes from an
+// untrusted source such as user input or an archive. It is safe for
+// concurrent use and must be closed when no longer needed.
+type DirRoot struct {
+	root *os.Root
+	dir  DirPath
+}
+
+// OpenRoot opens dp as a DirRoot.
+func (dp DirPath) OpenRoot() (dr *DirRoot, err error) {
+	var root *os.Root
+	root, err = os.OpenRoot(string(dp))
+	if err != nil {
+		goto end
+	}
+	dr = &DirRoot{root: root, dir: dp}
+end:
+	return dr, err
+}
+
+// Dir returns the DirPath the root was opened with.
+func (dr *DirRoot) Dir() DirPath {
+	return dr.dir
+}
+
+// Close closes the root. Files already opened through it remain usable.
+func (dr *DirRoot) Close() error {
+	return dr.root.Close()
+}
+
+// OpenRoot opens the subdirectory ps as a DirRoot confined to it.
+func (dr *DirRoot) OpenRoot(ps PathSegments) (sub *DirRoot, err error) {
+	var root *os.Root
+	root, err = dr.root.OpenRoot(string(ps))
+	if err != nil {
+		goto end
+	}
+	sub = &DirRoot{root: root, dir: DirPathJoin(dr.dir, ps)}
+end:
+	return sub, err
+}
+
+// FS returns a read-only fs.FS for the root.
+func (dr *DirRoot) FS() fs.FS {
+	return dr.root.FS()
+}
+
+func (dr *DirRoot) Open(fp RelFilepath) (*os.File, error) {
+	return dr.root.Open(string(fp))
+}
+
+func (dr *DirRoot) OpenFile(fp RelFilepath, flag int, perm os.FileMode) (*os.File, error) {
+	return dr.root.OpenFile(string(fp), flag, perm)
+}
+
+func (dr *DirRoot) Create(fp RelFilepath) (*os.File, error) {
+	return dr.root.Create(string(fp))
+}
+
+func (dr *DirRoot) ReadFile(fp RelFilepath) ([]byte, error) {
+	return dr.root.ReadFile(string(fp))
+}
+
+func (dr *DirRoot) WriteFile(fp RelFilepath, data []byte, perm os.FileMode) error {
+	return dr.root.WriteFile(string(fp), data, perm)
+}
+
+func (dr *DirRoot) Mkdir(ps PathSegments, perm os.FileMode) error {
+	return dr.root.Mkdir(string(ps), perm)
+}
+
+func (dr *DirRoot) MkdirAll(ps PathSegments, perm os.FileMode) error {
+	return dr.root.MkdirAll(string(ps), perm)
+}
+
+func (dr *DirRoot) Stat(rp RelPath) (os.FileInfo, error) {
+	return dr.root.Stat(string(rp))
+}
+
+func (dr *DirRoot) Lstat(rp RelPath) (os.FileInfo, error) {
+	return dr.root.Lstat(string(rp))
+}
+
+func (dr *DirRoot) Readlink(rp RelPath) (string, error) {
+	return dr.root.Readlink(string(rp))
+}
+
+// Remove removes the file or empty directory rp.
+func (dr *DirRoot) Remove(rp RelPath) error {
+	return dr.root.Remove(string(rp))
+}
+
+// RemoveAll removes rp and everything it contains.
+func (dr *DirRoot) RemoveAll(rp RelPath) error {
+	return dr.root.RemoveAll(string(rp))
+}
+
+func (dr *DirRoot) Rename(oldPath, newPath RelPath) error {
+	return dr.root.Rename(string(oldPath), string(newPath))
+}
+
+func (dr *DirRoot) Chmod(rp RelPath, mode os.FileMode) error {
+	return dr.root.Chmod(string(rp), mode)
+}
+
+func (dr *DirRoot) Chtimes(rp RelPath, atime time.Time, mtime time.Time) error {
+	return dr.root.Chtimes(string(rp), atime, mtime)
+}
+
+// Symlink creates link as a symlink to target. The target must be relative and
+// may not point outside the root when it is later followed.
+func (dr *DirRoot) Symlink(target string, link RelPath) error {
+	return dr.root.Symlink(target, string(link))
+}
+
+// WritableFS returns a WritableFS backed by the root, so that dt's *FS
+// methods get the same confinement guarantees.
+func (dr *DirRoot) WritableFS() WritableFS {
+	return rootWritableFS{
+		osDirFS: dr.root.FS().(osDirFS),
+		root:    dr.root,
+	}
+}
+
+var _ WritableFS = rootWritableFS{}
+
+// rootWritableFS implements WritableFS with an os.Root.
+type rootWritableFS struct {
+	osDirFS
+	root *os.Root
+}
+
+// checkName validates name as an fs.FS name, which os.Root alone would not do
+// for names such as "a/../b".
+func (fsys rootWritableFS) checkName(op, name string) (err error) {
+	if !fs.ValidPath(name) {
+		err = &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return err
+}
+
+func (fsys rootWritableFS) OpenFile(name string, flag int, perm fs.FileMode) (wf WritableFile, err error) {
+	var f *os.File
+	err = fsys.checkName("open", name)
+	if err != nil {
+		goto end
+	}
+	f, err = fsys.root.OpenFile(name, flag, perm)
+	if err != nil {
+		goto end
+	}
+	wf = f
+end:
+	return wf, err
+}
+
+func (fsys rootWritableFS) Mkdir(name string, perm fs.FileMode) (err error) {
+	err = fsys.checkName("mkdir", name)
+	if err != nil {
+		goto end
+	}
+	err = fsys.root.Mkdir(name, perm)
+end:
+	return err
+}
+
+func (fsys rootWritableFS) Remove(name string) (err error) {
+	err = fsys.checkName("remove", name)
+	if err != nil {
+		goto end
+	}
+	err = fsys.root.Remove(name)
+end:
+	return err
+}
+
+func (fsys rootWritableFS) Rename(oldname, newname string) (err error) {
+	err = fsys.checkName("rename", oldname)
+	if err != nil {
+		goto end
+	}
+	err = fsys.checkName("rename", newname)
+	if err != nil {
+		goto end
+	}
+	err = fsys.root.Rename(oldname, newname)
+end:
+	return err
+}
+
+func (fsys rootWritableFS) Chmod(name string, mode fs.FileMode) (err error) {
+	err = fsys.checkName("chmod", name)
+	if err != nil {
+		goto end
+	}
+	err = fsys.root.Chmod(name, mode)
+end:
+	return err
+}
+
+func (fsys rootWritableFS) Chtimes(name string, atime time.Time, mtime time.Time) (err error) {
+	err = fsys.checkName("chtimes", name)
+	if err != nil {
+		goto end
+	}
+	err = fsys.root.Chtimes(name, atime, mtime)
+end:
+	return err
+}
+
+func (fsys rootWritableFS) Symlink(oldname, newname string) (err error) {
+	err = fsys.checkName("symlink", newname)
+	if err != nil {
+		goto end
+	}
+	err = fsys.root.Symlink(filepath.FromSlash(oldname), newname)
+end:
+	return err
+}
@@ -0,0 +1,98 @@
+package dt_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+func TestDirPath_OpenRoot(t *testing.T) {
+	parent := dt.DirPath(t.TempDir())
+	dir := dt.DirPathJoin(parent, "root")
+	if err := dir.MkdirAll(0o755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	secret := dt.FilepathJoin(parent, "secret.txt")
+	if err := secret.WriteFile([]byte("secret"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	dr, err := dir.OpenRoot()
+	if err != nil {
+		t.Fatalf("OpenRoot() error = %v", err)
+	}
+	defer dr.Close()
+
+	t.Run("operations inside the root", func(t *testing.T) {
+		if err := dr.MkdirAll("a/b", 0o755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		f, err := dr.Create("a/b/file.txt")
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if _, err = f.WriteString("hello"); err != nil {
+			t.Fatalf("WriteString() error = %v", err)
+		}
+		if err = f.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		got, err := dr.ReadFile("a/b/file.txt")
+		if err != nil || string(got) != "hello" {
+			t.Errorf("ReadFile() = %q, %v; want %q", got, err, "hello")
+		}
+		info, err := dr.Stat("a/b")
+		if err != nil || !info.IsDir() {
+			t.Errorf("Stat() = %v, %v; want a directory", info, err)
+		}
+		if err = dr.Remove("a/b/file.txt"); err != nil {
+			t.Errorf("Remove() error = %v", err)
+		}
+		if _, err = dr.Stat("a/b/file.txt"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Stat() after Remove error = %v, want %v", err, fs.ErrNotExist)
+		}
+	})
+
+	t.Run("escapes are rejected", func(t *testing.T) {
+		if _, err := dr.ReadFile("../secret.txt"); err == nil {
+			t.Error("ReadFile(../secret.txt) error = nil, want error")
+		}
+		if _, err := dr.Open(dt.RelFilepath(secret)); err == nil {
+			t.Error("Open(absolute) error = nil, want error")
+		}
+		if err := dr.MkdirAll("../escaped", 0o755); err == nil {
+			t.Error("MkdirAll(../escaped) error = nil, want error")
+		}
+		if runtime.GOOS == "windows" {
+			return
+		}
+		if err := os.Symlink(filepath.Join("..", "secret.txt"), string(dt.FilepathJoin(dir, "link.txt"))); err != nil {
+			t.Fatalf("Symlink() error = %v", err)
+		}
+		if _, err := dr.ReadFile("link.txt"); err == nil {
+			t.Error("ReadFile() through escaping symlink error = nil, want error")
+		}
+	})
+
+	t.Run("WritableFS", func(t *testing.T) {
+		wfs := dr.WritableFS()
+		if err := dt.Filepath("c/d.txt").Dir().MkdirAllFS(wfs, 0o755); err != nil {
+			t.Fatalf("MkdirAllFS() error = %v", err)
+		}
+		if err := dt.Filepath("c/d.txt").WriteFileFS(wfs, []byte("data"), 0o644); err != nil {
+			t.Fatalf("WriteFileFS() error = %v", err)
+		}
+		got, err := dt.FilepathJoin(dir, "c/d.txt").ReadFile()
+		if err != nil || string(got) != "data" {
+			t.Errorf("ReadFile() = %q, %v; want %q", got, err, "data")
+		}
+		if err := wfs.Mkdir("a/../b", 0o755); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("Mkdir(a/../b) error = %v, want %v", err, fs.ErrInvalid)
+		}
+	})
+}
@@ -143,6 +143,9 @@ func NewErr(parts ...any) error {
 	if e.empty() {
 		return cause // if we only had a cause, return it
 	}
+	if errCallerMode.Load() != int32(ErrCallersOff) {
+		e.pcs = captureCallers()
+	}
 
 	// Append optional cause to entry.errors (cause last)
 	if cause != nil {
@@ -245,16 +248,22 @@ func WithErr(parts ...any) error {
 	// Middle segment are the metadata/sentinels to apply.
 	middle := parts[i : j+1]
 
+	// Record where a new entry was created, if enabled (see SetErrCallerMode)
+	var pcs []uintptr
+	if errCallerMode.Load() != int32(ErrCallersOff) {
+		pcs = captureCallers()
+	}
+
 	// No base error: build entry from middle, then (if present) join cause LAST.
 	if baseErr == nil {
-		return handleCause(buildEntry(middle...), cause)
+		return withCallers(handleCause(buildEntry(middle...), cause), pcs)
 	}
 
 	// Have a base error: try to enrich rightmost entry or join a fresh entry.
 	err := buildErr(baseErr, middle)
 
 	// Now handle the cause
-	return handleCause(err, cause)
+	return withCallers(handleCause(err, cause), pcs)
 
 }
 
@@ -350,8 +359,35 @@ func ErrMeta(err error) []ErrKV {
 	return nil
 }
 
+// ErrEntryMeta returns the key/value pairs stored on err itself, in insertion
+// order and without those of the errors it holds, and reports whether err is a
+// doterr entry. Unlike ErrMeta, it does not scan the error tree.
+func ErrEntryMeta(err error) (kvs []ErrKV, ok bool) {
+	var e entry
+	var ep *entry
+
+	//goland:noinspection GoTypeAssertionOnErrors
+	e, ok = err.(entry)
+	if !ok {
+		//goland:noinspection GoTypeAssertionOnErrors
+		ep, ok = err.(*entry)
+		if !ok || ep == nil {
+			ok = false
+			goto end
+		}
+		e = *ep
+	}
+	kvs = make([]ErrKV, len(e.kvs))
+	for i, pair := range e.kvs {
+		kvs[i] = pair
+	}
+end:
+	return kvs, ok
+}
+
 // ErrValue extracts a single metadata value by key with type safety.
 // Returns the value and true if found and the value is of type T.
+// Values added with SecretKV are returned unredacted.
 // Returns the zero value of T and false if not found or type mismatch.
 //
 // Example:
@@ -371,7 +407,8 @@ func ErrValue[T any](err error, key string) (T, bool) {
 
 	for _, pair := range kvs {
 		if pair.Key() == key {
-			if val, ok := pair.Value().(T); ok {
+			// Redacted values are only masked in output
+			if val, ok := unredact(pair.Value()).(T); ok {
 				return val, true
 			}
 			return zero, false
@@ -547,9 +584,10 @@ var uniqueId = func() int {
 // Each function creates one entry with errors (sentinels, custom typed errors) and metadata.
 // It implements error and Unwrap() []error.
 type entry struct {
-	id     int     // Unique ID
-	errors []error // sentinels, custom typed errors (NOT the primary cause)
-	kvs    []kv    // metadata in insertion order
+	id     int       // Unique ID
+	errors []error   // sentinels, custom typed errors (NOT the primary cause)
+	kvs    []kv      // metadata in insertion order
+	pcs    []uintptr // where the entry was created, if enabled (see SetErrCallerMode)
 }
 
 func newEntry(errors []error, kvs []kv) *entry {
@@ -589,7 +627,7 @@ func (e entry) Error() (s string) {
 		if i != 0 {
 			meta.WriteString(", ")
 		}
-		_, _ = fmt.Fprintf(&meta, " %s=%v", pair.k, pair.v)
+		_, _ = fmt.Fprintf(&meta, " %s=%v", pair.k, redactKV(pair.k, pair.v))
 	}
 	s = sb.String()
 	if meta.Len() > 0 {
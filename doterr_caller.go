@@ -0,0 +1,191 @@
+package dt
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// doterr_caller.go records where doterr entries are created and prints the
+// entry chain with those locations for %+v.
+
+// ErrCallerMode selects what NewErr and WithErr record about where each new
+// entry was created.
+type ErrCallerMode int32
+
+const (
+	// ErrCallersOff records nothing; it is the default and costs nothing.
+	ErrCallersOff ErrCallerMode = iota
+
+	// ErrCallersCaller records the file, line and function that called
+	// NewErr or WithErr.
+	ErrCallersCaller
+
+	// ErrCallersStack records the full call stack, up to maxErrStackDepth
+	// frames.
+	ErrCallersStack
+)
+
+const maxErrStackDepth = 32
+
+var errCallerMode atomic.Int32
+
+// SetErrCallerMode sets what new entries record about where they were created
+// and returns the previous mode. Entries created earlier keep what they
+// recorded. Enable it in main or in tests:
+//
+//	defer dt.SetErrCallerMode(dt.SetErrCallerMode(dt.ErrCallersCaller))
+func SetErrCallerMode(mode ErrCallerMode) (prev ErrCallerMode) {
+	return ErrCallerMode(errCallerMode.Swap(int32(mode)))
+}
+
+// GetErrCallerMode returns the current ErrCallerMode.
+func GetErrCallerMode() ErrCallerMode {
+	return ErrCallerMode(errCallerMode.Load())
+}
+
+// captureCallers returns the program counters of the caller of the doterr
+// function that called it.
+func captureCallers() []uintptr {
+	n := 1
+	if ErrCallerMode(errCallerMode.Load()) == ErrCallersStack {
+		n = maxErrStackDepth
+	}
+	pcs := make([]uintptr, n)
+	// Skip runtime.Callers, captureCallers and NewErr/WithErr
+	return pcs[:runtime.Callers(3, pcs)]
+}
+
+// withCallers records pcs on err when it is a new entry without a location.
+func withCallers(err error, pcs []uintptr) error {
+	if len(pcs) == 0 {
+		return err
+	}
+	//goland:noinspection GoTypeAssertionOnErrors
+	e, ok := err.(entry)
+	if !ok || e.pcs != nil {
+		return err
+	}
+	e.pcs = pcs
+	return e
+}
+
+// ErrFrames returns the locations recorded by the first doterr entry in err,
+// innermost call first, or nil when none were recorded.
+func ErrFrames(err error) (frames []runtime.Frame) {
+	var e entry
+	var ok bool
+
+	e, ok = FindErr[entry](err)
+	if !ok {
+		goto end
+	}
+	frames = e.frames()
+end:
+	return frames
+}
+
+func (e entry) frames() (frames []runtime.Frame) {
+	if len(e.pcs) == 0 {
+		return nil
+	}
+	fs := runtime.CallersFrames(e.pcs)
+	for {
+		frame, more := fs.Next()
+		frames = append(frames, frame)
+		if !more {
+			break
+		}
+	}
+	return frames
+}
+
+// Format implements fmt.Formatter. %+v prints the entry chain as an indented
+// tree with the recorded location of each entry; other verbs print Error().
+func (e entry) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		formatErrChain(s, e)
+	case verb == 'q':
+		_, _ = fmt.Fprintf(s, "%q", e.Error())
+	default:
+		_, _ = io.WriteString(s, e.Error())
+	}
+}
+
+// Format implements fmt.Formatter like entry.Format.
+func (c combined) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		formatErrChain(s, c)
+	case verb == 'q':
+		_, _ = fmt.Fprintf(s, "%q", c.Error())
+	default:
+		_, _ = io.WriteString(s, c.Error())
+	}
+}
+
+// formatErrChain writes the %+v form of err, without a trailing newline.
+func formatErrChain(s fmt.State, err error) {
+	var sb strings.Builder
+	writeErrChain(&sb, err, "")
+	_, _ = io.WriteString(s, strings.TrimSuffix(sb.String(), "\n"))
+}
+
+// writeErrChain writes err and its causes, one line per entry followed by its
+// locations, nesting causes one level deeper.
+func writeErrChain(w io.Writer, err error, indent string) {
+	var causes []error
+
+	switch e := err.(type) {
+	case entry:
+		causes = e.writeHeadline(w, indent)
+		for _, f := range e.frames() {
+			_, _ = fmt.Fprintf(w, "%s    at %s (%s:%d)\n", indent, f.Function, f.File, f.Line)
+		}
+		for _, cause := range causes {
+			writeErrChain(w, cause, indent+"  ")
+		}
+	case *entry:
+		writeErrChain(w, *e, indent)
+	case combined:
+		for _, member := range e.errs {
+			writeErrChain(w, member, indent)
+		}
+	case interface{ Unwrap() []error }:
+		for _, member := range e.Unwrap() {
+			writeErrChain(w, member, indent)
+		}
+	default:
+		_, _ = fmt.Fprintf(w, "%s%s\n", indent, strings.ReplaceAll(err.Error(), "\n", "\n"+indent))
+	}
+}
+
+// writeHeadline writes the messages of e's plain errors and its own KVs on one
+// line and returns its structured causes.
+func (e entry) writeHeadline(w io.Writer, indent string) (causes []error) {
+	var msgs []string
+	var meta []string
+
+	for _, err := range e.errors {
+		if isStructuredErr(err) {
+			causes = append(causes, err)
+			continue
+		}
+		msgs = append(msgs, err.Error())
+	}
+	for _, pair := range e.kvs {
+		if e.MatchKV(pair.k, pair.v) {
+			continue
+		}
+		meta = append(meta, fmt.Sprintf("%s=%v", pair.k, redactKV(pair.k, pair.v)))
+	}
+	line := strings.Join(msgs, "; ")
+	if len(meta) > 0 {
+		line += "; meta: " + strings.Join(meta, ", ")
+	}
+	_, _ = fmt.Fprintf(w, "%s%s\n", indent, strings.TrimPrefix(line, "; "))
+	return causes
+}
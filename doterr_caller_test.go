@@ -0,0 +1,79 @@
+package dt_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+func newTestInnerErr() error {
+	return dt.NewErr(errTestInner, "file", "a.txt")
+}
+
+func TestErrCallers_Off(t *testing.T) {
+	defer dt.SetErrCallerMode(dt.SetErrCallerMode(dt.ErrCallersOff))
+
+	err := newTestInnerErr()
+	if frames := dt.ErrFrames(err); frames != nil {
+		t.Errorf("ErrFrames() = %v, want nil", frames)
+	}
+	if got, want := fmt.Sprintf("%+v", err), "inner failed; meta: file=a.txt"; got != want {
+		t.Errorf("%%+v = %q, want %q", got, want)
+	}
+}
+
+func TestErrCallers_Caller(t *testing.T) {
+	defer dt.SetErrCallerMode(dt.SetErrCallerMode(dt.ErrCallersCaller))
+
+	err := dt.WithErr(newTestInnerErr(), errTestOuter, "op", "copy")
+	frames := dt.ErrFrames(err)
+	if len(frames) != 1 || !strings.HasSuffix(frames[0].Function, ".TestErrCallers_Caller") {
+		t.Fatalf("ErrFrames() = %v, want one frame in TestErrCallers_Caller", frames)
+	}
+
+	got := fmt.Sprintf("%+v", err)
+	lines := strings.Split(got, "\n")
+	if len(lines) != 4 {
+		t.Fatalf("%%+v = %q, want 4 lines", got)
+	}
+	checks := []struct {
+		line   string
+		prefix string
+		has    string
+	}{
+		{lines[0], "outer failed; meta: op=copy", ""},
+		{lines[1], "    at ", ".TestErrCallers_Caller ("},
+		{lines[2], "  inner failed; meta: file=a.txt", ""},
+		{lines[3], "      at ", ".newTestInnerErr ("},
+	}
+	for _, c := range checks {
+		if !strings.HasPrefix(c.line, c.prefix) || !strings.Contains(c.line, c.has) {
+			t.Errorf("%%+v line %q, want prefix %q containing %q", c.line, c.prefix, c.has)
+		}
+	}
+	if !strings.Contains(got, "doterr_caller_test.go:") {
+		t.Errorf("%%+v = %q, want file and line", got)
+	}
+
+	// Other verbs are unchanged.
+	if got := fmt.Sprintf("%v", err); got != err.Error() {
+		t.Errorf("%%v = %q, want %q", got, err.Error())
+	}
+	if !errors.Is(err, errTestInner) {
+		t.Errorf("errors.Is(err, errTestInner) = false")
+	}
+}
+
+func TestErrCallers_Stack(t *testing.T) {
+	defer dt.SetErrCallerMode(dt.SetErrCallerMode(dt.ErrCallersStack))
+
+	frames := dt.ErrFrames(newTestInnerErr())
+	if len(frames) < 2 ||
+		!strings.HasSuffix(frames[0].Function, ".newTestInnerErr") ||
+		!strings.HasSuffix(frames[1].Function, ".TestErrCallers_Stack") {
+		t.Errorf("ErrFrames() = %v, want newTestInnerErr then TestErrCallers_Stack", frames)
+	}
+}
@@ -0,0 +1,279 @@
+package dt
+
+import (
+	"fmt"
+	"iter"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// doterr_collect.go aggregates the errors of bulk operations without keeping
+// every one: ErrCollector stores up to a maximum, groups similar errors and
+// summarizes the rest as counts.
+
+// DefaultErrCollectorMax is the number of errors an ErrCollector stores when
+// its Max is zero.
+const DefaultErrCollectorMax = 100
+
+// ErrCollector collects errors from a bulk operation, such as copying or
+// walking many files, where CombineErrs could grow without bound. It stores
+// up to Max errors and, past that, only counts them. Errors are grouped by
+// sentinel and normalized message, where digits and path-like words are
+// masked, so repeated failures summarize as one line.
+//
+// The zero value is ready to use. An ErrCollector is safe for concurrent use
+// and must not be copied after first use.
+type ErrCollector struct {
+	// Max is the number of errors stored, and of groups tracked; errors in
+	// further groups are only counted. Zero means DefaultErrCollectorMax.
+	Max int
+
+	mu      sync.Mutex
+	groups  []*errGroup
+	index   map[errGroupKey]*errGroup
+	stored  []error
+	total   int
+	dropped int // errors not in any tracked group
+}
+
+// ErrGroup describes errors an ErrCollector grouped together.
+type ErrGroup struct {
+	// Sentinel is the first plain error in the tree of the group's first
+	// error, such as the sentinel passed to NewErr, or the error it wraps. It
+	// is nil for errors that hold no sentinel, such as those from errors.New.
+	Sentinel error
+
+	// Message is the normalized message the group's errors share.
+	Message string
+
+	// Count is the number of errors added to the group.
+	Count int
+
+	// Errs are the group's errors the collector stored, in the order added.
+	Errs []error
+}
+
+type errGroup struct {
+	ErrGroup
+	first string // Error() of the first error, for the summary
+}
+
+type errGroupKey struct {
+	sentinel error
+	message  string
+}
+
+// Add collects err. It ignores nil and adds the members of a CombineErrs
+// result individually.
+func (c *ErrCollector) Add(err error) {
+	if err == nil {
+		return
+	}
+	if ce, ok := err.(combined); ok {
+		for _, member := range ce.errs {
+			c.Add(member)
+		}
+		return
+	}
+	key := newErrGroupKey(err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.total++
+	g, ok := c.index[key]
+	if !ok {
+		if len(c.groups) >= c.max() {
+			c.dropped++
+			return
+		}
+		if c.index == nil {
+			c.index = make(map[errGroupKey]*errGroup)
+		}
+		g = &errGroup{
+			ErrGroup: ErrGroup{Sentinel: key.sentinel, Message: key.message},
+			first:    err.Error(),
+		}
+		c.groups = append(c.groups, g)
+		c.index[key] = g
+	}
+	g.Count++
+	if len(c.stored) < c.max() {
+		g.Errs = append(g.Errs, err)
+		c.stored = append(c.stored, err)
+	}
+}
+
+func (c *ErrCollector) max() int {
+	if c.Max <= 0 {
+		return DefaultErrCollectorMax
+	}
+	return c.Max
+}
+
+// Len returns the number of errors added, stored or not.
+func (c *ErrCollector) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.total
+}
+
+// All returns the stored errors in the order added, with full detail.
+func (c *ErrCollector) All() iter.Seq[error] {
+	c.mu.Lock()
+	stored := c.stored[:len(c.stored):len(c.stored)]
+	c.mu.Unlock()
+	return func(yield func(error) bool) {
+		for _, err := range stored {
+			if !yield(err) {
+				return
+			}
+		}
+	}
+}
+
+// Groups returns the groups in the order their first error was added.
+func (c *ErrCollector) Groups() iter.Seq[ErrGroup] {
+	c.mu.Lock()
+	groups := make([]ErrGroup, len(c.groups))
+	for i, g := range c.groups {
+		groups[i] = g.ErrGroup
+		groups[i].Errs = g.Errs[:len(g.Errs):len(g.Errs)]
+	}
+	c.mu.Unlock()
+	return func(yield func(ErrGroup) bool) {
+		for _, g := range groups {
+			if !yield(g) {
+				return
+			}
+		}
+	}
+}
+
+// Err returns nil when no errors were added and the error itself when only one
+// was. Otherwise it returns a summary listing the first error of each group,
+// followed by "(and N more similar)" when the group has more. The summary
+// unwraps to the stored errors, so errors.Is and errors.As see them.
+func (c *ErrCollector) Err() (err error) {
+	var s collectedErrs
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch c.total {
+	case 0:
+		goto end
+	case 1:
+		if len(c.stored) == 1 {
+			err = c.stored[0]
+			goto end
+		}
+	}
+	s = collectedErrs{
+		errs:  c.stored[:len(c.stored):len(c.stored)],
+		lines: make([]string, 0, len(c.groups)+1),
+	}
+	for _, g := range c.groups {
+		line := g.first
+		if g.Count > 1 {
+			line += fmt.Sprintf(" (and %d more similar)", g.Count-1)
+		}
+		s.lines = append(s.lines, line)
+	}
+	if c.dropped > 0 {
+		s.lines = append(s.lines, fmt.Sprintf("and %d more errors", c.dropped))
+	}
+	err = s
+end:
+	return err
+}
+
+// collectedErrs is the summary returned by ErrCollector.Err.
+type collectedErrs struct {
+	errs  []error
+	lines []string
+}
+
+func (s collectedErrs) Error() string   { return strings.Join(s.lines, "\n") }
+func (s collectedErrs) Unwrap() []error { return s.errs }
+
+// newErrGroupKey returns the key grouping err with similar errors.
+func newErrGroupKey(err error) (key errGroupKey) {
+	var msgs []string
+
+	key.sentinel, msgs = plainErrs(err, nil, nil)
+	switch {
+	case key.sentinel == nil:
+	case !reflect.TypeOf(key.sentinel).Comparable():
+		// Checked first, as == panics for such errors and they cannot be
+		// map keys
+		key.sentinel = nil
+	case key.sentinel == err:
+		// An error that neither holds nor wraps a sentinel, such as one from
+		// errors.New, is new on every call, so only its message groups it
+		key.sentinel = nil
+	}
+	key.message = normalizeErrMsg(strings.Join(msgs, "; "))
+	return key
+}
+
+// plainErrs appends the messages of the plain errors in err's tree to msgs,
+// looking inside doterr entries and joins, whose own messages include varying
+// KVs. It returns first or, when first is nil, the error the first plain error
+// wraps, such as fs.ErrPermission for an *fs.PathError.
+func plainErrs(err, first error, msgs []string) (error, []string) {
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, child := range e.Unwrap() {
+			first, msgs = plainErrs(child, first, msgs)
+		}
+	case nil:
+	default:
+		if first == nil {
+			first = innermostErr(err)
+		}
+		// The message of a wrapping error includes those it wraps
+		msgs = append(msgs, err.Error())
+	}
+	return first, msgs
+}
+
+// innermostErr follows err's chain of single wrapped errors to its end.
+func innermostErr(err error) error {
+	for {
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok || u.Unwrap() == nil {
+			return err
+		}
+		err = u.Unwrap()
+	}
+}
+
+// normalizeErrMsg masks the parts of msg that vary between otherwise similar
+// errors: words containing a path separator become "…" and runs of digits
+// become "#".
+func normalizeErrMsg(msg string) string {
+	var sb strings.Builder
+
+	for i, w := range strings.Fields(msg) {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		if strings.ContainsAny(w, `/\`) {
+			sb.WriteString("…")
+			continue
+		}
+		inDigits := false
+		for _, r := range w {
+			switch {
+			case !unicode.IsDigit(r):
+				sb.WriteRune(r)
+				inDigits = false
+			case !inDigits:
+				sb.WriteByte('#')
+				inDigits = true
+			}
+		}
+	}
+	return sb.String()
+}
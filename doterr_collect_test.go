@@ -0,0 +1,118 @@
+package dt_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+// listErr is an error whose type is not comparable.
+type listErr struct {
+	items []string
+}
+
+func (e listErr) Error() string {
+	return "invalid items: " + strings.Join(e.items, ", ")
+}
+
+func TestErrCollector(t *testing.T) {
+	t.Run("empty and single", func(t *testing.T) {
+		var c dt.ErrCollector
+		c.Add(nil)
+		if err := c.Err(); err != nil {
+			t.Errorf("Err() = %v, want nil", err)
+		}
+		single := dt.NewErr(dt.ErrFailedToCopyFile, "source", "a.txt")
+		c.Add(single)
+		if err := c.Err(); !errors.Is(err, dt.ErrFailedToCopyFile) || err.Error() != single.Error() {
+			t.Errorf("Err() = %v, want %v", err, single)
+		}
+	})
+
+	t.Run("groups similar errors", func(t *testing.T) {
+		c := dt.ErrCollector{Max: 3}
+		for i := range 5 {
+			c.Add(dt.NewErr(dt.ErrFailedToCopyFile, "source", fmt.Sprintf("file%d.txt", i),
+				&fs.PathError{Op: "open", Path: fmt.Sprintf("/src/file%d.txt", i), Err: fs.ErrPermission},
+			))
+		}
+		c.Add(dt.CombineErrs([]error{
+			dt.NewErr(dt.ErrFileNotExist, "path", "x"),
+			fmt.Errorf("retry %d of 3 failed", 2),
+			fmt.Errorf("retry %d of 3 failed", 3),
+		}))
+		err := c.Err()
+		want := strings.Join([]string{
+			"failed to copy file; open /src/file0.txt: permission denied; meta: source=file0.txt (and 4 more similar)",
+			"file does not exist; meta: path=x",
+			"retry 2 of 3 failed (and 1 more similar)",
+		}, "\n")
+		if err == nil || err.Error() != want {
+			t.Errorf("Err() =\n%v\nwant:\n%s", err, want)
+		}
+		if c.Len() != 8 {
+			t.Errorf("Len() = %d, want 8", c.Len())
+		}
+		if got := slices.Collect(c.All()); len(got) != 3 {
+			t.Errorf("All() yielded %d errors, want the 3 stored", len(got))
+		}
+		if !errors.Is(err, fs.ErrPermission) || !errors.Is(err, dt.ErrFailedToCopyFile) {
+			t.Errorf("Err() does not unwrap to the stored errors")
+		}
+		var counts []int
+		for g := range c.Groups() {
+			counts = append(counts, g.Count)
+		}
+		if !slices.Equal(counts, []int{5, 1, 2}) {
+			t.Errorf("Groups() counts = %v, want [5 1 2]", counts)
+		}
+	})
+
+	t.Run("bounds groups", func(t *testing.T) {
+		c := dt.ErrCollector{Max: 2}
+		for _, msg := range []string{"alpha", "beta", "gamma", "delta"} {
+			c.Add(errors.New(msg))
+		}
+		if got, want := c.Err().Error(), "alpha\nbeta\nand 2 more errors"; got != want {
+			t.Errorf("Err() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		var c dt.ErrCollector
+		var wg sync.WaitGroup
+		for i := range 50 {
+			wg.Go(func() {
+				c.Add(fmt.Errorf("worker %d: %w", i, fs.ErrClosed))
+			})
+		}
+		wg.Wait()
+		if c.Len() != 50 || !strings.HasSuffix(c.Err().Error(), "(and 49 more similar)") {
+			t.Errorf("Len() = %d, Err() = %v", c.Len(), c.Err())
+		}
+	})
+	t.Run("non-comparable errors", func(t *testing.T) {
+		var c dt.ErrCollector
+		c.Add(listErr{items: []string{"a", "b"}})
+		c.Add(fmt.Errorf("wrapped: %w", listErr{items: []string{"c"}}))
+		c.Add(dt.NewErr(dt.ErrInvalidArgumentType, listErr{items: []string{"d"}}))
+		c.Add(listErr{items: []string{"e", "f"}})
+		if c.Len() != 4 {
+			t.Errorf("Len() = %d, want 4", c.Len())
+		}
+		for g := range c.Groups() {
+			if g.Sentinel != nil && g.Sentinel != dt.ErrInvalidArgumentType {
+				t.Errorf("Groups() sentinel = %v, want nil or ErrInvalidArgumentType", g.Sentinel)
+			}
+		}
+		if err := c.Err(); !errors.As(err, new(listErr)) {
+			t.Errorf("Err() = %v, want a listErr", err)
+		}
+	})
+}
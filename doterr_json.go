@@ -0,0 +1,344 @@
+package dt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// doterr_json.go serializes doterr errors as a JSON tree that keeps the
+// sentinels, typed KV values and join structure that Error() flattens away,
+// and rebuilds errors from it.
+//
+// Each node has a "kind":
+//
+//   - "entry": a NewErr/WithErr entry with its "kvs" and, in order, its
+//     sentinels and causes under "errors",
+//   - "combined": a CombineErrs result with its members under "errors",
+//   - "join": any other multi-error, such as errors.Join, with its message,
+//   - "wrap": an error wrapping one other, such as fmt.Errorf with %w,
+//   - "error": any other error, with its "message" and, for registered
+//     sentinels, its "code".
+//
+// KVs record their Go type so UnmarshalErrJSON can restore it; see
+// RegisterErrKVType.
+
+// errNode is one error in the JSON tree.
+type errNode struct {
+	Kind    string     `json:"kind"`
+	Message string     `json:"message,omitempty"`
+	Code    string     `json:"code,omitempty"`
+	KVs     []kvNode   `json:"kvs,omitempty"`
+	Errors  []*errNode `json:"errors,omitempty"`
+}
+
+// kvNode is one KV of an entry node. Type is the Go type of the value, or
+// "error" for error values, which are encoded as nodes.
+type kvNode struct {
+	Key   string          `json:"key"`
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+const (
+	errKindEntry    = "entry"
+	errKindCombined = "combined"
+	errKindJoin     = "join"
+	errKindWrap     = "wrap"
+	errKindError    = "error"
+	kvTypeError     = "error"
+)
+
+// MarshalErrJSON encodes err as a JSON tree of its entries, sentinels, KVs
+// and causes. It returns "null" for a nil error. KV values that cannot be
+// encoded as JSON are recorded as strings formatted with %v.
+func MarshalErrJSON(err error) (data []byte, errOut error) {
+	var node *errNode
+
+	if err == nil {
+		data = []byte("null")
+		goto end
+	}
+	node, errOut = encodeErrNode(err)
+	if errOut != nil {
+		goto end
+	}
+	data, errOut = json.Marshal(node)
+end:
+	if errOut != nil {
+		errOut = NewErr(ErrFailedToMarshalJSON, errOut)
+	}
+	return data, errOut
+}
+
+// UnmarshalErrJSON rebuilds the error encoded by MarshalErrJSON in data,
+// returning it as decoded; err reports malformed input. Leaf errors whose code
+// or message was registered with RegisterErr decode to the sentinel itself, so
+// errors.Is matches them; other leaves decode to errors with the same message.
+// Entries are rebuilt as doterr entries, so ErrValue and ErrMeta work on the
+// result.
+func UnmarshalErrJSON(data []byte) (decoded error, err error) {
+	var node *errNode
+
+	err = json.Unmarshal(data, &node)
+	if err != nil || node == nil {
+		goto end
+	}
+	decoded, err = decodeErrNode(node)
+end:
+	if err != nil {
+		decoded = nil
+		err = NewErr(ErrFailedToUnmarshalJSON, err)
+	}
+	return decoded, err
+}
+
+func encodeErrNode(err error) (node *errNode, errOut error) {
+	var children []error
+
+	node = &errNode{Kind: errKindError, Message: err.Error()}
+	switch e := err.(type) {
+	case entry:
+		node, errOut = encodeEntryNode(e)
+		goto end
+	case *entry:
+		node, errOut = encodeEntryNode(*e)
+		goto end
+	case combined:
+		node = &errNode{Kind: errKindCombined}
+		children = e.errs
+	case interface{ Unwrap() []error }:
+		node.Kind = errKindJoin
+		children = e.Unwrap()
+	default:
+		if r, ok := registeredSentinel(err); ok {
+			node.Code = r.spec.Code
+			goto end
+		}
+		if u := errors.Unwrap(err); u != nil {
+			node.Kind = errKindWrap
+			children = []error{u}
+		}
+	}
+	node.Errors, errOut = encodeErrNodes(children)
+end:
+	return node, errOut
+}
+
+func encodeErrNodes(errs []error) (nodes []*errNode, err error) {
+	var node *errNode
+
+	for _, e := range errs {
+		if e == nil {
+			continue
+		}
+		node, err = encodeErrNode(e)
+		if err != nil {
+			goto end
+		}
+		nodes = append(nodes, node)
+	}
+end:
+	return nodes, err
+}
+
+func encodeEntryNode(e entry) (node *errNode, err error) {
+	var kn kvNode
+
+	node = &errNode{Kind: errKindEntry}
+	for _, pair := range e.kvs {
+		kn, err = encodeKVNode(pair)
+		if err != nil {
+			goto end
+		}
+		node.KVs = append(node.KVs, kn)
+	}
+	node.Errors, err = encodeErrNodes(e.errors)
+end:
+	return node, err
+}
+
+func encodeKVNode(pair kv) (kn kvNode, err error) {
+	var node *errNode
+
+	kn.Key = pair.k
+	pair.v = redactKV(pair.k, pair.v)
+	if e, ok := pair.v.(error); ok && e != nil {
+		kn.Type = kvTypeError
+		node, err = encodeErrNode(e)
+		if err != nil {
+			goto end
+		}
+		kn.Value, err = json.Marshal(node)
+		goto end
+	}
+	kn.Type = fmt.Sprintf("%T", pair.v)
+	kn.Value, err = json.Marshal(pair.v)
+	if err != nil {
+		kn.Type = "string"
+		kn.Value, err = json.Marshal(fmt.Sprint(pair.v))
+	}
+end:
+	return kn, err
+}
+
+func decodeErrNode(node *errNode) (decoded error, err error) {
+	var errs []error
+	var e entry
+	var ok bool
+
+	if node == nil {
+		goto end
+	}
+	errs, err = decodeErrNodes(node.Errors)
+	if err != nil {
+		goto end
+	}
+	switch node.Kind {
+	case errKindEntry:
+		e = entry{id: uniqueId, errors: errs}
+		e.kvs, err = decodeKVNodes(node.KVs)
+		decoded = e
+	case errKindCombined:
+		decoded = combined{errs: errs}
+	case errKindJoin, errKindWrap:
+		decoded = decodedErr{msg: node.Message, errs: errs}
+	case errKindError:
+		decoded, ok = lookupSentinel(node.Code, node.Message)
+		if !ok {
+			decoded = decodedErr{msg: node.Message}
+		}
+	default:
+		err = NewErr(ErrInvalidArgumentType, "kind", node.Kind)
+	}
+end:
+	return decoded, err
+}
+
+func decodeErrNodes(nodes []*errNode) (errs []error, err error) {
+	var e error
+
+	for _, node := range nodes {
+		e, err = decodeErrNode(node)
+		if err != nil {
+			goto end
+		}
+		if e != nil {
+			errs = append(errs, e)
+		}
+	}
+end:
+	return errs, err
+}
+
+func decodeKVNodes(nodes []kvNode) (kvs []kv, err error) {
+	var v any
+	var node *errNode
+
+	for _, kn := range nodes {
+		if kn.Type == kvTypeError {
+			err = json.Unmarshal(kn.Value, &node)
+			if err == nil {
+				v, err = decodeErrNode(node)
+			}
+		} else {
+			v, err = decodeKVValue(kn.Type, kn.Value)
+		}
+		if err != nil {
+			err = NewErr(ErrFailedToUnmarshalJSON, "key", kn.Key, "type", kn.Type, err)
+			goto end
+		}
+		kvs = append(kvs, kv{k: kn.Key, v: v})
+	}
+end:
+	return kvs, err
+}
+
+// decodedErr stands in for an error that was not a registered sentinel or a
+// doterr entry; it keeps the original message and children.
+type decodedErr struct {
+	msg  string
+	errs []error
+}
+
+func (d decodedErr) Error() string   { return d.msg }
+func (d decodedErr) Unwrap() []error { return d.errs }
+
+var kvTypes = struct {
+	mu      sync.RWMutex
+	decoder map[string]func(json.RawMessage) (any, error)
+}{
+	decoder: make(map[string]func(json.RawMessage) (any, error)),
+}
+
+// RegisterErrKVType lets UnmarshalErrJSON restore KV values of type T, so
+// ErrValue[T] finds them. Built-in numeric, string and bool types,
+// time.Time, time.Duration, []string and dt's string types are registered
+// already. Values of unregistered types decode as encoding/json decodes into
+// an any.
+func RegisterErrKVType[T any]() {
+	kvTypes.mu.Lock()
+	defer kvTypes.mu.Unlock()
+	kvTypes.decoder[reflect.TypeFor[T]().String()] = func(data json.RawMessage) (any, error) {
+		var v T
+		err := json.Unmarshal(data, &v)
+		return v, err
+	}
+}
+
+func decodeKVValue(typ string, data json.RawMessage) (v any, err error) {
+	kvTypes.mu.RLock()
+	decode, ok := kvTypes.decoder[typ]
+	kvTypes.mu.RUnlock()
+	if ok {
+		v, err = decode(data)
+		goto end
+	}
+	err = json.Unmarshal(data, &v)
+end:
+	return v, err
+}
+
+var _ = func() bool {
+	RegisterErrKVType[string]()
+	RegisterErrKVType[bool]()
+	RegisterErrKVType[int]()
+	RegisterErrKVType[int8]()
+	RegisterErrKVType[int16]()
+	RegisterErrKVType[int32]()
+	RegisterErrKVType[int64]()
+	RegisterErrKVType[uint]()
+	RegisterErrKVType[uint8]()
+	RegisterErrKVType[uint16]()
+	RegisterErrKVType[uint32]()
+	RegisterErrKVType[uint64]()
+	RegisterErrKVType[float32]()
+	RegisterErrKVType[float64]()
+	RegisterErrKVType[time.Time]()
+	RegisterErrKVType[time.Duration]()
+	RegisterErrKVType[[]string]()
+	RegisterErrKVType[DirPath]()
+	RegisterErrKVType[EntryPath]()
+	RegisterErrKVType[Filepath]()
+	RegisterErrKVType[RelFilepath]()
+	RegisterErrKVType[RelPath]()
+	RegisterErrKVType[Filename]()
+	RegisterErrKVType[FileExt]()
+	RegisterErrKVType[PathSegment]()
+	RegisterErrKVType[PathSegments]()
+	RegisterErrKVType[TildeDirPath]()
+	RegisterErrKVType[TildeEntryPath]()
+	RegisterErrKVType[TildeFilepath]()
+	RegisterErrKVType[URL]()
+	RegisterErrKVType[URLSegment]()
+	RegisterErrKVType[URLSegments]()
+	RegisterErrKVType[Identifier]()
+	RegisterErrKVType[Version]()
+	RegisterErrKVType[InternetDomain]()
+	RegisterErrKVType[Redacted]()
+	RegisterErrKVType[ErrStack]()
+	return true
+}()
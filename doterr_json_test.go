@@ -0,0 +1,127 @@
+package dt_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+var (
+	errTestRegistered = errors.New("registered failure")
+	errTestCoded      = errors.New("coded failure")
+	errTestUnknown    = errors.New("unknown failure")
+)
+
+func TestErrJSON_RoundTrip(t *testing.T) {
+	dt.RegisterErr(errTestRegistered)
+	dt.RegisterErr(errTestCoded, dt.ErrSpec{Code: "test.coded"})
+
+	when := time.Date(2025, 3, 14, 9, 26, 53, 0, time.UTC)
+	cause := fmt.Errorf("reading: %w", errTestUnknown)
+	inner := dt.NewErr(errTestCoded, "file", dt.Filepath("a.txt"), "size", 3, cause)
+	err := dt.NewErr(errTestRegistered,
+		"when", when,
+		"ratio", 0.5,
+		"ok", true,
+		"elapsed", 2*time.Second,
+		"inner", dt.NewErr(errTestUnknown, "n", int64(7)),
+		dt.CombineErrs([]error{inner, errors.Join(errTestUnknown, errTestCoded)}),
+	)
+
+	data, e := dt.MarshalErrJSON(err)
+	if e != nil {
+		t.Fatalf("MarshalErrJSON() error = %v", e)
+	}
+	if !json.Valid(data) {
+		t.Fatalf("MarshalErrJSON() = %s, not valid JSON", data)
+	}
+	got, e := dt.UnmarshalErrJSON(data)
+	if e != nil {
+		t.Fatalf("UnmarshalErrJSON(%s) error = %v", data, e)
+	}
+
+	if got.Error() != err.Error() {
+		t.Errorf("Error() = %q, want %q", got.Error(), err.Error())
+	}
+	if !errors.Is(got, errTestRegistered) {
+		t.Errorf("errors.Is(got, errTestRegistered) = false")
+	}
+	if !errors.Is(got, errTestCoded) {
+		t.Errorf("errors.Is(got, errTestCoded) = false")
+	}
+	if errors.Is(got, errTestUnknown) {
+		t.Errorf("errors.Is(got, errTestUnknown) = true for an unregistered sentinel")
+	}
+	if v, ok := dt.ErrValue[time.Time](got, "when"); !ok || !v.Equal(when) {
+		t.Errorf("ErrValue[time.Time](when) = %v, %v", v, ok)
+	}
+	if v, ok := dt.ErrValue[float64](got, "ratio"); !ok || v != 0.5 {
+		t.Errorf("ErrValue[float64](ratio) = %v, %v", v, ok)
+	}
+	if v, ok := dt.ErrValue[bool](got, "ok"); !ok || !v {
+		t.Errorf("ErrValue[bool](ok) = %v, %v", v, ok)
+	}
+	if v, ok := dt.ErrValue[time.Duration](got, "elapsed"); !ok || v != 2*time.Second {
+		t.Errorf("ErrValue[time.Duration](elapsed) = %v, %v", v, ok)
+	}
+	if v, ok := dt.ErrValue[error](got, "inner"); !ok || v.Error() != "unknown failure; meta: n=7" {
+		t.Errorf("ErrValue[error](inner) = %v, %v", v, ok)
+	}
+	var combinedErr interface{ Unwrap() []error }
+	for _, child := range got.(interface{ Unwrap() []error }).Unwrap() {
+		if c, ok := child.(interface{ Unwrap() []error }); ok {
+			combinedErr = c
+		}
+	}
+	if combinedErr == nil || len(combinedErr.Unwrap()) != 2 {
+		t.Fatalf("combined cause not preserved: %#v", got)
+	}
+	innerGot := combinedErr.Unwrap()[0]
+	if v, ok := dt.ErrValue[dt.Filepath](innerGot, "file"); !ok || v != "a.txt" {
+		t.Errorf("ErrValue[dt.Filepath](file) = %v, %v", v, ok)
+	}
+	if v, ok := dt.ErrValue[int](innerGot, "size"); !ok || v != 3 {
+		t.Errorf("ErrValue[int](size) = %v, %v", v, ok)
+	}
+}
+
+func TestErrJSON_Code(t *testing.T) {
+	dt.RegisterErr(errTestCoded, dt.ErrSpec{Code: "test.coded"})
+
+	data, err := dt.MarshalErrJSON(dt.NewErr(errTestCoded))
+	if err != nil {
+		t.Fatalf("MarshalErrJSON() error = %v", err)
+	}
+	var tree struct {
+		Errors []struct {
+			Code string `json:"code"`
+		} `json:"errors"`
+	}
+	if err = json.Unmarshal(data, &tree); err != nil || len(tree.Errors) != 1 || tree.Errors[0].Code != "test.coded" {
+		t.Fatalf("MarshalErrJSON() = %s, want code test.coded", data)
+	}
+
+	// The code finds the sentinel even when its message has changed.
+	got, err := dt.UnmarshalErrJSON([]byte(`{"kind":"error","message":"old wording","code":"test.coded"}`))
+	if err != nil || !errors.Is(got, errTestCoded) {
+		t.Errorf("UnmarshalErrJSON() = %v, %v; want errTestCoded", got, err)
+	}
+}
+
+func TestErrJSON_Invalid(t *testing.T) {
+	if data, err := dt.MarshalErrJSON(nil); err != nil || string(data) != "null" {
+		t.Errorf("MarshalErrJSON(nil) = %s, %v; want null", data, err)
+	}
+	if got, err := dt.UnmarshalErrJSON([]byte("null")); got != nil || err != nil {
+		t.Errorf("UnmarshalErrJSON(null) = %v, %v; want nil, nil", got, err)
+	}
+	for _, data := range []string{`{`, `{"kind":"bogus"}`, `{"kind":"entry","kvs":[{"key":"n","type":"int","value":"x"}]}`} {
+		if _, err := dt.UnmarshalErrJSON([]byte(data)); !errors.Is(err, dt.ErrFailedToUnmarshalJSON) {
+			t.Errorf("UnmarshalErrJSON(%s) error = %v, want ErrFailedToUnmarshalJSON", data, err)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package dt
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// doterr_message.go renders errors as messages for end users from templates
+// that interpolate the entries' KVs, looked up in per-locale catalogs. Error()
+// is unaffected and stays the stable text for logs.
+
+// errCatalogs maps normalized locales to templates keyed by error code or
+// sentinel message.
+var errCatalogs = struct {
+	mu       sync.RWMutex
+	byLocale map[string]map[string][]templatePart
+}{
+	byLocale: make(map[string]map[string][]templatePart),
+}
+
+// RegisterErrMessages adds message templates for locale, such as "de" or
+// "ja-JP", to those registered before. Templates are keyed by the code given
+// to RegisterErr, such as "dt.failed_to_copy_file", or else by the sentinel's
+// message. Placeholders name KV keys, as in "cannot copy {source} to
+// {destination}"; "{{" and "}}" stand for literal braces. The locale ""
+// holds the default templates, used when a locale has none.
+//
+// RegisterErrMessages registers nothing if any template is malformed.
+func RegisterErrMessages(locale string, templates map[string]string) (err error) {
+	var parsed map[string][]templatePart
+	var parts []templatePart
+
+	parsed = make(map[string][]templatePart, len(templates))
+	for key, tmpl := range templates {
+		parts, err = parseErrTemplate(tmpl)
+		if err != nil {
+			err = WithErr(err, "locale", locale, "key", key)
+			goto end
+		}
+		parsed[key] = parts
+	}
+	locale = normalizeLocale(locale)
+	errCatalogs.mu.Lock()
+	defer errCatalogs.mu.Unlock()
+	if errCatalogs.byLocale[locale] == nil {
+		errCatalogs.byLocale[locale] = make(map[string][]templatePart, len(parsed))
+	}
+	for key, parts := range parsed {
+		errCatalogs.byLocale[locale][key] = parts
+	}
+end:
+	return err
+}
+
+// parseErrTemplate splits tmpl into literals and placeholders.
+func parseErrTemplate(tmpl string) (parts []templatePart, err error) {
+	var lit strings.Builder
+	var end int
+
+	for i := 0; i < len(tmpl); i++ {
+		switch {
+		case strings.HasPrefix(tmpl[i:], "{{"), strings.HasPrefix(tmpl[i:], "}}"):
+			lit.WriteByte(tmpl[i])
+			i++
+		case tmpl[i] == '}':
+			err = NewErr(ErrInvalidErrTemplate, "template", tmpl, "position", i)
+			goto end
+		case tmpl[i] == '{':
+			end = strings.IndexByte(tmpl[i:], '}')
+			if end <= 1 || strings.ContainsRune(tmpl[i+1:i+end], '{') {
+				err = NewErr(ErrInvalidErrTemplate, "template", tmpl, "position", i)
+				goto end
+			}
+			if lit.Len() > 0 {
+				parts = append(parts, templatePart{literal: lit.String()})
+				lit.Reset()
+			}
+			parts = append(parts, templatePart{name: tmpl[i+1 : i+end]})
+			i += end
+		default:
+			lit.WriteByte(tmpl[i])
+		}
+	}
+	if lit.Len() > 0 {
+		parts = append(parts, templatePart{literal: lit.String()})
+	}
+end:
+	return parts, err
+}
+
+// ErrMessage returns err as a message for end users in locale, such as "de"
+// or "ja-JP". Each sentinel renders from the first template registered with
+// RegisterErrMessages whose placeholders the KVs of its entry, or of an outer
+// entry, can fill. Templates are tried for the locale, then its language,
+// such as "de" for "de-CH", then the default locale "", and then the
+// sentinel's own message is used. An entry's sentinels and causes are joined
+// with ": " and the members of joined errors with "; ". Redacted values stay
+// redacted. ErrMessage returns "" for a nil error.
+func ErrMessage(err error, locale string) string {
+	r := errMessageRenderer{locales: localeChain(locale)}
+	errCatalogs.mu.RLock()
+	defer errCatalogs.mu.RUnlock()
+	return r.render(err, nil)
+}
+
+type errMessageRenderer struct {
+	locales []string
+}
+
+// render returns the message for err. scope holds the KVs of the entries
+// enclosing err, innermost first.
+func (r errMessageRenderer) render(err error, scope []kv) (msg string) {
+	var msgs []string
+	var sep string
+
+	switch e := err.(type) {
+	case nil:
+		goto end
+	case *entry:
+		msg = r.render(*e, scope)
+		goto end
+	case entry:
+		scope = append(slices.Clone(e.kvs), scope...)
+		sep = ": "
+		for _, child := range e.errors {
+			msgs = append(msgs, r.render(child, scope))
+		}
+	case interface{ Unwrap() []error }:
+		sep = "; "
+		for _, child := range e.Unwrap() {
+			msgs = append(msgs, r.render(child, scope))
+		}
+	default:
+		msg = r.leaf(err, scope)
+		goto end
+	}
+	msgs = slices.DeleteFunc(msgs, func(m string) bool { return m == "" })
+	msg = strings.Join(msgs, sep)
+end:
+	return msg
+}
+
+// leaf returns the message for an error that is not an entry or a join.
+func (r errMessageRenderer) leaf(err error, scope []kv) (msg string) {
+	var keys []string
+	var ok bool
+
+	if reg, found := registeredSentinel(err); found && reg.spec.Code != "" {
+		keys = append(keys, reg.spec.Code)
+	}
+	keys = append(keys, err.Error())
+	for _, locale := range r.locales {
+		catalog := errCatalogs.byLocale[locale]
+		for _, key := range keys {
+			parts, found := catalog[key]
+			if !found {
+				continue
+			}
+			msg, ok = fillErrTemplate(parts, scope)
+			if ok {
+				goto end
+			}
+		}
+	}
+	msg = err.Error()
+end:
+	return msg
+}
+
+// fillErrTemplate renders parts with the first value in scope for each
+// placeholder, reporting false if one has none.
+func fillErrTemplate(parts []templatePart, scope []kv) (msg string, ok bool) {
+	var sb strings.Builder
+
+	for _, p := range parts {
+		if p.name == "" {
+			sb.WriteString(p.literal)
+			continue
+		}
+		v, found := lookupKV(scope, p.name)
+		if !found {
+			goto end
+		}
+		sb.WriteString(fmt.Sprint(redactKV(p.name, v)))
+	}
+	msg, ok = sb.String(), true
+end:
+	return msg, ok
+}
+
+func lookupKV(kvs []kv, key string) (v any, ok bool) {
+	for _, pair := range kvs {
+		if pair.k == key {
+			v, ok = pair.v, true
+			break
+		}
+	}
+	return v, ok
+}
+
+// localeChain returns locale normalized, followed by its less specific forms
+// and the default locale "", as in "de-ch", "de", "".
+func localeChain(locale string) (chain []string) {
+	locale = normalizeLocale(locale)
+	for locale != "" {
+		chain = append(chain, locale)
+		i := strings.LastIndexByte(locale, '-')
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+	return append(chain, "")
+}
+
+// normalizeLocale lowercases locale and uses "-" as its separator, so "de_CH"
+// and "de-ch" are the same locale.
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
@@ -0,0 +1,95 @@
+package dt_test
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+var errTestCopyMsg = errors.New("copy failed")
+
+func TestErrMessage(t *testing.T) {
+	dt.RegisterErr(errTestCopyMsg, dt.ErrSpec{Code: "test.copy_failed"})
+	catalogs := map[string]map[string]string{
+		"": {
+			"test.copy_failed": "cannot copy {source} to {destination}",
+		},
+		"de": {
+			"test.copy_failed": "{source} kann nicht nach {destination} kopiert werden",
+			"fs.not_exist":     "Datei existiert nicht",
+		},
+		"ja": {
+			"test.copy_failed":    "{source} を {destination} にコピーできません",
+			"file does not exist": "ファイルが存在しません",
+		},
+	}
+	for locale, templates := range catalogs {
+		if err := dt.RegisterErrMessages(locale, templates); err != nil {
+			t.Fatalf("RegisterErrMessages(%q) = %v", locale, err)
+		}
+	}
+
+	err := dt.NewErr(errTestCopyMsg, "source", "a.txt", "destination", "b.txt",
+		dt.NewErr(fs.ErrNotExist, "path", "a.txt"),
+	)
+	tests := []struct {
+		name   string
+		err    error
+		locale string
+		want   string
+	}{
+		{name: "nil", err: nil, want: ""},
+		{name: "default", err: err, want: "cannot copy a.txt to b.txt: file does not exist"},
+		{name: "language", err: err, locale: "de", want: "a.txt kann nicht nach b.txt kopiert werden: Datei existiert nicht"},
+		{name: "region falls back to language", err: err, locale: "de_CH", want: "a.txt kann nicht nach b.txt kopiert werden: Datei existiert nicht"},
+		{name: "keyed by message", err: err, locale: "ja-JP", want: "a.txt を b.txt にコピーできません: ファイルが存在しません"},
+		{name: "unknown locale", err: err, locale: "fr", want: "cannot copy a.txt to b.txt: file does not exist"},
+		{
+			name:   "missing placeholder falls back to sentinel",
+			err:    dt.NewErr(errTestCopyMsg, "source", "a.txt"),
+			locale: "de",
+			want:   "copy failed",
+		},
+		{
+			name:   "placeholder from outer entry",
+			err:    dt.NewErr(dt.ErrFailedToCopyFile, "source", "x", "destination", "y", dt.NewErr(errTestCopyMsg)),
+			locale: "de",
+			want:   "failed to copy file: x kann nicht nach y kopiert werden",
+		},
+		{
+			name: "joined",
+			err:  dt.CombineErrs([]error{dt.NewErr(fs.ErrNotExist), fs.ErrPermission}),
+			want: "file does not exist; permission denied",
+		},
+		{
+			name: "redacted",
+			err:  dt.NewErr(errTestCopyMsg, dt.SecretKV("source", "secret.txt"), "destination", "b.txt"),
+			want: "cannot copy [REDACTED] to b.txt",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dt.ErrMessage(tt.err, tt.locale); got != tt.want {
+				t.Errorf("ErrMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if got := err.Error(); !strings.HasPrefix(got, "copy failed; file does not exist") {
+		t.Errorf("Error() = %q, want the unlocalized message", got)
+	}
+}
+
+func TestRegisterErrMessagesInvalid(t *testing.T) {
+	for _, tmpl := range []string{"cannot copy {source", "cannot copy source}", "cannot copy {}"} {
+		err := dt.RegisterErrMessages("de", map[string]string{"test.invalid": tmpl})
+		if !errors.Is(err, dt.ErrInvalidErrTemplate) {
+			t.Errorf("RegisterErrMessages(%q) = %v, want ErrInvalidErrTemplate", tmpl, err)
+		}
+	}
+	if got := dt.ErrMessage(errors.New("test.invalid"), "de"); got != "test.invalid" {
+		t.Errorf("malformed template was registered: %q", got)
+	}
+}
@@ -0,0 +1,195 @@
+package dt
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// doterr_recover.go turns panics back into doterr errors, so that a panic in
+// a worker goroutine fails its task instead of the process.
+
+// Keys of the KVs recording a recovered panic.
+const (
+	PanicKey = "panic"
+	StackKey = "stack"
+)
+
+// ErrStack is a goroutine stack trace, as from runtime/debug.Stack. It prints
+// as the frame that panicked, keeping Error() to one line; convert it to a
+// string for the full trace.
+type ErrStack string
+
+// String returns the function and location that panicked.
+func (s ErrStack) String() (frame string) {
+	var lines []string
+	var start int
+
+	// The trace is a header line and then two lines per frame: the function
+	// and, indented, its location
+	lines = strings.Split(strings.TrimSpace(string(s)), "\n")
+	if len(lines) < 3 {
+		frame = strings.TrimSpace(string(s))
+		goto end
+	}
+	start = 1
+	for i := 1; i+1 < len(lines); i += 2 {
+		if strings.HasPrefix(lines[i], "panic(") {
+			start = i + 2
+			break
+		}
+	}
+	for i := start; i+1 < len(lines); i += 2 {
+		// Skip runtime frames such as runtime.panicmem
+		if strings.HasPrefix(lines[i], "runtime.") && i+3 < len(lines) {
+			continue
+		}
+		frame = fmt.Sprintf("%s (%s)", trimStackFunc(lines[i]), trimStackLocation(lines[i+1]))
+		goto end
+	}
+	frame = lines[0]
+end:
+	return frame
+}
+
+// trimStackFunc removes the arguments from a function line of a stack trace.
+func trimStackFunc(line string) string {
+	if i := strings.LastIndexByte(line, '('); i > 0 {
+		line = line[:i]
+	}
+	return line
+}
+
+// trimStackLocation removes the indent and program counter offset from a
+// location line of a stack trace.
+func trimStackLocation(line string) string {
+	line = strings.TrimSpace(line)
+	if i := strings.LastIndex(line, " +0x"); i > 0 {
+		line = line[:i]
+	}
+	return line
+}
+
+// Recover converts a panic into an error stored in *errp. Defer it directly:
+//
+//	func process() (err error) {
+//		defer dt.Recover(&err)
+//		...
+//	}
+//
+// The error is an entry with ErrInternalError, the panic value under PanicKey
+// and the stack under StackKey as an ErrStack. A panic value that is an error
+// is also its cause, so errors.Is sees it. An error already in *errp is kept
+// after the panic's.
+func Recover(errp *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	err := panicErr(r, debug.Stack())
+	if *errp != nil {
+		err = CombineErrs([]error{err, *errp})
+	}
+	*errp = err
+}
+
+// panicErr returns the error Recover reports for the panic value r.
+func panicErr(r any, stack []byte) error {
+	parts := []any{ErrInternalError, PanicKey, r, StackKey, ErrStack(stack)}
+	if t := reflect.TypeOf(r); t != nil && !t.Comparable() {
+		// KV values are compared with ==, which panics for these
+		parts[2] = fmt.Sprint(r)
+	}
+	if err, ok := r.(error); ok {
+		parts = append(parts, err)
+	}
+	return NewErr(parts...)
+}
+
+// Go runs fn in a new goroutine and returns a channel that receives its
+// error, or nil, once fn returns. A panic in fn is recovered as by Recover.
+func Go(fn func() error) <-chan error {
+	ch := make(chan error, 1)
+	go func() {
+		ch <- runRecovered(fn)
+	}()
+	return ch
+}
+
+func runRecovered(fn func() error) (err error) {
+	defer Recover(&err)
+	err = fn()
+	return err
+}
+
+// GoGroup runs functions in goroutines and collects their errors, like
+// golang.org/x/sync/errgroup, except that panics are recovered as by Recover
+// and Wait returns every error rather than the first. The zero value is ready
+// to use; see NewGoGroup for one that cancels a context on the first error.
+type GoGroup struct {
+	// Errs collects the errors; set its Max before the first call to Go to
+	// bound the errors stored.
+	Errs ErrCollector
+
+	wg     sync.WaitGroup
+	cancel context.CancelCauseFunc
+	once   sync.Once
+
+	mu   sync.Mutex
+	lost []error // errors that panicked when added to Errs
+}
+
+// NewGoGroup returns a GoGroup and a context derived from ctx that is
+// canceled, with the error as its cause, when a function returns an error or
+// panics, or when Wait returns.
+func NewGoGroup(ctx context.Context) (*GoGroup, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &GoGroup{cancel: cancel}, ctx
+}
+
+// Go runs fn in a new goroutine.
+func (g *GoGroup) Go(fn func() error) {
+	g.wg.Go(func() {
+		err := runRecovered(fn)
+		if err != nil {
+			g.fail(err)
+		}
+	})
+}
+
+// fail records err and cancels the group's context. Recording is recovered
+// too, so that no error a function returns can crash the process; an error
+// that cannot be recorded is kept, with the panic, for Wait.
+func (g *GoGroup) fail(err error) {
+	if g.cancel != nil {
+		g.once.Do(func() { g.cancel(err) })
+	}
+	recErr := runRecovered(func() error {
+		g.Errs.Add(err)
+		return nil
+	})
+	if recErr != nil {
+		g.mu.Lock()
+		g.lost = append(g.lost, recErr, err)
+		g.mu.Unlock()
+	}
+}
+
+// Wait waits for all functions to return and returns their errors, as
+// ErrCollector.Err does, or nil if none failed.
+func (g *GoGroup) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel(nil)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.lost) > 0 {
+		return CombineErrs(append(slices.Clone(g.lost), g.Errs.Err()))
+	}
+	return g.Errs.Err()
+}
@@ -0,0 +1,127 @@
+package dt_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+func panicking(v any) (err error) {
+	defer dt.Recover(&err)
+	panic(v)
+}
+
+func TestRecover(t *testing.T) {
+	t.Run("string", func(t *testing.T) {
+		err := panicking("boom")
+		if !errors.Is(err, dt.ErrInternalError) {
+			t.Fatalf("Recover() = %v, want ErrInternalError", err)
+		}
+		if v, _ := dt.ErrValue[string](err, dt.PanicKey); v != "boom" {
+			t.Errorf("panic = %q, want %q", v, "boom")
+		}
+		stack, _ := dt.ErrValue[dt.ErrStack](err, dt.StackKey)
+		if !strings.Contains(string(stack), "goroutine ") {
+			t.Errorf("stack = %q, want a goroutine trace", stack)
+		}
+		if got := stack.String(); !strings.HasPrefix(got, "github.com/mikeschinkel/go-dt_test.panicking (") {
+			t.Errorf("stack.String() = %q, want the panicking frame", got)
+		}
+		if strings.Contains(err.Error(), "\n") {
+			t.Errorf("Error() spans lines: %q", err.Error())
+		}
+	})
+
+	t.Run("error value", func(t *testing.T) {
+		err := panicking(fs.ErrClosed)
+		if !errors.Is(err, dt.ErrInternalError) || !errors.Is(err, fs.ErrClosed) {
+			t.Errorf("Recover() = %v, want ErrInternalError and fs.ErrClosed", err)
+		}
+	})
+
+	t.Run("runtime error", func(t *testing.T) {
+		err := func() (err error) {
+			defer dt.Recover(&err)
+			var m map[string]int
+			m["x"] = 1
+			return nil
+		}()
+		var re runtime.Error
+		if !errors.As(err, &re) {
+			t.Errorf("Recover() = %v, want a runtime.Error cause", err)
+		}
+	})
+
+	t.Run("uncomparable value", func(t *testing.T) {
+		err := panicking([]string{"a", "b"})
+		if v, _ := dt.ErrValue[string](err, dt.PanicKey); v != "[a b]" {
+			t.Errorf("panic = %q, want %q", v, "[a b]")
+		}
+	})
+
+	t.Run("no panic", func(t *testing.T) {
+		err := func() (err error) {
+			defer dt.Recover(&err)
+			return fs.ErrExist
+		}()
+		if err != fs.ErrExist {
+			t.Errorf("Recover() = %v, want fs.ErrExist unchanged", err)
+		}
+	})
+}
+
+func TestGo(t *testing.T) {
+	if err := <-dt.Go(func() error { return nil }); err != nil {
+		t.Errorf("Go() = %v, want nil", err)
+	}
+	if err := <-dt.Go(func() error { panic("boom") }); !errors.Is(err, dt.ErrInternalError) {
+		t.Errorf("Go() = %v, want ErrInternalError", err)
+	}
+}
+
+func TestGoGroup(t *testing.T) {
+	t.Run("collects errors and panics", func(t *testing.T) {
+		var g dt.GoGroup
+		g.Go(func() error { return nil })
+		g.Go(func() error { return fs.ErrPermission })
+		g.Go(func() error { panic("boom") })
+		err := g.Wait()
+		if !errors.Is(err, fs.ErrPermission) || !errors.Is(err, dt.ErrInternalError) {
+			t.Errorf("Wait() = %v, want both errors", err)
+		}
+	})
+
+	t.Run("cancels context", func(t *testing.T) {
+		g, ctx := dt.NewGoGroup(context.Background())
+		g.Go(func() error { return fs.ErrPermission })
+		g.Go(func() error {
+			<-ctx.Done()
+			return nil
+		})
+		if err := g.Wait(); err != fs.ErrPermission {
+			t.Errorf("Wait() = %v, want fs.ErrPermission", err)
+		}
+		if cause := context.Cause(ctx); cause != fs.ErrPermission {
+			t.Errorf("context.Cause() = %v, want fs.ErrPermission", cause)
+		}
+	})
+	t.Run("non-comparable errors", func(t *testing.T) {
+		var g dt.GoGroup
+		for range 3 {
+			g.Go(func() error { return listErr{items: []string{"a"}} })
+		}
+		g.Go(func() error { panic(listErr{items: []string{"b"}}) })
+		err := g.Wait()
+		if !errors.As(err, new(listErr)) || !errors.Is(err, dt.ErrInternalError) {
+			t.Errorf("Wait() = %v, want listErr and ErrInternalError", err)
+		}
+		if g.Errs.Len() != 4 {
+			t.Errorf("Errs.Len() = %d, want 4", g.Errs.Len())
+		}
+	})
+}
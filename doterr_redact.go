@@ -0,0 +1,164 @@
+package dt
+
+import (
+	"encoding/json"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// doterr_redact.go masks sensitive KV values wherever an error is rendered:
+// Error(), %+v, slog, JSON and FormatErrTree. ErrValue still returns the
+// original value to code that asks for it by key.
+
+// RedactedText replaces redacted values in output.
+const RedactedText = "[REDACTED]"
+
+// Redacted holds a sensitive KV value. It prints, logs and serializes as
+// RedactedText; ErrValue returns the original value. Values decoded by
+// UnmarshalErrJSON are lost, as they were never serialized.
+type Redacted struct {
+	// A pointer keeps the value out of %#v output, which prints unexported
+	// fields without calling their methods, and keeps Redacted comparable.
+	secret *secret
+}
+
+type secret struct {
+	value any
+}
+
+var (
+	_ slog.LogValuer   = Redacted{}
+	_ json.Marshaler   = Redacted{}
+	_ json.Unmarshaler = (*Redacted)(nil)
+)
+
+// SecretKV creates a KV whose value is redacted in output.
+func SecretKV(key string, value any) ErrKV {
+	return kv{k: key, v: newRedacted(value)}
+}
+
+func newRedacted(value any) Redacted {
+	return Redacted{secret: &secret{value: value}}
+}
+
+// Value returns the original value.
+func (r Redacted) Value() (v any) {
+	if r.secret != nil {
+		v = r.secret.value
+	}
+	return v
+}
+
+func (r Redacted) String() string {
+	return RedactedText
+}
+
+func (r Redacted) GoString() string {
+	return "dt.Redacted{}"
+}
+
+func (r Redacted) LogValue() slog.Value {
+	return slog.StringValue(RedactedText)
+}
+
+func (r Redacted) MarshalJSON() ([]byte, error) {
+	return json.Marshal(RedactedText)
+}
+
+func (r *Redacted) UnmarshalJSON([]byte) error {
+	r.secret = nil
+	return nil
+}
+
+// ErrRedaction configures the redaction applied to every error.
+type ErrRedaction struct {
+	// Keys are path.Match patterns, such as "password" or "*_token", for KV
+	// keys whose values are redacted as if added with SecretKV. Matching
+	// ignores case.
+	Keys []string
+
+	// TildePaths renders DirPath, Filepath, EntryPath and absolute string
+	// values under the current user's home directory as "~/...".
+	TildePaths bool
+}
+
+var errRedaction atomic.Pointer[ErrRedaction]
+
+// SetErrRedaction sets the redaction applied when errors are rendered and
+// returns the previous one. It affects errors created before the call too.
+func SetErrRedaction(r ErrRedaction) (prev ErrRedaction) {
+	r.Keys = lowerAll(r.Keys)
+	if p := errRedaction.Swap(&r); p != nil {
+		prev = *p
+	}
+	return prev
+}
+
+// GetErrRedaction returns the redaction set with SetErrRedaction.
+func GetErrRedaction() (r ErrRedaction) {
+	if p := errRedaction.Load(); p != nil {
+		r = *p
+	}
+	return r
+}
+
+// redactKV returns v as it should be rendered for key.
+func redactKV(key string, v any) any {
+	var home DirPath
+	var err error
+
+	r := errRedaction.Load()
+	if r == nil {
+		goto end
+	}
+	if _, ok := v.(Redacted); ok {
+		goto end
+	}
+	key = strings.ToLower(key)
+	for _, pattern := range r.Keys {
+		if matched, _ := path.Match(pattern, key); matched {
+			v = newRedacted(v)
+			goto end
+		}
+	}
+	if !r.TildePaths {
+		goto end
+	}
+	home, err = GetHomeDirProvider().HomeDir()
+	if err != nil || home == "" {
+		goto end
+	}
+	switch p := v.(type) {
+	case DirPath:
+		v = DirPathToTilde(p, OrFullPath, StaticHomeDirs{Current: home})
+	case Filepath:
+		v = FilepathToTilde(p, OrFullPath, StaticHomeDirs{Current: home})
+	case EntryPath:
+		v = EntryPathToTilde(p, OrFullPath, StaticHomeDirs{Current: home})
+	case string:
+		if filepath.IsAbs(p) {
+			v = string(EntryPathToTilde(EntryPath(p), OrFullPath, StaticHomeDirs{Current: home}))
+		}
+	}
+end:
+	return v
+}
+
+// unredact returns the original of a Redacted value.
+func unredact(v any) any {
+	if r, ok := v.(Redacted); ok {
+		return r.Value()
+	}
+	return v
+}
+
+func lowerAll(ss []string) []string {
+	out := make([]string, len(ss))
+	for i, s := range ss {
+		out[i] = strings.ToLower(s)
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package dt_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+func renderErr(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Error("failed", "err", err)
+	data, e := dt.MarshalErrJSON(err)
+	if e != nil {
+		t.Fatalf("MarshalErrJSON() error = %v", e)
+	}
+	return map[string]string{
+		"Error":         err.Error(),
+		"%+v":           fmt.Sprintf("%+v", err),
+		"%#v":           fmt.Sprintf("%#v", dt.ErrMeta(err)),
+		"slog":          buf.String(),
+		"JSON":          string(data),
+		"FormatErrTree": dt.FormatErrTree(err),
+	}
+}
+
+func TestSecretKV(t *testing.T) {
+	err := dt.NewErr(errTestOuter, dt.SecretKV("dsn", "postgres://u:hunter2@db"), "user", "alice")
+
+	for name, out := range renderErr(t, err) {
+		if strings.Contains(out, "hunter2") {
+			t.Errorf("%s output leaks the secret: %s", name, out)
+		}
+		if name != "%#v" && !strings.Contains(out, dt.RedactedText) {
+			t.Errorf("%s output = %s, want %s", name, out, dt.RedactedText)
+		}
+		if !strings.Contains(out, "alice") {
+			t.Errorf("%s output = %s, want other KVs unchanged", name, out)
+		}
+	}
+	if v, ok := dt.ErrValue[string](err, "dsn"); !ok || v != "postgres://u:hunter2@db" {
+		t.Errorf("ErrValue(dsn) = %q, %v; want original value", v, ok)
+	}
+
+	// The secret is not serialized, so it cannot be recovered from JSON.
+	data, _ := dt.MarshalErrJSON(err)
+	got, e := dt.UnmarshalErrJSON(data)
+	if e != nil {
+		t.Fatalf("UnmarshalErrJSON() error = %v", e)
+	}
+	if v, ok := dt.ErrValue[string](got, "dsn"); ok {
+		t.Errorf("ErrValue(dsn) after round trip = %q, want not found", v)
+	}
+}
+
+func TestSetErrRedaction(t *testing.T) {
+	home := filepath.FromSlash("/home/alice")
+	defer dt.SetHomeDirProvider(dt.SetHomeDirProvider(dt.StaticHomeDirs{Current: dt.DirPath(home)}))
+	defer dt.SetErrRedaction(dt.SetErrRedaction(dt.ErrRedaction{
+		Keys:       []string{"*_token", "Password"},
+		TildePaths: true,
+	}))
+
+	cfg := dt.Filepath(filepath.Join(home, ".config", "app.json"))
+	err := dt.NewErr(errTestOuter,
+		"api_token", "abc123",
+		"password", "hunter2",
+		"config", cfg,
+		"other", filepath.FromSlash("/etc/app.json"),
+	)
+	wantTilde := filepath.Join("~", ".config", "app.json")
+	for name, out := range renderErr(t, err) {
+		if name == "%#v" {
+			continue
+		}
+		if strings.Contains(out, "abc123") || strings.Contains(out, "hunter2") {
+			t.Errorf("%s output leaks a secret: %s", name, out)
+		}
+		wantPath := wantTilde
+		if name == "slog" || name == "JSON" {
+			wantPath = strings.ReplaceAll(wantPath, `\`, `\\`)
+		}
+		if !strings.Contains(out, wantPath) || strings.Contains(out, string(cfg)) {
+			t.Errorf("%s output = %s, want %s", name, out, wantTilde)
+		}
+	}
+	if v, ok := dt.ErrValue[string](err, "api_token"); !ok || v != "abc123" {
+		t.Errorf("ErrValue(api_token) = %q, %v; want original value", v, ok)
+	}
+	if v, ok := dt.ErrValue[dt.Filepath](err, "config"); !ok || v != cfg {
+		t.Errorf("ErrValue(config) = %q, %v; want %q", v, ok, cfg)
+	}
+}
+
+func TestSecretKV_Nested(t *testing.T) {
+	token := dt.SecretKV("token", "abc123")
+	err := dt.NewErr(errTestOuter, token, dt.NewErr(errTestInner, token))
+	if got := err.Error(); strings.Contains(got, "abc123") || strings.Count(got, dt.RedactedText) != 1 {
+		t.Errorf("Error() = %q, want the secret redacted once", got)
+	}
+}
@@ -0,0 +1,298 @@
+package dt
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// ErrCategory is a broad class of failure, used to derive exit codes and
+// HTTP status codes.
+type ErrCategory string
+
+const (
+	ErrCategoryNotFound     ErrCategory = "not-found"
+	ErrCategoryInvalidInput ErrCategory = "invalid-input"
+	ErrCategoryPermission   ErrCategory = "permission"
+	ErrCategoryInternal     ErrCategory = "internal"
+)
+
+// ExitCode returns the process exit code for the category, following
+// sysexits.h, or 1 for an unknown category.
+func (c ErrCategory) ExitCode() (code int) {
+	switch c {
+	case ErrCategoryNotFound:
+		code = 66 // EX_NOINPUT
+	case ErrCategoryInvalidInput:
+		code = 65 // EX_DATAERR
+	case ErrCategoryPermission:
+		code = 77 // EX_NOPERM
+	case ErrCategoryInternal:
+		code = 70 // EX_SOFTWARE
+	default:
+		code = 1
+	}
+	return code
+}
+
+// HTTPStatus returns the HTTP status code for the category, or 500 for an
+// unknown category.
+func (c ErrCategory) HTTPStatus() (status int) {
+	switch c {
+	case ErrCategoryNotFound:
+		status = 404
+	case ErrCategoryInvalidInput:
+		status = 400
+	case ErrCategoryPermission:
+		status = 403
+	default:
+		status = 500
+	}
+	return status
+}
+
+// ErrSpec describes a registered sentinel.
+type ErrSpec struct {
+	// Code is a stable, machine-readable identifier for the sentinel that
+	// survives changes to its message.
+	Code string
+
+	// Category classifies the failure. Generic wrappers such as "failed to
+	// copy file" are ErrCategoryInternal, so a more specific cause decides
+	// the classification; see LookupErrSpec.
+	Category ErrCategory
+
+	// ExitCode and HTTPStatus override the category's defaults when non-zero.
+	ExitCode   int
+	HTTPStatus int
+}
+
+// exitCode returns the exit code for the spec.
+func (s ErrSpec) exitCode() int {
+	if s.ExitCode != 0 {
+		return s.ExitCode
+	}
+	return s.Category.ExitCode()
+}
+
+// httpStatus returns the HTTP status code for the spec.
+func (s ErrSpec) httpStatus() int {
+	if s.HTTPStatus != 0 {
+		return s.HTTPStatus
+	}
+	return s.Category.HTTPStatus()
+}
+
+type registeredErr struct {
+	sentinel error
+	spec     ErrSpec
+}
+
+var errRegistry struct {
+	mu         sync.RWMutex
+	list       []*registeredErr
+	bySentinel map[error]*registeredErr
+	byCode     map[string]*registeredErr
+	byMessage  map[string]*registeredErr
+}
+
+// RegisterErr records sentinel, with an optional spec, so that errors decoded
+// by UnmarshalErrJSON match it through errors.Is and so that ErrCode,
+// ExitCode and HTTPStatus can classify errors containing it. Decoding finds a
+// sentinel by its code, else by its message; of sentinels sharing a code or
+// message, the first registered is found. Registering a sentinel again
+// replaces its spec. dt registers its own sentinels, and fs.ErrNotExist,
+// fs.ErrExist, fs.ErrPermission and fs.ErrInvalid, with codes prefixed "dt."
+// and "fs.".
+func RegisterErr(sentinel error, spec ...ErrSpec) {
+	var r *registeredErr
+
+	if sentinel == nil || !reflect.TypeOf(sentinel).Comparable() {
+		panic("dt.RegisterErr: sentinel must be a non-nil, comparable error")
+	}
+	r = &registeredErr{sentinel: sentinel}
+	if len(spec) > 0 {
+		r.spec = spec[0]
+	}
+	errRegistry.mu.Lock()
+	defer errRegistry.mu.Unlock()
+	if errRegistry.bySentinel == nil {
+		errRegistry.bySentinel = make(map[error]*registeredErr)
+		errRegistry.byCode = make(map[string]*registeredErr)
+		errRegistry.byMessage = make(map[string]*registeredErr)
+	}
+	if prev, ok := errRegistry.bySentinel[sentinel]; ok {
+		if errRegistry.byCode[prev.spec.Code] == prev {
+			delete(errRegistry.byCode, prev.spec.Code)
+		}
+		*prev = *r
+		r = prev
+	} else {
+		errRegistry.list = append(errRegistry.list, r)
+	}
+	errRegistry.bySentinel[sentinel] = r
+	if _, ok := errRegistry.byCode[r.spec.Code]; !ok && r.spec.Code != "" {
+		errRegistry.byCode[r.spec.Code] = r
+	}
+	if _, ok := errRegistry.byMessage[sentinel.Error()]; !ok {
+		errRegistry.byMessage[sentinel.Error()] = r
+	}
+}
+
+// LookupErrSpec returns the spec that classifies err. It searches the error
+// tree outermost first, in order, and returns the first registered sentinel
+// with a category other than ErrCategoryInternal, else the first registered
+// sentinel. Errors that are not themselves registered, such as
+// syscall.Errno, match a registered sentinel when errors.Is says so.
+func LookupErrSpec(err error) (spec ErrSpec, ok bool) {
+	var first *registeredErr
+
+	walkErrTree(err, func(e error) bool {
+		r, found := matchRegistered(e)
+		if !found {
+			return true
+		}
+		if first == nil {
+			first = r
+		}
+		if r.spec.Category != "" && r.spec.Category != ErrCategoryInternal {
+			first = r
+			return false
+		}
+		return true
+	})
+	if first != nil {
+		spec, ok = first.spec, true
+	}
+	return spec, ok
+}
+
+// ErrCode returns the code of the spec that classifies err, or "" when no
+// registered sentinel is found. See LookupErrSpec.
+func ErrCode(err error) string {
+	spec, _ := LookupErrSpec(err)
+	return spec.Code
+}
+
+// ErrCategoryOf returns the category of the spec that classifies err, or ""
+// when no registered sentinel is found. See LookupErrSpec.
+func ErrCategoryOf(err error) ErrCategory {
+	spec, _ := LookupErrSpec(err)
+	return spec.Category
+}
+
+// ExitCode returns the process exit code for err: 0 for nil, the exit code
+// of the spec that classifies err, or 1 when no registered sentinel is found.
+func ExitCode(err error) (code int) {
+	var spec ErrSpec
+	var ok bool
+
+	if err == nil {
+		goto end
+	}
+	code = 1
+	spec, ok = LookupErrSpec(err)
+	if ok {
+		code = spec.exitCode()
+	}
+end:
+	return code
+}
+
+// HTTPStatus returns the HTTP status code for err: 200 for nil, the status of
+// the spec that classifies err, or 500 when no registered sentinel is found.
+func HTTPStatus(err error) (status int) {
+	var spec ErrSpec
+	var ok bool
+
+	status = 200
+	if err == nil {
+		goto end
+	}
+	status = 500
+	spec, ok = LookupErrSpec(err)
+	if ok {
+		status = spec.httpStatus()
+	}
+end:
+	return status
+}
+
+// walkErrTree calls fn for err and each error it wraps, outermost first, in
+// order, until fn returns false.
+func walkErrTree(err error, fn func(error) bool) (more bool) {
+	more = true
+	if err == nil {
+		goto end
+	}
+	more = fn(err)
+	if !more {
+		goto end
+	}
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, child := range e.Unwrap() {
+			more = walkErrTree(child, fn)
+			if !more {
+				goto end
+			}
+		}
+	case interface{ Unwrap() error }:
+		more = walkErrTree(e.Unwrap(), fn)
+	}
+end:
+	return more
+}
+
+// matchRegistered returns the registration of err itself or, for errors with
+// an Is method, of the first registered sentinel it reports a match for.
+func matchRegistered(err error) (r *registeredErr, ok bool) {
+	r, ok = registeredSentinel(err)
+	if ok {
+		goto end
+	}
+	if _, hasIs := err.(interface{ Is(error) bool }); !hasIs {
+		goto end
+	}
+	errRegistry.mu.RLock()
+	defer errRegistry.mu.RUnlock()
+	for _, reg := range errRegistry.list {
+		if errors.Is(err, reg.sentinel) {
+			r, ok = reg, true
+			break
+		}
+	}
+end:
+	return r, ok
+}
+
+// registeredSentinel returns the registration of err itself, not of errors it
+// wraps.
+func registeredSentinel(err error) (r *registeredErr, ok bool) {
+	if err == nil || !reflect.TypeOf(err).Comparable() {
+		goto end
+	}
+	errRegistry.mu.RLock()
+	r, ok = errRegistry.bySentinel[err]
+	errRegistry.mu.RUnlock()
+end:
+	return r, ok
+}
+
+// lookupSentinel returns the sentinel registered with code, else with msg.
+func lookupSentinel(code, msg string) (sentinel error, ok bool) {
+	var r *registeredErr
+
+	errRegistry.mu.RLock()
+	defer errRegistry.mu.RUnlock()
+	if code != "" {
+		r, ok = errRegistry.byCode[code]
+	}
+	if !ok {
+		r, ok = errRegistry.byMessage[msg]
+	}
+	if ok {
+		sentinel = r.sentinel
+	}
+	return sentinel, ok
+}
@@ -0,0 +1,128 @@
+package dt_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+func TestErrClassification(t *testing.T) {
+	errTestQuota := errors.New("quota exceeded")
+	dt.RegisterErr(errTestQuota, dt.ErrSpec{
+		Code:       "test.quota_exceeded",
+		Category:   dt.ErrCategoryPermission,
+		HTTPStatus: 429,
+	})
+
+	_, statErr := os.Stat(filepath.Join(t.TempDir(), "missing"))
+
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   string
+		wantCat    dt.ErrCategory
+		wantExit   int
+		wantStatus int
+	}{
+		{name: "nil", err: nil, wantExit: 0, wantStatus: 200},
+		{name: "unregistered", err: errors.New("boom"), wantExit: 1, wantStatus: 500},
+		{
+			name:     "sentinel",
+			err:      dt.NewErr(dt.ErrFileNotExist, "file", "a.txt"),
+			wantCode: "dt.file_not_exist", wantCat: dt.ErrCategoryNotFound, wantExit: 66, wantStatus: 404,
+		},
+		{
+			name:     "overrides",
+			err:      dt.NewErr(dt.ErrFlagIsRequired, "flag", "--name"),
+			wantCode: "dt.flag_is_required", wantCat: dt.ErrCategoryInvalidInput, wantExit: 64, wantStatus: 400,
+		},
+		{
+			name:     "internal wrapper only",
+			err:      dt.NewErr(dt.ErrFailedToCopyFile, errors.New("boom")),
+			wantCode: "dt.failed_to_copy_file", wantCat: dt.ErrCategoryInternal, wantExit: 70, wantStatus: 500,
+		},
+		{
+			name:     "specific cause wins",
+			err:      dt.NewErr(dt.ErrFailedToCopyFile, "source", "a.txt", statErr),
+			wantCode: "fs.not_exist", wantCat: dt.ErrCategoryNotFound, wantExit: 66, wantStatus: 404,
+		},
+		{
+			name:     "wrapped",
+			err:      fmt.Errorf("saving: %w", dt.NewErr(dt.ErrFailedToSaveFile, fs.ErrPermission)),
+			wantCode: "fs.permission", wantCat: dt.ErrCategoryPermission, wantExit: 77, wantStatus: 403,
+		},
+		{
+			name: "combined",
+			err: dt.CombineErrs([]error{
+				errors.New("boom"),
+				dt.NewErr(errTestQuota, "user", "alice"),
+				dt.NewErr(dt.ErrFileNotExist),
+			}),
+			wantCode: "test.quota_exceeded", wantCat: dt.ErrCategoryPermission, wantExit: 77, wantStatus: 429,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dt.ErrCode(tt.err); got != tt.wantCode {
+				t.Errorf("ErrCode() = %q, want %q", got, tt.wantCode)
+			}
+			if got := dt.ErrCategoryOf(tt.err); got != tt.wantCat {
+				t.Errorf("ErrCategoryOf() = %q, want %q", got, tt.wantCat)
+			}
+			if got := dt.ExitCode(tt.err); got != tt.wantExit {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.wantExit)
+			}
+			if got := dt.HTTPStatus(tt.err); got != tt.wantStatus {
+				t.Errorf("HTTPStatus() = %d, want %d", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRegisterErr_ReplacesSpec(t *testing.T) {
+	errTestReplaced := errors.New("replaced spec")
+	dt.RegisterErr(errTestReplaced, dt.ErrSpec{Code: "test.old"})
+	dt.RegisterErr(errTestReplaced, dt.ErrSpec{Code: "test.new", Category: dt.ErrCategoryInvalidInput})
+
+	if got := dt.ErrCode(errTestReplaced); got != "test.new" {
+		t.Errorf("ErrCode() = %q, want test.new", got)
+	}
+	got, err := dt.UnmarshalErrJSON([]byte(`{"kind":"error","message":"other","code":"test.old"}`))
+	if err != nil || errors.Is(got, errTestReplaced) {
+		t.Errorf("UnmarshalErrJSON(test.old) = %v, %v; want no match", got, err)
+	}
+}
+
+func TestRegisterErr_SharedMessage(t *testing.T) {
+	errTestFirst := errors.New("shared message")
+	errTestSecond := errors.New("shared message")
+	dt.RegisterErr(errTestFirst, dt.ErrSpec{Code: "test.first"})
+	dt.RegisterErr(errTestSecond, dt.ErrSpec{Code: "test.second"})
+
+	tests := map[string]error{
+		`{"kind":"error","message":"shared message","code":"test.second"}`: errTestSecond,
+		`{"kind":"error","message":"shared message","code":"test.first"}`:  errTestFirst,
+		`{"kind":"error","message":"shared message"}`:                      errTestFirst,
+	}
+	for data, want := range tests {
+		got, err := dt.UnmarshalErrJSON([]byte(data))
+		if err != nil || !errors.Is(got, want) {
+			t.Errorf("UnmarshalErrJSON(%s) = %v, %v; want %v", data, got, err, want)
+		}
+	}
+	if got := dt.ErrCode(dt.ErrFailedtoRemoveFile); got != "dt.failed_to_remove_file" {
+		t.Errorf("ErrCode(ErrFailedtoRemoveFile) = %q, want dt.failed_to_remove_file", got)
+	}
+	got, err := dt.UnmarshalErrJSON([]byte(`{"kind":"error","message":"x","code":"dt.failed_to_remove_file"}`))
+	if err != nil || got != dt.ErrFailedToRemoveFile {
+		t.Errorf("UnmarshalErrJSON(dt.failed_to_remove_file) = %v, %v; want ErrFailedToRemoveFile", got, err)
+	}
+	if errors.Is(dt.ErrFailedtoRemoveFile, dt.ErrFailedToRemoveFile) {
+		t.Error("ErrFailedtoRemoveFile is ErrFailedToRemoveFile, want distinct sentinels")
+	}
+}
@@ -0,0 +1,196 @@
+package dt
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// doterr_retry.go classifies errors as retryable, temporary or user-facing,
+// and retries operations whose errors are retryable.
+
+// Keys of the classification KVs.
+const (
+	RetryableKey  = "retryable"
+	TemporaryKey  = "temporary"
+	UserFacingKey = "user_facing"
+	AttemptsKey   = "attempts"
+)
+
+// RetryableKV marks an error as worth retrying, or explicitly not.
+func RetryableKV(retryable bool) ErrKV {
+	return kv{k: RetryableKey, v: retryable}
+}
+
+// TemporaryKV marks an error as caused by a condition expected to clear,
+// such as a timeout, or explicitly not.
+func TemporaryKV(temporary bool) ErrKV {
+	return kv{k: TemporaryKey, v: temporary}
+}
+
+// UserFacingKV marks an error whose message is meant for end users, or
+// explicitly not.
+func UserFacingKV(userFacing bool) ErrKV {
+	return kv{k: UserFacingKey, v: userFacing}
+}
+
+// Retryable reports whether err is worth retrying. The outermost
+// RetryableKV in the error tree decides; without one, temporary errors are
+// retryable. See Temporary.
+func Retryable(err error) (retryable bool) {
+	var ok bool
+
+	if err == nil {
+		goto end
+	}
+	retryable, ok = ErrValue[bool](err, RetryableKey)
+	if ok {
+		goto end
+	}
+	retryable = Temporary(err)
+end:
+	return retryable
+}
+
+// Temporary reports whether err is caused by a condition expected to clear.
+// The outermost TemporaryKV in the error tree decides; without one, an error
+// in the tree with a Temporary() or Timeout() method returning true, such as
+// a net.Error, makes err temporary.
+func Temporary(err error) (temporary bool) {
+	var ok bool
+
+	if err == nil {
+		goto end
+	}
+	temporary, ok = ErrValue[bool](err, TemporaryKey)
+	if ok {
+		goto end
+	}
+	walkErrTree(err, func(e error) bool {
+		switch t := e.(type) {
+		case interface{ Temporary() bool }:
+			temporary = t.Temporary()
+		case interface{ Timeout() bool }:
+			temporary = t.Timeout()
+		}
+		return !temporary
+	})
+end:
+	return temporary
+}
+
+// UserFacing reports whether err carries a message meant for end users, as
+// marked by the outermost UserFacingKV in the error tree.
+func UserFacing(err error) (userFacing bool) {
+	userFacing, _ = ErrValue[bool](err, UserFacingKey)
+	return userFacing
+}
+
+// RetryPolicy controls Retry. The zero value makes 3 attempts, waiting
+// 100ms and then 200ms with 20% jitter, and retries errors for which
+// Retryable returns true.
+type RetryPolicy struct {
+	// MaxAttempts is the number of calls, including the first. Zero means 3.
+	MaxAttempts int
+
+	// InitialDelay is the wait before the second attempt. Zero means 100ms.
+	InitialDelay time.Duration
+
+	// MaxDelay caps the wait between attempts. Zero means no cap.
+	MaxDelay time.Duration
+
+	// Multiplier scales the wait after each attempt. Zero means 2.
+	Multiplier float64
+
+	// Jitter randomizes each wait by up to this fraction either way, so
+	// clients retrying together spread out. Zero means 0.2; negative means
+	// none.
+	Jitter float64
+
+	// ShouldRetry decides whether an error is worth another attempt. Nil
+	// means Retryable.
+	ShouldRetry func(error) bool
+}
+
+// DefaultRetryPolicy is the policy Retry uses for a zero RetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:  3,
+	InitialDelay: 100 * time.Millisecond,
+	Multiplier:   2,
+	Jitter:       0.2,
+	ShouldRetry:  Retryable,
+}
+
+// withDefaults fills in the zero fields of p from DefaultRetryPolicy.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	d := DefaultRetryPolicy
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = d.MaxAttempts
+	}
+	if p.InitialDelay <= 0 {
+		p.InitialDelay = d.InitialDelay
+	}
+	if p.Multiplier <= 0 {
+		p.Multiplier = d.Multiplier
+	}
+	switch {
+	case p.Jitter == 0:
+		p.Jitter = d.Jitter
+	case p.Jitter < 0:
+		p.Jitter = 0
+	}
+	if p.ShouldRetry == nil {
+		p.ShouldRetry = d.ShouldRetry
+	}
+	return p
+}
+
+// delay returns the wait after attempt, counting from 1.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := float64(p.InitialDelay)
+	for i := 1; i < attempt; i++ {
+		d *= p.Multiplier
+		if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+			break
+		}
+	}
+	if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+		d = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+// Retry calls fn until it succeeds, returns an error the policy does not
+// retry, makes policy.MaxAttempts attempts or ctx is done, waiting with
+// exponential backoff between attempts. The error returned carries the
+// number of attempts under AttemptsKey and, when ctx ended the retries,
+// context.Cause(ctx) as its last cause.
+func Retry(ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) error) (err error) {
+	var attempt int
+	var timer *time.Timer
+
+	policy = policy.withDefaults()
+	for attempt = 1; ; attempt++ {
+		err = fn(ctx)
+		if err == nil {
+			goto end
+		}
+		if attempt >= policy.MaxAttempts || !policy.ShouldRetry(err) {
+			err = WithErr(err, AttemptsKey, attempt)
+			goto end
+		}
+		timer = time.NewTimer(policy.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			err = WithErr(err, AttemptsKey, attempt, context.Cause(ctx))
+			goto end
+		case <-timer.C:
+		}
+	}
+end:
+	return err
+}
@@ -0,0 +1,114 @@
+package dt_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+var errTestTransient = errors.New("transient failure")
+
+func TestErrClassifiers(t *testing.T) {
+	timeout := &os.PathError{Op: "read", Path: "a.txt", Err: syscall.ETIMEDOUT}
+	tests := []struct {
+		name           string
+		err            error
+		wantRetryable  bool
+		wantTemporary  bool
+		wantUserFacing bool
+	}{
+		{name: "nil"},
+		{name: "plain", err: errors.New("boom")},
+		{name: "marked retryable", err: dt.NewErr(errTestTransient, dt.RetryableKV(true)), wantRetryable: true},
+		{name: "marked temporary", err: dt.NewErr(errTestTransient, dt.TemporaryKV(true)), wantRetryable: true, wantTemporary: true},
+		{name: "timeout cause", err: dt.NewErr(dt.ErrFailedToReadFile, timeout), wantRetryable: true, wantTemporary: true},
+		{
+			name:          "outer marker wins",
+			err:           dt.NewErr(dt.ErrFailedToReadFile, dt.RetryableKV(false), dt.NewErr(errTestTransient, dt.RetryableKV(true))),
+			wantRetryable: false,
+		},
+		{
+			name: "in joined chain",
+			err: dt.CombineErrs([]error{
+				errors.New("boom"),
+				dt.NewErr(errTestTransient, dt.TemporaryKV(true), dt.UserFacingKV(true)),
+			}),
+			wantRetryable: true, wantTemporary: true, wantUserFacing: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dt.Retryable(tt.err); got != tt.wantRetryable {
+				t.Errorf("Retryable() = %v, want %v", got, tt.wantRetryable)
+			}
+			if got := dt.Temporary(tt.err); got != tt.wantTemporary {
+				t.Errorf("Temporary() = %v, want %v", got, tt.wantTemporary)
+			}
+			if got := dt.UserFacing(tt.err); got != tt.wantUserFacing {
+				t.Errorf("UserFacing() = %v, want %v", got, tt.wantUserFacing)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	policy := dt.RetryPolicy{MaxAttempts: 4, InitialDelay: time.Millisecond, Jitter: -1}
+	retryable := dt.NewErr(errTestTransient, dt.RetryableKV(true))
+
+	t.Run("succeeds after retries", func(t *testing.T) {
+		calls := 0
+		err := dt.Retry(context.Background(), policy, func(context.Context) error {
+			calls++
+			if calls < 3 {
+				return retryable
+			}
+			return nil
+		})
+		if err != nil || calls != 3 {
+			t.Errorf("Retry() = %v after %d calls, want nil after 3", err, calls)
+		}
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		calls := 0
+		err := dt.Retry(context.Background(), policy, func(context.Context) error {
+			calls++
+			return retryable
+		})
+		if !errors.Is(err, errTestTransient) || calls != 4 {
+			t.Errorf("Retry() = %v after %d calls, want errTestTransient after 4", err, calls)
+		}
+		if n, ok := dt.ErrValue[int](err, dt.AttemptsKey); !ok || n != 4 {
+			t.Errorf("ErrValue(attempts) = %d, %v; want 4", n, ok)
+		}
+	})
+
+	t.Run("stops on non-retryable error", func(t *testing.T) {
+		calls := 0
+		err := dt.Retry(context.Background(), policy, func(context.Context) error {
+			calls++
+			return errors.New("boom")
+		})
+		if n, _ := dt.ErrValue[int](err, dt.AttemptsKey); calls != 1 || n != 1 {
+			t.Errorf("Retry() made %d calls, recorded %d; want 1", calls, n)
+		}
+	})
+
+	t.Run("stops when context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		err := dt.Retry(ctx, dt.RetryPolicy{MaxAttempts: 10, InitialDelay: time.Hour}, func(context.Context) error {
+			calls++
+			cancel()
+			return retryable
+		})
+		if !errors.Is(err, context.Canceled) || !errors.Is(err, errTestTransient) || calls != 1 {
+			t.Errorf("Retry() = %v after %d calls, want canceled after 1", err, calls)
+		}
+	})
+}
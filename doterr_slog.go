@@ -0,0 +1,124 @@
+package dt
+
+import (
+	"log/slog"
+	"strconv"
+)
+
+// doterr_slog.go lets doterr errors log as structured slog groups instead of
+// the flattened string from Error().
+
+var (
+	_ slog.LogValuer = entry{}
+	_ slog.LogValuer = combined{}
+)
+
+// LogValue implements slog.LogValuer. See ErrAttrs for the layout.
+func (e entry) LogValue() slog.Value {
+	return slog.GroupValue(e.attrs()...)
+}
+
+// LogValue implements slog.LogValuer, logging the members as sub-groups
+// keyed "0", "1", ... in order.
+func (c combined) LogValue() slog.Value {
+	return slog.GroupValue(indexedAttrs(c.errs)...)
+}
+
+// ErrAttrs returns err as slog attributes, for use with slog.Group or
+// Logger.LogAttrs. For a doterr entry they are, in order:
+//
+//   - "errors": the messages of its sentinels and other plain errors,
+//   - one attribute per KV, in insertion order, omitting those repeated by a
+//     nested entry as Error() does,
+//   - "causes": nested entries and joined errors as sub-groups keyed "0",
+//     "1", ... in order.
+//
+// A CombineErrs or errors.Join result gives one sub-group per member, keyed
+// the same way, and any other error gives a single "error" attribute. ErrAttrs
+// returns nil for a nil error.
+func ErrAttrs(err error) (attrs []slog.Attr) {
+	var v slog.Value
+
+	if err == nil {
+		goto end
+	}
+	v = errLogValue(err)
+	if v.Kind() != slog.KindGroup {
+		attrs = []slog.Attr{slog.Attr{Key: "error", Value: v}}
+		goto end
+	}
+	attrs = v.Group()
+end:
+	return attrs
+}
+
+// attrs returns the attributes described by ErrAttrs for e.
+func (e entry) attrs() (attrs []slog.Attr) {
+	var msgs []string
+	var causes []error
+
+	for _, err := range e.errors {
+		if isStructuredErr(err) {
+			causes = append(causes, err)
+			continue
+		}
+		msgs = append(msgs, err.Error())
+	}
+	if len(msgs) > 0 {
+		attrs = append(attrs, slog.Any("errors", msgs))
+	}
+	for _, pair := range e.kvs {
+		if e.MatchKV(pair.k, pair.v) {
+			continue
+		}
+		attrs = append(attrs, slog.Any(pair.k, redactKV(pair.k, pair.v)))
+	}
+	if len(causes) > 0 {
+		attrs = append(attrs, slog.Attr{
+			Key:   "causes",
+			Value: slog.GroupValue(indexedAttrs(causes)...),
+		})
+	}
+	return attrs
+}
+
+// isStructuredErr reports whether err logs as a group rather than a message.
+func isStructuredErr(err error) (ok bool) {
+	switch err.(type) {
+	case entry, *entry, combined, interface{ Unwrap() []error }:
+		ok = true
+	}
+	return ok
+}
+
+// errLogValue returns the slog value for err.
+func errLogValue(err error) (v slog.Value) {
+	switch e := err.(type) {
+	case entry:
+		v = e.LogValue()
+	case *entry:
+		v = e.LogValue()
+	case combined:
+		v = e.LogValue()
+	case interface{ Unwrap() []error }:
+		v = slog.GroupValue(indexedAttrs(e.Unwrap())...)
+	default:
+		v = slog.StringValue(err.Error())
+	}
+	return v
+}
+
+// indexedAttrs returns errs as attributes keyed by their position.
+func indexedAttrs(errs []error) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(errs))
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		attrs = append(attrs, slog.Attr{
+			Key:   strconv.Itoa(len(attrs)),
+			Value: errLogValue(err),
+		})
+	}
+	return attrs
+}
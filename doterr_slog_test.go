@@ -0,0 +1,93 @@
+package dt_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+var (
+	errTestOuter = errors.New("outer failed")
+	errTestInner = errors.New("inner failed")
+)
+
+func logJSON(t *testing.T, err error) map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Error("failed", "err", err)
+	var out map[string]any
+	if e := json.Unmarshal(buf.Bytes(), &out); e != nil {
+		t.Fatalf("json.Unmarshal(%s) error = %v", buf.Bytes(), e)
+	}
+	return out
+}
+
+func TestErr_LogValue(t *testing.T) {
+	inner := dt.NewErr(errTestInner, "file", "a.txt", "size", 3)
+	err := dt.NewErr(errTestOuter, "op", "copy", inner)
+
+	got := logJSON(t, err)["err"]
+	want := map[string]any{
+		"errors": []any{"outer failed"},
+		"op":     "copy",
+		"causes": map[string]any{
+			"0": map[string]any{
+				"errors": []any{"inner failed"},
+				"file":   "a.txt",
+				"size":   float64(3),
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("logged err = %#v, want %#v", got, want)
+	}
+}
+
+func TestErr_LogValueCombined(t *testing.T) {
+	err := dt.CombineErrs([]error{
+		dt.NewErr(errTestInner, "file", "a.txt"),
+		errors.New("plain"),
+		dt.CombineErrs([]error{
+			dt.NewErr(errTestOuter, "file", "b.txt"),
+			dt.NewErr(errTestOuter, "file", "c.txt"),
+		}),
+	})
+
+	got := logJSON(t, err)["err"]
+	want := map[string]any{
+		"0": map[string]any{"errors": []any{"inner failed"}, "file": "a.txt"},
+		"1": "plain",
+		"2": map[string]any{
+			"0": map[string]any{"errors": []any{"outer failed"}, "file": "b.txt"},
+			"1": map[string]any{"errors": []any{"outer failed"}, "file": "c.txt"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("logged err = %#v, want %#v", got, want)
+	}
+}
+
+func TestErrAttrs(t *testing.T) {
+	if attrs := dt.ErrAttrs(nil); attrs != nil {
+		t.Errorf("ErrAttrs(nil) = %v, want nil", attrs)
+	}
+
+	attrs := dt.ErrAttrs(errors.New("plain"))
+	if len(attrs) != 1 || attrs[0].Key != "error" || attrs[0].Value.String() != "plain" {
+		t.Errorf("ErrAttrs(plain) = %v, want [error=plain]", attrs)
+	}
+
+	attrs = dt.ErrAttrs(dt.NewErr(errTestOuter, "b", 2, "a", 1))
+	var keys []string
+	for _, a := range attrs {
+		keys = append(keys, a.Key)
+	}
+	if want := []string{"errors", "b", "a"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("ErrAttrs() keys = %v, want %v", keys, want)
+	}
+}
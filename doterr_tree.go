@@ -0,0 +1,271 @@
+package dt
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// doterr_tree.go renders an error tree for people: one node per entry with
+// its sentinels, KVs and causes, instead of the single line from Error().
+
+// ErrTreeOptions controls FormatErrTree.
+type ErrTreeOptions struct {
+	// Width truncates lines to this many characters, marking the cut with
+	// "…". Zero means no limit.
+	Width int
+
+	// Color highlights messages, keys and the tree with ANSI escapes.
+	Color bool
+
+	// Dedupe prints a cause that already appeared, by message, only once;
+	// later occurrences show "(repeated)" without their details.
+	Dedupe bool
+
+	// MaxChildren shows only the first MaxChildren causes of each node and
+	// summarizes the rest, grouped by message. Zero shows all.
+	MaxChildren int
+
+	// Callers adds the locations recorded when SetErrCallerMode is enabled.
+	Callers bool
+}
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiDim   = "\x1b[2m"
+	ansiCyan  = "\x1b[36m"
+)
+
+// maxErrTreeSummaryGroups limits the message groups named in the summary of
+// causes hidden by MaxChildren.
+const maxErrTreeSummaryGroups = 3
+
+// FormatErrTree renders err as an indented tree:
+//
+//	failed to copy file
+//	│ source: a.txt
+//	├── file does not exist
+//	│     path: a.txt
+//	└── 2 errors
+//	    ├── permission denied
+//	    └── disk full
+//
+// It returns "" for a nil error.
+func FormatErrTree(err error, opts ...ErrTreeOptions) string {
+	var sb strings.Builder
+	_ = WriteErrTree(&sb, err, opts...)
+	return sb.String()
+}
+
+// WriteErrTree writes FormatErrTree(err, opts...) to w.
+func WriteErrTree(w io.Writer, err error, opts ...ErrTreeOptions) (errOut error) {
+	var r errTreeRenderer
+
+	if err == nil {
+		goto end
+	}
+	if len(opts) > 0 {
+		r.opts = opts[0]
+	}
+	r.seen = make(map[string]bool)
+	r.node(err, "", "", "")
+	_, errOut = io.WriteString(w, r.sb.String())
+end:
+	return errOut
+}
+
+type errTreeRenderer struct {
+	opts ErrTreeOptions
+	sb   strings.Builder
+	seen map[string]bool
+}
+
+// errTreeNode is what the renderer shows for one error.
+type errTreeNode struct {
+	label    string
+	details  []errTreeDetail
+	children []error
+}
+
+type errTreeDetail struct {
+	key   string
+	value string
+}
+
+// newErrTreeNode describes err.
+func newErrTreeNode(err error, callers bool) (n errTreeNode) {
+	switch e := err.(type) {
+	case entry:
+		var msgs []string
+		for _, child := range e.errors {
+			if isStructuredErr(child) {
+				n.children = append(n.children, child)
+				continue
+			}
+			msgs = append(msgs, child.Error())
+		}
+		n.label = strings.Join(msgs, "; ")
+		for _, pair := range e.kvs {
+			if e.MatchKV(pair.k, pair.v) {
+				continue
+			}
+			n.details = append(n.details, errTreeDetail{key: pair.k, value: fmt.Sprint(redactKV(pair.k, pair.v))})
+		}
+		if callers {
+			for _, f := range e.frames() {
+				n.details = append(n.details, errTreeDetail{
+					key:   "at",
+					value: fmt.Sprintf("%s (%s:%d)", f.Function, f.File, f.Line),
+				})
+			}
+		}
+		if n.label == "" && len(n.children) == 1 && len(n.details) > 0 {
+			// Metadata added by WithErr to a structured error
+			n.label = "metadata"
+		}
+	case *entry:
+		n = newErrTreeNode(*e, callers)
+	case combined:
+		n.label = fmt.Sprintf("%d errors", len(e.errs))
+		n.children = e.errs
+	case interface{ Unwrap() []error }:
+		n.children = e.Unwrap()
+		n.label = fmt.Sprintf("%d errors", len(n.children))
+	default:
+		n.label = strings.ReplaceAll(err.Error(), "\n", " ")
+	}
+	return n
+}
+
+// node writes err. head is written before the label, and prefix before each
+// of its following lines.
+func (r *errTreeRenderer) node(err error, head, prefix, childPrefix string) {
+	var shown []error
+	var hidden []error
+
+	n := newErrTreeNode(err, r.opts.Callers)
+	if r.opts.Dedupe && head != "" {
+		key := err.Error()
+		if r.seen[key] {
+			r.line(head, r.color(ansiBold, n.label)+r.color(ansiDim, " (repeated)"), utf8.RuneCountInString(n.label+" (repeated)"))
+			return
+		}
+		r.seen[key] = true
+	}
+	r.line(head, r.color(ansiBold, n.label), utf8.RuneCountInString(n.label))
+
+	detailPrefix := prefix + "  "
+	if len(n.children) > 0 {
+		detailPrefix = prefix + "│ "
+	}
+	for _, d := range n.details {
+		text := d.key + ": " + d.value
+		r.line(r.color(ansiDim, detailPrefix), r.color(ansiCyan, d.key+":")+" "+d.value, utf8.RuneCountInString(text))
+	}
+
+	shown = n.children
+	if r.opts.MaxChildren > 0 && len(shown) > r.opts.MaxChildren {
+		shown, hidden = shown[:r.opts.MaxChildren], shown[r.opts.MaxChildren:]
+	}
+	for i, child := range shown {
+		branch, next := "├── ", "│   "
+		if i == len(shown)-1 && len(hidden) == 0 {
+			branch, next = "└── ", "    "
+		}
+		r.node(child, r.color(ansiDim, childPrefix+branch), childPrefix+next, childPrefix+next)
+	}
+	if len(hidden) > 0 {
+		summary := summarizeErrs(hidden)
+		r.line(r.color(ansiDim, childPrefix+"└── "), r.color(ansiDim, summary), utf8.RuneCountInString(summary))
+	}
+}
+
+// line writes head and text as one line, truncating text so the visible
+// width stays within opts.Width. n is the visible width of text.
+func (r *errTreeRenderer) line(head, text string, n int) {
+	width := visibleWidth(head) + n
+	if r.opts.Width > 0 && width > r.opts.Width {
+		text = truncateVisible(text, n-(width-r.opts.Width)-1) + "…"
+		if r.opts.Color {
+			text += ansiReset
+		}
+	}
+	r.sb.WriteString(head)
+	r.sb.WriteString(text)
+	r.sb.WriteByte('\n')
+}
+
+func (r *errTreeRenderer) color(code, s string) string {
+	if !r.opts.Color || s == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// summarizeErrs describes errs as "… and N more: 30 × msg, 7 × msg".
+func summarizeErrs(errs []error) string {
+	var order []string
+	counts := make(map[string]int)
+
+	for _, err := range errs {
+		label := newErrTreeNode(err, false).label
+		if counts[label] == 0 {
+			order = append(order, label)
+		}
+		counts[label]++
+	}
+	// Most frequent first, ties in order of appearance
+	slices.SortStableFunc(order, func(a, b string) int {
+		return counts[b] - counts[a]
+	})
+	groups := make([]string, 0, maxErrTreeSummaryGroups+1)
+	for i, label := range order {
+		if i == maxErrTreeSummaryGroups {
+			groups = append(groups, "…")
+			break
+		}
+		groups = append(groups, fmt.Sprintf("%d × %s", counts[label], label))
+	}
+	return fmt.Sprintf("… and %d more: %s", len(errs), strings.Join(groups, ", "))
+}
+
+// visibleWidth returns the number of runes in s that are not part of an ANSI
+// escape sequence.
+func visibleWidth(s string) (n int) {
+	inEscape := false
+	for _, c := range s {
+		switch {
+		case c == '\x1b':
+			inEscape = true
+		case inEscape:
+			inEscape = c != 'm'
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+// truncateVisible returns the leading part of s with at most n visible runes,
+// keeping ANSI escape sequences.
+func truncateVisible(s string, n int) string {
+	var sb strings.Builder
+	inEscape := false
+	for _, c := range s {
+		switch {
+		case c == '\x1b':
+			inEscape = true
+		case inEscape:
+			inEscape = c != 'm'
+		case n <= 0:
+			continue
+		default:
+			n--
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
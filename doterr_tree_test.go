@@ -0,0 +1,129 @@
+package dt_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+func testTreeErr() error {
+	shared := dt.NewErr(errTestInner, "file", "shared.txt")
+	var errs []error
+	for i := 0; i < 4; i++ {
+		errs = append(errs, dt.NewErr(dt.ErrFileNotExist, "path", fmt.Sprintf("f%d", i)))
+	}
+	errs = append(errs, shared, errors.New("permission denied"), shared)
+	return dt.NewErr(dt.ErrFailedToCopyFile, "source", "a.txt", dt.CombineErrs(errs))
+}
+
+func TestFormatErrTree(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		opts dt.ErrTreeOptions
+		want string
+	}{
+		{name: "nil", err: nil, want: ""},
+		{name: "plain", err: errors.New("boom"), want: "boom\n"},
+		{
+			name: "full",
+			err:  testTreeErr(),
+			want: `failed to copy file
+│ source: a.txt
+└── 7 errors
+    ├── file does not exist
+    │     path: f0
+    ├── file does not exist
+    │     path: f1
+    ├── file does not exist
+    │     path: f2
+    ├── file does not exist
+    │     path: f3
+    ├── inner failed
+    │     file: shared.txt
+    ├── permission denied
+    └── inner failed
+          file: shared.txt
+`,
+		},
+		{
+			name: "dedupe",
+			err:  testTreeErr(),
+			opts: dt.ErrTreeOptions{Dedupe: true, MaxChildren: 6},
+			want: `failed to copy file
+│ source: a.txt
+└── 7 errors
+    ├── file does not exist
+    │     path: f0
+    ├── file does not exist
+    │     path: f1
+    ├── file does not exist
+    │     path: f2
+    ├── file does not exist
+    │     path: f3
+    ├── inner failed
+    │     file: shared.txt
+    ├── permission denied
+    └── … and 1 more: 1 × inner failed
+`,
+		},
+		{
+			name: "first N plus summary",
+			err:  testTreeErr(),
+			opts: dt.ErrTreeOptions{MaxChildren: 1},
+			want: `failed to copy file
+│ source: a.txt
+└── 7 errors
+    ├── file does not exist
+    │     path: f0
+    └── … and 6 more: 3 × file does not exist, 2 × inner failed, 1 × permission denied
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dt.FormatErrTree(tt.err, tt.opts); got != tt.want {
+				t.Errorf("FormatErrTree() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatErrTree_Repeated(t *testing.T) {
+	shared := dt.NewErr(errTestInner, "file", "shared.txt")
+	err := dt.CombineErrs([]error{shared, shared})
+	want := `2 errors
+├── inner failed
+│     file: shared.txt
+└── inner failed (repeated)
+`
+	if got := dt.FormatErrTree(err, dt.ErrTreeOptions{Dedupe: true}); got != want {
+		t.Errorf("FormatErrTree() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatErrTree_Width(t *testing.T) {
+	for _, color := range []bool{false, true} {
+		got := dt.FormatErrTree(testTreeErr(), dt.ErrTreeOptions{Width: 20, Color: color, MaxChildren: 1})
+		plain := got
+		for _, code := range []string{"\x1b[0m", "\x1b[1m", "\x1b[2m", "\x1b[36m"} {
+			plain = strings.ReplaceAll(plain, code, "")
+		}
+		for i, line := range strings.Split(strings.TrimSuffix(got, "\n"), "\n") {
+			visible := strings.Split(plain, "\n")[i]
+			if n := utf8.RuneCountInString(visible); n > 20 {
+				t.Errorf("color=%v: line %q is %d wide, want <= 20", color, visible, n)
+			}
+			if strings.Contains(line, "\x1b[") != color {
+				t.Errorf("color=%v: line %q has unexpected escapes", color, line)
+			}
+		}
+		if !strings.Contains(plain, "    └── … and 6 mor…\n") {
+			t.Errorf("color=%v: FormatErrTree() = %q, want truncated summary", color, plain)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+// Command doterrvet runs the doterrvet analyzer as a go vet tool:
+//
+//	go vet -vettool=$(which doterrvet) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/unitchecker"
+
+	"github.com/mikeschinkel/go-dt/doterrvet"
+)
+
+func main() {
+	unitchecker.Main(doterrvet.Analyzer)
+}
@@ -0,0 +1,291 @@
+// Package doterrvet provides a go/analysis analyzer that reports malformed
+// dt.NewErr and dt.WithErr calls at build time, mistakes that otherwise only
+// surface at runtime as ErrMissingSentinel, ErrOddKeyValueCount and friends
+// joined into the returned error, or as a panic.
+//
+// It reports:
+//
+//   - NewErr calls without a sentinel error as their first argument,
+//   - keys without a value,
+//   - errors between key-value pairs, which are neither sentinels nor the
+//     trailing cause (NewErr only),
+//   - arguments that are not an error, string key or ErrKV, such as a
+//     dt.Filepath passed where a key is expected,
+//   - keys that are not constants,
+//   - keys repeated within one call.
+//
+// Run it with go vet:
+//
+//	go install github.com/mikeschinkel/go-dt/doterrvet/cmd/doterrvet@latest
+//	go vet -vettool=$(which doterrvet) ./...
+package doterrvet
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const (
+	dtPath  = "github.com/mikeschinkel/go-dt"
+	dtxPath = "github.com/mikeschinkel/go-dt/dtx"
+)
+
+// Analyzer reports malformed dt.NewErr and dt.WithErr calls.
+var Analyzer = &analysis.Analyzer{
+	Name:     "doterr",
+	Doc:      "report malformed dt.NewErr and dt.WithErr calls",
+	URL:      "https://pkg.go.dev/github.com/mikeschinkel/go-dt/doterrvet",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// argKind classifies a NewErr/WithErr argument by its static type, the way
+// the runtime classifies it by its dynamic type.
+type argKind int
+
+const (
+	// argUnknown is an interface, such as any, whose dynamic type decides.
+	argUnknown argKind = iota
+	argError
+	argKV
+	argKey
+	argInvalid
+)
+
+func run(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	insp.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		name, errKV := doterrCallee(pass, call)
+		if errKV == nil || call.Ellipsis.IsValid() {
+			return
+		}
+		c := callChecker{
+			pass:  pass,
+			call:  call,
+			name:  name,
+			errKV: errKV,
+			seen:  make(map[string]bool),
+		}
+		c.check()
+	})
+	return nil, nil
+}
+
+// doterrCallee returns "NewErr" or "WithErr" and the dt.ErrKV interface when
+// call calls one of them, directly or through the dtx aliases.
+func doterrCallee(pass *analysis.Pass, call *ast.CallExpr) (name string, errKV *types.Interface) {
+	var dt *types.Package
+
+	// Callee also returns the func variables dtx declares as aliases
+	obj := typeutil.Callee(pass.TypesInfo, call)
+	if obj == nil || obj.Pkg() == nil {
+		goto end
+	}
+	switch obj.Name() {
+	case "NewErr", "WithErr":
+	default:
+		goto end
+	}
+	switch obj.Pkg().Path() {
+	case dtPath:
+		dt = obj.Pkg()
+	case dtxPath:
+		dt = importedPackage(obj.Pkg(), dtPath)
+	}
+	if dt == nil {
+		goto end
+	}
+	errKV = lookupInterface(dt, "ErrKV")
+	if errKV != nil {
+		name = obj.Name()
+	}
+end:
+	return name, errKV
+}
+
+func importedPackage(pkg *types.Package, path string) (imported *types.Package) {
+	for _, p := range pkg.Imports() {
+		if p.Path() == path {
+			imported = p
+			break
+		}
+	}
+	return imported
+}
+
+func lookupInterface(pkg *types.Package, name string) (iface *types.Interface) {
+	obj := pkg.Scope().Lookup(name)
+	if obj != nil {
+		iface, _ = obj.Type().Underlying().(*types.Interface)
+	}
+	return iface
+}
+
+// callChecker checks the arguments of one NewErr or WithErr call.
+type callChecker struct {
+	pass  *analysis.Pass
+	call  *ast.CallExpr
+	name  string
+	errKV *types.Interface
+	seen  map[string]bool
+}
+
+func (c *callChecker) check() {
+	args := c.call.Args
+	i := 0
+
+	if c.name == "WithErr" {
+		// An optional base error comes first, and sentinels may follow
+		if len(args) > 0 && c.kind(args[0]) == argError {
+			i++
+		}
+		c.checkPairs(args[i:], true)
+		return
+	}
+	for i < len(args) && c.kind(args[i]) == argError {
+		i++
+	}
+	if i == 0 && (len(args) == 0 || c.kind(args[0]) != argUnknown) {
+		c.pass.ReportRangef(c.call, "dt.NewErr call has no sentinel error as its first argument")
+	}
+	c.checkPairs(args[i:], false)
+}
+
+// checkPairs checks the arguments following the sentinels. A final error is
+// the trailing cause; other errors are sentinels only when sentinelsOK.
+func (c *callChecker) checkPairs(args []ast.Expr, sentinelsOK bool) {
+	for j := 0; j < len(args); j++ {
+		arg := args[j]
+		switch c.kind(arg) {
+		case argUnknown:
+			// Later arguments depend on how this one is classified at runtime
+			return
+		case argError:
+			if sentinelsOK || j == len(args)-1 {
+				continue
+			}
+			c.pass.ReportRangef(arg, "dt.%s: error %s among key-value pairs is neither a sentinel nor the trailing cause",
+				c.name, render(arg))
+		case argKV:
+			c.checkKVCall(arg)
+		case argKey:
+			c.checkKey(arg)
+			if j == len(args)-1 {
+				c.pass.ReportRangef(arg, "dt.%s: key %s has no value (odd number of key-value arguments)",
+					c.name, render(arg))
+			}
+			j++ // Skip the value, which may be of any type
+		case argInvalid:
+			c.pass.ReportRangef(arg, "dt.%s: argument %s of type %s is not an error, string key or ErrKV",
+				c.name, render(arg), c.pass.TypesInfo.TypeOf(arg))
+		}
+	}
+}
+
+// checkKVCall checks the key of an ErrKV built by a dt constructor that takes
+// one, such as dt.StringKV("key", value).
+func (c *callChecker) checkKVCall(arg ast.Expr) {
+	var sig *types.Signature
+	var params *types.Tuple
+
+	call, ok := ast.Unparen(arg).(*ast.CallExpr)
+	if !ok || len(call.Args) == 0 || call.Ellipsis.IsValid() {
+		goto end
+	}
+	sig, ok = c.pass.TypesInfo.TypeOf(call.Fun).(*types.Signature)
+	if !ok {
+		goto end
+	}
+	params = sig.Params()
+	if params.Len() == 0 || params.At(0).Name() != "key" || !isString(params.At(0).Type()) {
+		goto end
+	}
+	if fn := typeutil.StaticCallee(c.pass.TypesInfo, call); fn == nil || fn.Pkg() == nil || fn.Pkg().Path() != dtPath {
+		goto end
+	}
+	c.checkKey(call.Args[0])
+end:
+	return
+}
+
+// checkKey reports non-constant and repeated keys.
+func (c *callChecker) checkKey(key ast.Expr) {
+	var s string
+
+	tv := c.pass.TypesInfo.Types[key]
+	if tv.Value == nil || tv.Value.Kind() != constant.String {
+		c.pass.ReportRangef(key, "dt.%s: key %s is not a constant", c.name, render(key))
+		goto end
+	}
+	s = constant.StringVal(tv.Value)
+	if c.seen[s] {
+		c.pass.ReportRangef(key, "dt.%s: duplicate key %q", c.name, s)
+		goto end
+	}
+	c.seen[s] = true
+end:
+	return
+}
+
+// kind classifies arg as NewErr and WithErr would at runtime.
+func (c *callChecker) kind(arg ast.Expr) (k argKind) {
+	t := c.pass.TypesInfo.TypeOf(arg)
+	switch {
+	case t == nil:
+		k = argUnknown
+	case types.Identical(t, types.Typ[types.UntypedNil]):
+		k = argInvalid
+	case types.Identical(t, types.Typ[types.String]) || types.Identical(t, types.Typ[types.UntypedString]):
+		// Only the string type itself; named string types such as
+		// dt.Filepath are not keys
+		k = argKey
+	case types.Implements(t, c.errKV) || c.isKVSlice(t) || c.isKVFunc(t):
+		k = argKV
+	case types.Implements(t, errorType):
+		k = argError
+	case types.IsInterface(t):
+		k = argUnknown
+	default:
+		k = argInvalid
+	}
+	return k
+}
+
+func (c *callChecker) isKVSlice(t types.Type) bool {
+	s, ok := t.Underlying().(*types.Slice)
+	return ok && isErrKV(s.Elem(), c.errKV)
+}
+
+func (c *callChecker) isKVFunc(t types.Type) bool {
+	sig, ok := t.Underlying().(*types.Signature)
+	return ok && sig.Params().Len() == 0 && sig.Results().Len() == 1 && isErrKV(sig.Results().At(0).Type(), c.errKV)
+}
+
+func isErrKV(t types.Type, errKV *types.Interface) bool {
+	return types.Identical(t.Underlying(), errKV)
+}
+
+func isString(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Info()&types.IsString != 0
+}
+
+var errorType = types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+
+// render returns the source of expr, shortened for use in messages.
+func render(expr ast.Expr) (s string) {
+	const maxLen = 40
+
+	s = types.ExprString(expr)
+	if len(s) > maxLen {
+		s = s[:maxLen-3] + "..."
+	}
+	return s
+}
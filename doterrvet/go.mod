@@ -0,0 +1,5 @@
+module github.com/mikeschinkel/go-dt/doterrvet
+
+go 1.25.3
+
+require golang.org/x/tools v0.47.0
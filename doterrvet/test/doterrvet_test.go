@@ -0,0 +1,13 @@
+package test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/mikeschinkel/go-dt/doterrvet"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), doterrvet.Analyzer, "a")
+}
@@ -0,0 +1,46 @@
+package a
+
+import (
+	"errors"
+
+	"github.com/mikeschinkel/go-dt"
+	"github.com/mikeschinkel/go-dt/dtx"
+)
+
+var errOther = errors.New("other")
+
+const pathKey = "path"
+
+func wellFormed(path dt.Filepath, key string, cause error, parts []any) {
+	_ = dt.NewErr(dt.ErrFailedToReadFile)
+	_ = dt.NewErr(dt.ErrFailedToReadFile, errOther, pathKey, path, "size", 10, cause)
+	_ = dt.NewErr(dt.ErrFailedToReadFile, "cause", cause)
+	_ = dt.NewErr(dt.ErrFailedToReadFile, dt.StringKV("name", "x"), dt.RetryableKV(true), "path", path)
+	_ = dt.NewErr(dt.ErrFailedToReadFile, dt.AppendKV(nil), func() dt.ErrKV { return nil })
+	_ = dt.NewErr(parts...)
+	_ = dt.WithErr(cause, "path", path)
+	_ = dt.WithErr(cause, dt.ErrFailedToReadFile, "path", path, errOther, cause)
+	_ = dt.WithErr("path", path)
+	_ = dtx.NewErr(dt.ErrFailedToReadFile, "path", path)
+}
+
+func unknownTypes(v any) {
+	// Runtime types decide, so nothing after v is checked
+	_ = dt.NewErr(v, "key")
+	_ = dt.NewErr(dt.ErrFailedToReadFile, v, "key")
+}
+
+func malformed(path dt.Filepath, key string, cause error) {
+	_ = dt.NewErr()                                                      // want `dt.NewErr call has no sentinel error as its first argument`
+	_ = dt.NewErr("key")                                                 // want `no sentinel error` `key "key" has no value`
+	_ = dtx.NewErr("path", path)                                         // want `no sentinel error`
+	_ = dt.NewErr(dt.ErrFailedToReadFile, "path")                        // want `dt.NewErr: key "path" has no value \(odd number of key-value arguments\)`
+	_ = dt.WithErr(cause, "path", path, "size")                          // want `dt.WithErr: key "size" has no value`
+	_ = dt.NewErr(dt.ErrFailedToReadFile, key, path)                     // want `dt.NewErr: key key is not a constant`
+	_ = dt.NewErr(dt.ErrFailedToReadFile, dt.StringKV(key, "x"))         // want `dt.NewErr: key key is not a constant`
+	_ = dt.NewErr(dt.ErrFailedToReadFile, "path", path, pathKey, path)   // want `dt.NewErr: duplicate key "path"`
+	_ = dt.WithErr(cause, dt.StringKV("path", "x"), "path", path)        // want `dt.WithErr: duplicate key "path"`
+	_ = dt.NewErr(dt.ErrFailedToReadFile, "path", path, errOther, cause) // want `dt.NewErr: error errOther among key-value pairs is neither a sentinel nor the trailing cause`
+	_ = dt.NewErr(dt.ErrFailedToReadFile, path)                          // want `dt.NewErr: argument path of type github.com/mikeschinkel/go-dt.Filepath is not an error, string key or ErrKV`
+	_ = dt.NewErr(dt.ErrFailedToReadFile, nil)                           // want `argument nil of type untyped nil`
+}
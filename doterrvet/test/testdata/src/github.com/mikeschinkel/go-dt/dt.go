@@ -0,0 +1,29 @@
+// Package dt stubs the parts of github.com/mikeschinkel/go-dt the analyzer
+// looks at.
+package dt
+
+import "errors"
+
+type ErrKV interface {
+	Key() string
+	Value() any
+}
+
+type kv struct {
+	k string
+	v any
+}
+
+func (kv kv) Key() string { return kv.k }
+func (kv kv) Value() any  { return kv.v }
+
+type Filepath string
+
+var ErrFailedToReadFile = errors.New("failed to read file")
+
+func NewErr(parts ...any) error  { return nil }
+func WithErr(parts ...any) error { return nil }
+
+func StringKV(key, value string) ErrKV       { return kv{k: key, v: value} }
+func RetryableKV(retryable bool) ErrKV       { return kv{k: "retryable", v: retryable} }
+func AppendKV(kvs []ErrKV, _ ...any) []ErrKV { return kvs }
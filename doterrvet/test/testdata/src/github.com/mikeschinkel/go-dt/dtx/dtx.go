@@ -0,0 +1,8 @@
+package dtx
+
+import "github.com/mikeschinkel/go-dt"
+
+var (
+	NewErr  = dt.NewErr
+	WithErr = dt.WithErr
+)
@@ -0,0 +1,71 @@
+package dtglob
+
+import (
+	"io/fs"
+	"path/filepath"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+// ArchiveTo writes the files matched by all rules to a reproducible archive at
+// dest, placing each where CopyTo would place it relative to the installation
+// directory. The format is taken from dest's extension when format is
+// dt.UnknownArchive; see dt.ArchiveBuilder for what is stored.
+func (grs *GlobRules) ArchiveTo(dest dt.Filepath, format dt.ArchiveFormat, opts *dt.ArchiveOptions) (err error) {
+	var errs []error
+	var fsys fs.FS
+	var ab *dt.ArchiveBuilder
+
+	fsys = grs.FS
+	if fsys == nil {
+		fsys = grs.BaseDir.DirFS()
+	}
+	ab = dest.NewArchiveBuilder(format, opts)
+	for _, rule := range grs.Rules {
+		err = rule.archiveTo(fsys, ab)
+		if err != nil && !rule.Optional {
+			errs = dt.AppendErr(errs, err)
+		}
+	}
+	err = dt.CombineErrs(errs)
+	if err != nil {
+		goto end
+	}
+	err = ab.Write()
+end:
+	return err
+}
+
+// archiveTo adds the files matched by a single rule to ab
+func (rule *GlobRule) archiveTo(fsys fs.FS, ab *dt.ArchiveBuilder) (err error) {
+	var matches []string
+	var info fs.FileInfo
+	var destPath dt.Filepath
+	var errs []error
+
+	matches, err = rule.glob(fsys)
+	if err != nil {
+		goto end
+	}
+	for _, match := range matches {
+		info, err = fs.Stat(fsys, match)
+		if err != nil {
+			errs = dt.AppendErr(errs, err)
+			continue
+		}
+		if info.IsDir() {
+			// Skip directories - we only archive files
+			continue
+		}
+		destPath, err = rule.computeDestPath(dt.Filepath(match), "")
+		if err == nil {
+			err = ab.Add(fsys, match, filepath.ToSlash(string(destPath)))
+		}
+		if err != nil {
+			errs = dt.AppendErr(errs, err)
+		}
+	}
+	err = dt.CombineErrs(errs)
+end:
+	return err
+}
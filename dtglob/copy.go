@@ -2,15 +2,22 @@ package dtglob
 
 import (
 	"fmt"
+	"io/fs"
 	"strings"
 
 	"github.com/bmatcuk/doublestar/v4"
 	"github.com/mikeschinkel/go-dt"
 )
 
-// CopyTo applies all rules to copy files to the installation directory
+// CopyTo applies all rules to copy files to the installation directory. Files
+// are read from grs.FS when set and from grs.BaseDir otherwise.
 func (grs *GlobRules) CopyTo(installDir dt.DirPath, opts *dt.CopyOptions) (err error) {
 	var errs []error
+	var fileSys []fs.FS
+
+	if grs.FS != nil {
+		fileSys = []fs.FS{grs.FS}
+	}
 
 	// Normalize opts
 	if opts == nil {
@@ -19,7 +26,7 @@ func (grs *GlobRules) CopyTo(installDir dt.DirPath, opts *dt.CopyOptions) (err e
 
 	// 3. Copy all files (no MkdirAll per file)
 	for _, rule := range grs.Rules {
-		err = rule.copyTo(grs.BaseDir, installDir, opts)
+		err = rule.copyTo(grs.BaseDir, installDir, opts, fileSys...)
 		if err != nil && !rule.Optional {
 			errs = dt.AppendErr(errs, err)
 		}
@@ -30,40 +37,41 @@ func (grs *GlobRules) CopyTo(installDir dt.DirPath, opts *dt.CopyOptions) (err e
 	return err
 }
 
-// copyTo processes a single rule
-func (rule *GlobRule) copyTo(baseDir, installDir dt.DirPath, opts *dt.CopyOptions) (err error) {
+// copyTo processes a single rule, reading from fileSys[0] when passed and from
+// baseDir otherwise
+func (rule *GlobRule) copyTo(baseDir, installDir dt.DirPath, opts *dt.CopyOptions, fileSys ...fs.FS) (err error) {
 	var matches []string
 	var match string
 	var sourcePath dt.Filepath
 	var destPath dt.Filepath
 	var errs []error
+	var fsys fs.FS
 
-	// Find all files matching the glob pattern
-	matches, err = doublestar.Glob(baseDir.DirFS(), string(rule.From))
-	if err != nil {
-		err = fmt.Errorf("glob pattern error for '%s': %w", rule.From, err)
-		goto end
+	fsys = baseDir.DirFS()
+	if len(fileSys) > 0 {
+		fsys = fileSys[0]
 	}
 
-	if len(matches) == 0 {
-		if rule.Optional {
-			goto end // No matches for optional rule is OK
-		}
-		err = fmt.Errorf("no files matched pattern: %s", rule.From)
+	// Find all files matching the glob pattern
+	matches, err = rule.glob(fsys)
+	if err != nil {
 		goto end
 	}
 
 	// Process each matched file
 	for _, match = range matches {
 		sourcePath = dt.FilepathJoin(baseDir, match)
+		if len(fileSys) > 0 {
+			sourcePath = dt.Filepath(match)
+		}
 
-		var status dt.EntryStatus
-		status, err = sourcePath.Status()
+		var info fs.FileInfo
+		info, err = sourcePath.Stat(fileSys...)
 		if err != nil {
 			errs = dt.AppendErr(errs, err)
 			continue
 		}
-		if status == dt.IsDirEntry {
+		if info.IsDir() {
 			// Skip directories - we only copy files
 			continue
 		}
@@ -87,7 +95,7 @@ func (rule *GlobRule) copyTo(baseDir, installDir dt.DirPath, opts *dt.CopyOption
 				continue
 			}
 		}
-		err = sourcePath.CopyTo(destPath, opts)
+		err = sourcePath.CopyTo(destPath, opts, fileSys...)
 		if err != nil {
 			err = dt.WithErr(err,
 				dt.ErrFailedToCopyFile,
@@ -105,6 +113,23 @@ end:
 	return err
 }
 
+// glob returns the names in fsys matching the rule's pattern. A rule that is
+// not Optional fails when nothing matches.
+func (rule *GlobRule) glob(fsys fs.FS) (matches []string, err error) {
+	matches, err = doublestar.Glob(fsys, string(rule.From))
+	if err != nil {
+		err = fmt.Errorf("glob pattern error for '%s': %w", rule.From, err)
+		goto end
+	}
+
+	if len(matches) == 0 && !rule.Optional {
+		err = fmt.Errorf("no files matched pattern: %s", rule.From)
+	}
+
+end:
+	return matches, err
+}
+
 // computeDestPath determines the destination path for a matched file
 func (rule *GlobRule) computeDestPath(matchedPath dt.Filepath, destDir dt.DirPath) (destPath dt.Filepath, err error) {
 	var parts []string
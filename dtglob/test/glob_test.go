@@ -1,9 +1,19 @@
 package test
 
 import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/mikeschinkel/go-dt"
 	"github.com/mikeschinkel/go-dt/dtglob"
+	"github.com/mikeschinkel/go-dt/dtx/memfs"
 )
 
 func TestGlobContains(t *testing.T) {
@@ -91,3 +101,126 @@ func TestGlobSplit(t *testing.T) {
 		})
 	}
 }
+
+func TestGlobRulesCopyToFromFS(t *testing.T) {
+	files := map[string]string{
+		"app/bin/tool":          "tool",
+		"app/config/a.json":     "a",
+		"app/config/sub/b.json": "b",
+		"app/README.md":         "readme",
+	}
+	mapFS := fstest.MapFS{}
+	memFS := memfs.New(nil)
+	for name, body := range files {
+		mapFS[name] = &fstest.MapFile{Data: []byte(body), Mode: 0o644}
+		if err := memFS.MkdirAll(path.Dir(name), 0o755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := memFS.WriteFile(name, []byte(body), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	archive := dt.FilepathJoin(t.TempDir(), "app.zip")
+	f, err := archive.Create()
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	zw := zip.NewWriter(f)
+	if err = zw.AddFS(mapFS); err != nil {
+		t.Fatalf("AddFS() error = %v", err)
+	}
+	if err = zw.Close(); err != nil {
+		t.Fatalf("zip Close() error = %v", err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	afs, err := archive.OpenArchiveFS(nil)
+	if err != nil {
+		t.Fatalf("OpenArchiveFS() error = %v", err)
+	}
+	defer afs.Close()
+
+	rules := []dtglob.GlobRule{
+		{From: "app/bin/*", To: "bin/"},
+		{From: "app/config/**/*.json", To: "etc/"},
+		{From: "app/*.txt", To: "doc/", Optional: true},
+	}
+	sources := map[string]dtglob.GlobRules{
+		"MapFS":     {FS: mapFS, Rules: rules},
+		"MemFS":     {FS: memFS, Rules: rules},
+		"ArchiveFS": {FS: afs, Rules: rules},
+	}
+	for name, grs := range sources {
+		t.Run(name, func(t *testing.T) {
+			dest := dt.DirPath(t.TempDir())
+			if err := grs.CopyTo(dest, nil); err != nil {
+				t.Fatalf("CopyTo() error = %v", err)
+			}
+			want := map[string]string{
+				"bin/tool":       "tool",
+				"etc/a.json":     "a",
+				"etc/sub/b.json": "b",
+			}
+			for rel, body := range want {
+				got, err := os.ReadFile(string(dt.FilepathJoin(dest, rel)))
+				if err != nil || string(got) != body {
+					t.Errorf("ReadFile(%s) = %q, %v; want %q", rel, got, err, body)
+				}
+			}
+		})
+	}
+}
+
+func TestGlobRulesArchiveTo(t *testing.T) {
+	mapFS := fstest.MapFS{
+		"app/bin/tool":          {Data: []byte("tool"), Mode: 0o700},
+		"app/config/a.json":     {Data: []byte("a"), Mode: 0o600},
+		"app/config/sub/b.json": {Data: []byte("b"), Mode: 0o600},
+		"app/config/c.bak":      {Data: []byte("c"), Mode: 0o600},
+	}
+	grs := dtglob.GlobRules{
+		FS: mapFS,
+		Rules: []dtglob.GlobRule{
+			{From: "app/bin/*", To: "bin/"},
+			{From: "app/config/**/*", To: "etc/"},
+		},
+	}
+	opts := &dt.ArchiveOptions{Prefix: "app-1.0", Ignore: []string{"*.bak"}}
+	out := dt.DirPath(t.TempDir())
+	first := dt.FilepathJoin(out, "first.tar.gz")
+	second := dt.FilepathJoin(out, "second.tar.gz")
+	if err := grs.ArchiveTo(first, dt.UnknownArchive, opts); err != nil {
+		t.Fatalf("ArchiveTo() error = %v", err)
+	}
+	mapFS["app/bin/tool"].ModTime = time.Now()
+	if err := grs.ArchiveTo(second, dt.UnknownArchive, opts); err != nil {
+		t.Fatalf("ArchiveTo() error = %v", err)
+	}
+	a, _ := first.ReadFile()
+	b, _ := second.ReadFile()
+	if !bytes.Equal(a, b) {
+		t.Error("ArchiveTo() output differs between runs")
+	}
+
+	afs, err := first.OpenArchiveFS(nil)
+	if err != nil {
+		t.Fatalf("OpenArchiveFS() error = %v", err)
+	}
+	defer afs.Close()
+	want := map[string]fs.FileMode{
+		"app-1.0/bin/tool":       0o755,
+		"app-1.0/etc/a.json":     0o644,
+		"app-1.0/etc/sub/b.json": 0o644,
+	}
+	for name, mode := range want {
+		info, err := afs.Stat(name)
+		if err != nil || info.Mode() != mode {
+			t.Errorf("Stat(%s) = %v, %v; want mode %v", name, info, err, mode)
+		}
+	}
+	if _, err = afs.Stat("app-1.0/etc/c.bak"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat(c.bak) error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
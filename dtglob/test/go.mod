@@ -6,9 +6,12 @@ replace github.com/mikeschinkel/go-dt/dtglob => ..
 
 replace github.com/mikeschinkel/go-dt => ../..
 
-require github.com/mikeschinkel/go-dt/dtglob v0.0.0-00010101000000-000000000000
+replace github.com/mikeschinkel/go-dt/dtx => ../../dtx
 
 require (
-	github.com/bmatcuk/doublestar/v4 v4.9.1 // indirect
-	github.com/mikeschinkel/go-dt v0.3.2 // indirect
+	github.com/mikeschinkel/go-dt v0.5.0
+	github.com/mikeschinkel/go-dt/dtglob v0.0.0-00010101000000-000000000000
+	github.com/mikeschinkel/go-dt/dtx v0.0.0-00010101000000-000000000000
 )
+
+require github.com/bmatcuk/doublestar/v4 v4.9.1 // indirect
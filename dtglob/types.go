@@ -1,6 +1,7 @@
 package dtglob
 
 import (
+	"io/fs"
 	"strings"
 
 	"github.com/mikeschinkel/go-dt"
@@ -30,4 +31,8 @@ type GlobRule struct {
 type GlobRules struct {
 	BaseDir dt.DirPath // Source directory containing files to match
 	Rules   []GlobRule // Ordered list of copy rules
+
+	// FS, when set, is matched and copied from in place of BaseDir, e.g. an
+	// archive opened with dt.Filepath.OpenArchiveFS.
+	FS fs.FS
 }
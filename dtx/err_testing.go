@@ -0,0 +1,411 @@
+package dtx
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+// err_testing.go provides test assertions for doterr errors, replacing the
+// errors.Is and dt.ErrValue boilerplate, and a matcher for whole error trees:
+//
+//	dtx.AssertErrTree(t, err, dtx.ErrEntry(ErrFailedToCopy,
+//		"source", src,
+//		dtx.ErrEntry(fs.ErrNotExist, "path", src),
+//	))
+
+// AssertNoErr reports err, rendered with dt.FormatErrTree, when it is not nil.
+// It returns true when err is nil.
+func AssertNoErr(t testing.TB, err error) (ok bool) {
+	t.Helper()
+	ok = err == nil
+	if !ok {
+		t.Errorf("unexpected error:\n%s", dt.FormatErrTree(err))
+	}
+	return ok
+}
+
+// AssertErrIs reports an error unless err matches every sentinel with
+// errors.Is. It returns true when all match.
+func AssertErrIs(t testing.TB, err error, sentinels ...error) (ok bool) {
+	var missing []string
+
+	t.Helper()
+	if err == nil {
+		t.Errorf("got nil error, want one matching %s", errMessages(sentinels))
+		goto end
+	}
+	for _, sentinel := range sentinels {
+		if !errors.Is(err, sentinel) {
+			missing = append(missing, sentinel.Error())
+		}
+	}
+	if len(missing) > 0 {
+		t.Errorf("error does not match %q:\n%s", missing, dt.FormatErrTree(err))
+		goto end
+	}
+	ok = true
+end:
+	return ok
+}
+
+// AssertErrKV reports an error unless the first value for key in err's tree,
+// as found by dt.ErrValue, deeply equals want. It returns true when it does.
+func AssertErrKV(t testing.TB, err error, key string, want any) (ok bool) {
+	var got any
+
+	t.Helper()
+	if err == nil {
+		t.Errorf("got nil error, want one with %s=%#v", key, want)
+		goto end
+	}
+	got, ok = dt.ErrValue[any](err, key)
+	if !ok {
+		t.Errorf("error has no %q key:\n%s", key, dt.FormatErrTree(err))
+		goto end
+	}
+	ok = reflect.DeepEqual(got, want)
+	if !ok {
+		t.Errorf("error key %q = %#v (%T), want %#v (%T)", key, got, got, want, want)
+	}
+end:
+	return ok
+}
+
+// AssertErrTree reports an error unless err has the shape want, showing the
+// difference between the two trees. It returns true when err matches.
+func AssertErrTree(t testing.TB, err error, want ErrShape) (ok bool) {
+	var d errShapeDiff
+
+	t.Helper()
+	d.compare(want, err, 0)
+	ok = slices.Equal(d.want, d.got)
+	if !ok {
+		t.Errorf("error tree mismatch (-want +got):\n%s", diffLines(d.want, d.got))
+	}
+	return ok
+}
+
+type errShapeKind int
+
+const (
+	anyErrShape errShapeKind = iota
+	isErrShape
+	msgErrShape
+	entryErrShape
+	joinErrShape
+)
+
+// ErrShape describes an expected error tree for AssertErrTree. Build one with
+// ErrEntry, ErrJoin, ErrIs, ErrMsg or AnyErr.
+type ErrShape struct {
+	kind   errShapeKind
+	target error
+	msg    string
+	kvs    []dt.ErrKV
+	errs   []ErrShape
+}
+
+// ErrEntry matches a doterr entry built by dt.NewErr or dt.WithErr. Its parts
+// are, in any order:
+//
+//   - error: matches, with errors.Is, the entry's error in the same position,
+//   - ErrShape: matches the entry's error in the same position,
+//   - dt.ErrKV or "key", value: matches the entry's KV in the same position,
+//     comparing values with reflect.DeepEqual.
+//
+// The entry must hold exactly the errors and KVs given, in order; KVs are
+// compared unredacted.
+func ErrEntry(parts ...any) (s ErrShape) {
+	s.kind = entryErrShape
+	for i := 0; i < len(parts); i++ {
+		switch p := parts[i].(type) {
+		case ErrShape:
+			s.errs = append(s.errs, p)
+		case error:
+			s.errs = append(s.errs, ErrIs(p))
+		case dt.ErrKV:
+			s.kvs = append(s.kvs, p)
+		case string:
+			if i+1 == len(parts) {
+				Panicf("dtx.ErrEntry: key %q has no value", p)
+			}
+			s.kvs = append(s.kvs, dt.AnyKV(p, parts[i+1]))
+			i++
+		default:
+			Panicf("dtx.ErrEntry: invalid part of type %T at position %d", p, i)
+		}
+	}
+	return s
+}
+
+// ErrJoin matches a dt.CombineErrs or errors.Join result whose members match
+// members in order. Members that are errors match with errors.Is.
+func ErrJoin(members ...any) (s ErrShape) {
+	s.kind = joinErrShape
+	for i, m := range members {
+		switch m := m.(type) {
+		case ErrShape:
+			s.errs = append(s.errs, m)
+		case error:
+			s.errs = append(s.errs, ErrIs(m))
+		default:
+			Panicf("dtx.ErrJoin: invalid member of type %T at position %d", m, i)
+		}
+	}
+	return s
+}
+
+// ErrIs matches any error for which errors.Is(err, target) is true.
+func ErrIs(target error) ErrShape {
+	return ErrShape{kind: isErrShape, target: target}
+}
+
+// ErrMsg matches any error whose Error() is msg.
+func ErrMsg(msg string) ErrShape {
+	return ErrShape{kind: msgErrShape, msg: msg}
+}
+
+// AnyErr matches any non-nil error.
+func AnyErr() ErrShape {
+	return ErrShape{kind: anyErrShape}
+}
+
+// errShapeDiff holds the expected and actual trees as lines, rendered side by
+// side so matching parts render identically.
+type errShapeDiff struct {
+	want []string
+	got  []string
+}
+
+func (d *errShapeDiff) line(depth int, want, got string) {
+	indent := strings.Repeat("  ", depth)
+	d.want = append(d.want, indent+want)
+	d.got = append(d.got, indent+got)
+}
+
+func (d *errShapeDiff) compare(want ErrShape, got error, depth int) {
+	var members []error
+
+	if got == nil {
+		d.renderShape(want, depth)
+		d.got = append(d.got, strings.Repeat("  ", depth)+"<nil>")
+		goto end
+	}
+	switch want.kind {
+	case anyErrShape:
+		d.line(depth, "<any error>", "<any error>")
+		goto end
+	case isErrShape:
+		d.compareLeaf(depth, "error: "+want.target.Error(), got, errors.Is(got, want.target))
+		goto end
+	case msgErrShape:
+		d.compareLeaf(depth, "error: "+want.msg, got, got.Error() == want.msg)
+		goto end
+	case entryErrShape:
+		if !isErrEntry(got) {
+			break
+		}
+		d.line(depth, "entry", "entry")
+		d.compareKVs(want.kvs, errEntryKVs(got), depth+1)
+		d.compareErrs(want.errs, unwrapErrs(got), depth+1)
+		goto end
+	case joinErrShape:
+		members = unwrapErrs(got)
+		if isErrEntry(got) || members == nil {
+			break
+		}
+		d.line(depth, "join", "join")
+		d.compareErrs(want.errs, members, depth+1)
+		goto end
+	}
+	// Different kinds of error: show both in full
+	d.renderShape(want, depth)
+	d.renderErr(got, depth)
+end:
+	return
+}
+
+func (d *errShapeDiff) compareLeaf(depth int, want string, got error, matched bool) {
+	var gotLine string
+
+	switch {
+	case matched:
+		gotLine = want
+	case isErrEntry(got) || unwrapErrs(got) != nil:
+		d.want = append(d.want, strings.Repeat("  ", depth)+want)
+		d.renderErr(got, depth)
+		goto end
+	default:
+		gotLine = "error: " + got.Error()
+		if gotLine == want {
+			gotLine += " (different error)"
+		}
+	}
+	d.line(depth, want, gotLine)
+end:
+	return
+}
+
+func (d *errShapeDiff) compareKVs(want, got []dt.ErrKV, depth int) {
+	for i := range max(len(want), len(got)) {
+		switch {
+		case i >= len(got):
+			d.want = append(d.want, strings.Repeat("  ", depth)+formatErrKV(want[i], false))
+		case i >= len(want):
+			d.got = append(d.got, strings.Repeat("  ", depth)+formatErrKV(got[i], false))
+		case want[i].Key() == got[i].Key() && reflect.DeepEqual(errKVValue(want[i]), errKVValue(got[i])):
+			d.line(depth, formatErrKV(want[i], false), formatErrKV(want[i], false))
+		default:
+			w, g := formatErrKV(want[i], false), formatErrKV(got[i], false)
+			if w == g {
+				// Equal when printed, so show the types
+				w, g = formatErrKV(want[i], true), formatErrKV(got[i], true)
+			}
+			d.line(depth, w, g)
+		}
+	}
+}
+
+func (d *errShapeDiff) compareErrs(want []ErrShape, got []error, depth int) {
+	for i := range max(len(want), len(got)) {
+		switch {
+		case i >= len(got):
+			d.renderShape(want[i], depth)
+		case i >= len(want):
+			d.renderErr(got[i], depth)
+		default:
+			d.compare(want[i], got[i], depth)
+		}
+	}
+}
+
+// renderShape appends s to the expected lines only.
+func (d *errShapeDiff) renderShape(s ErrShape, depth int) {
+	indent := strings.Repeat("  ", depth)
+	switch s.kind {
+	case anyErrShape:
+		d.want = append(d.want, indent+"<any error>")
+	case isErrShape:
+		d.want = append(d.want, indent+"error: "+s.target.Error())
+	case msgErrShape:
+		d.want = append(d.want, indent+"error: "+s.msg)
+	case entryErrShape, joinErrShape:
+		if s.kind == entryErrShape {
+			d.want = append(d.want, indent+"entry")
+		} else {
+			d.want = append(d.want, indent+"join")
+		}
+		for _, kv := range s.kvs {
+			d.want = append(d.want, indent+"  "+formatErrKV(kv, false))
+		}
+		for _, child := range s.errs {
+			d.renderShape(child, depth+1)
+		}
+	}
+}
+
+// renderErr appends err to the actual lines only.
+func (d *errShapeDiff) renderErr(err error, depth int) {
+	indent := strings.Repeat("  ", depth)
+	members := unwrapErrs(err)
+	switch {
+	case isErrEntry(err):
+		d.got = append(d.got, indent+"entry")
+		for _, kv := range errEntryKVs(err) {
+			d.got = append(d.got, indent+"  "+formatErrKV(kv, false))
+		}
+	case members != nil:
+		d.got = append(d.got, indent+"join")
+	default:
+		d.got = append(d.got, indent+"error: "+err.Error())
+	}
+	for _, child := range members {
+		d.renderErr(child, depth+1)
+	}
+}
+
+func isErrEntry(err error) (ok bool) {
+	_, ok = dt.ErrEntryMeta(err)
+	return ok
+}
+
+func errEntryKVs(err error) (kvs []dt.ErrKV) {
+	kvs, _ = dt.ErrEntryMeta(err)
+	return kvs
+}
+
+func unwrapErrs(err error) (errs []error) {
+	if u, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = slices.DeleteFunc(u.Unwrap(), func(e error) bool { return e == nil })
+	}
+	return errs
+}
+
+// errKVValue returns the value of kv, unredacted.
+func errKVValue(kv dt.ErrKV) (v any) {
+	v = kv.Value()
+	if r, ok := v.(dt.Redacted); ok {
+		v = r.Value()
+	}
+	return v
+}
+
+func formatErrKV(kv dt.ErrKV, withType bool) string {
+	v := errKVValue(kv)
+	if withType {
+		return fmt.Sprintf("%s: %#v (%T)", kv.Key(), v, v)
+	}
+	return fmt.Sprintf("%s: %#v", kv.Key(), v)
+}
+
+func errMessages(errs []error) string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%q", msgs)
+}
+
+// diffLines returns a line diff of want and got, prefixing lines only in
+// want with "- ", only in got with "+ " and common lines with "  ".
+func diffLines(want, got []string) string {
+	var sb strings.Builder
+
+	// lcs[i][j] is the length of the longest common subsequence of want[i:]
+	// and got[j:]
+	lcs := make([][]int, len(want)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(got)+1)
+	}
+	for i := len(want) - 1; i >= 0; i-- {
+		for j := len(got) - 1; j >= 0; j-- {
+			if want[i] == got[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+				continue
+			}
+			lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+		}
+	}
+	i, j := 0, 0
+	for i < len(want) || j < len(got) {
+		switch {
+		case i < len(want) && j < len(got) && want[i] == got[j]:
+			sb.WriteString("  " + want[i] + "\n")
+			i++
+			j++
+		case j == len(got) || (i < len(want) && lcs[i+1][j] >= lcs[i][j+1]):
+			sb.WriteString("- " + want[i] + "\n")
+			i++
+		default:
+			sb.WriteString("+ " + got[j] + "\n")
+			j++
+		}
+	}
+	return sb.String()
+}
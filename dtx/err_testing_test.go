@@ -0,0 +1,128 @@
+package dtx_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-dt"
+	"github.com/mikeschinkel/go-dt/dtx"
+)
+
+// recordingTB records the failures reported to it.
+type recordingTB struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+var errTestCopy = errors.New("failed to copy")
+
+func TestAssertErrHelpers(t *testing.T) {
+	err := dt.NewErr(errTestCopy, "source", dt.Filepath("a.txt"), "size", 10, fs.ErrNotExist)
+
+	tests := []struct {
+		name   string
+		assert func(tb testing.TB) bool
+		want   string
+	}{
+		{name: "no error", assert: func(tb testing.TB) bool { return dtx.AssertNoErr(tb, nil) }},
+		{name: "unexpected error", assert: func(tb testing.TB) bool { return dtx.AssertNoErr(tb, err) }, want: "unexpected error:\nfailed to copy"},
+		{name: "is", assert: func(tb testing.TB) bool { return dtx.AssertErrIs(tb, err, errTestCopy, fs.ErrNotExist) }},
+		{name: "is not", assert: func(tb testing.TB) bool { return dtx.AssertErrIs(tb, err, errTestCopy, fs.ErrExist) }, want: `error does not match ["file already exists"]`},
+		{name: "is nil", assert: func(tb testing.TB) bool { return dtx.AssertErrIs(tb, nil, errTestCopy) }, want: `got nil error, want one matching ["failed to copy"]`},
+		{name: "kv", assert: func(tb testing.TB) bool { return dtx.AssertErrKV(tb, err, "source", dt.Filepath("a.txt")) }},
+		{name: "kv type differs", assert: func(tb testing.TB) bool { return dtx.AssertErrKV(tb, err, "source", "a.txt") }, want: `error key "source" = "a.txt" (dt.Filepath), want "a.txt" (string)`},
+		{name: "kv missing", assert: func(tb testing.TB) bool { return dtx.AssertErrKV(tb, err, "dest", "b.txt") }, want: `error has no "dest" key`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := &recordingTB{TB: t}
+			ok := tt.assert(tb)
+			if ok != (tt.want == "") {
+				t.Errorf("assertion returned %v, failures %q", ok, tb.failures)
+			}
+			if tt.want != "" && (len(tb.failures) != 1 || !strings.HasPrefix(tb.failures[0], tt.want)) {
+				t.Errorf("failures = %q, want one starting with %q", tb.failures, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssertErrTree(t *testing.T) {
+	inner := dt.NewErr(fs.ErrNotExist, "path", "a.txt")
+	err := dt.NewErr(errTestCopy, dt.SecretKV("token", "s3cret"), "source", "a.txt",
+		dt.CombineErrs([]error{inner, errors.New("disk full")}),
+	)
+
+	tests := []struct {
+		name  string
+		shape dtx.ErrShape
+		want  string
+	}{
+		{
+			name: "matches",
+			shape: dtx.ErrEntry(errTestCopy, "token", "s3cret", "source", "a.txt",
+				dtx.ErrJoin(
+					dtx.ErrEntry(fs.ErrNotExist, "path", "a.txt"),
+					dtx.ErrMsg("disk full"),
+				),
+			),
+		},
+		{
+			name:  "wildcards",
+			shape: dtx.ErrEntry(errTestCopy, "token", "s3cret", "source", "a.txt", dtx.AnyErr()),
+		},
+		{
+			name: "differs",
+			shape: dtx.ErrEntry(errTestCopy, "token", "s3cret", "source", dt.Filepath("a.txt"),
+				dtx.ErrJoin(
+					dtx.ErrEntry(fs.ErrPermission, "path", "a.txt"),
+				),
+			),
+			want: strings.Join([]string{
+				"error tree mismatch (-want +got):",
+				"  entry",
+				`    token: "s3cret"`,
+				`-   source: "a.txt" (dt.Filepath)`,
+				`+   source: "a.txt" (string)`,
+				"    error: failed to copy",
+				"    join",
+				"      entry",
+				`        path: "a.txt"`,
+				"-       error: permission denied",
+				"+       error: file does not exist",
+				"+     error: disk full",
+				"",
+			}, "\n"),
+		},
+		{
+			name:  "nil",
+			shape: dtx.ErrEntry(errTestCopy),
+			want:  "error tree mismatch (-want +got):\n- entry\n-   error: failed to copy\n+ <nil>\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := &recordingTB{TB: t}
+			e := err
+			if tt.name == "nil" {
+				e = nil
+			}
+			ok := dtx.AssertErrTree(tb, e, tt.shape)
+			if ok != (tt.want == "") {
+				t.Errorf("AssertErrTree() = %v, failures:\n%s", ok, strings.Join(tb.failures, "\n"))
+			}
+			if tt.want != "" && (len(tb.failures) != 1 || tb.failures[0] != tt.want) {
+				t.Errorf("failures:\n%s\nwant:\n%s", strings.Join(tb.failures, "\n"), tt.want)
+			}
+		})
+	}
+}
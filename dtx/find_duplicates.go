@@ -0,0 +1,425 @@
+package dtx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"iter"
+	"os"
+	"slices"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+var (
+	ErrFailedToHashFile        = errors.New("failed to hash file")
+	ErrDuplicateContentChanged = errors.New("duplicate content changed since it was hashed")
+	ErrFailedToHardlinkFile    = errors.New("failed to hardlink file")
+)
+
+// DefaultPartialHashSize is the number of leading bytes hashed during the
+// second stage of FindDuplicates when FindDuplicatesOptions.PartialHashSize is
+// zero.
+const DefaultPartialHashSize = 4096
+
+// DuplicateAction controls what FindDuplicates does with each duplicate group.
+type DuplicateAction uint8
+
+const (
+	// ReportDuplicates only reports groups; no files are changed.
+	ReportDuplicates DuplicateAction = iota
+
+	// HardlinkDuplicates replaces every duplicate in a group with a hardlink
+	// to the group's original (its first Filepath).
+	HardlinkDuplicates
+
+	// RemoveDuplicates removes every duplicate in a group, keeping only the
+	// group's original (its first Filepath).
+	RemoveDuplicates
+)
+
+// FindDuplicatesOptions configures DirPaths.FindDuplicates.
+type FindDuplicatesOptions struct {
+	// MinSize excludes files smaller than MinSize bytes. Empty files are always
+	// excluded because they are trivially identical.
+	MinSize int64
+
+	// PartialHashSize is how many leading bytes are hashed to split same-size
+	// candidates before hashing full contents. Zero means DefaultPartialHashSize.
+	PartialHashSize int64
+
+	// Action is applied to each group before it is yielded.
+	Action DuplicateAction
+
+	// SkipPaths names directories (by base name) that are not descended into,
+	// e.g. ".git".
+	SkipPaths []dt.PathSegment
+}
+
+// DuplicateGroup is a set of files with identical content. The first Filepath
+// is the original that HardlinkDuplicates and RemoveDuplicates keep; the rest
+// are its duplicates. Filepaths are sorted so results are deterministic.
+type DuplicateGroup struct {
+	Size      int64
+	Hash      string // hex-encoded SHA-256 of the full contents
+	Filepaths []dt.Filepath
+}
+
+// Original returns the Filepath that is kept when duplicates are acted upon.
+func (g DuplicateGroup) Original() dt.Filepath {
+	return g.Filepaths[0]
+}
+
+// Duplicates returns the Filepaths that are redundant copies of Original().
+func (g DuplicateGroup) Duplicates() []dt.Filepath {
+	return g.Filepaths[1:]
+}
+
+// FindDuplicates walks every directory in dps and yields groups of regular
+// files that have identical content, using the classic three-stage approach:
+// files are grouped by size, then same-size files by a hash of their first
+// PartialHashSize bytes, and only then by a hash of their full contents.
+//
+// Symlinks are never followed and paths that are already hardlinks of one
+// another are treated as a single file. Per-file errors are yielded with a
+// zero DuplicateGroup and do not stop the search.
+//
+// When opts.Action is HardlinkDuplicates or RemoveDuplicates each duplicate is
+// compared byte-for-byte with the original immediately before it is changed,
+// and hardlinks replace duplicates atomically via a rename, so a file that was
+// modified after hashing is never lost.
+func (dps DirPaths) FindDuplicates(opts *FindDuplicatesOptions) iter.Seq2[DuplicateGroup, error] {
+	return func(yield func(DuplicateGroup, error) bool) {
+		var bySize map[int64][]dupCandidate
+		var sizes []int64
+
+		if opts == nil {
+			opts = new(FindDuplicatesOptions)
+		}
+		partialSize := opts.PartialHashSize
+		if partialSize <= 0 {
+			partialSize = DefaultPartialHashSize
+		}
+
+		// Stage 1: group by size.
+		bySize = make(map[int64][]dupCandidate)
+		for c, err := range dps.dupCandidates(opts) {
+			if err != nil {
+				if !yield(DuplicateGroup{}, err) {
+					goto end
+				}
+				continue
+			}
+			if slices.ContainsFunc(bySize[c.size], c.sameFile) {
+				// Already a hardlink of a file we have seen.
+				continue
+			}
+			bySize[c.size] = append(bySize[c.size], c)
+		}
+
+		sizes = make([]int64, 0, len(bySize))
+		for size, cands := range bySize {
+			if len(cands) < 2 {
+				continue
+			}
+			sizes = append(sizes, size)
+		}
+		slices.Sort(sizes)
+
+		for _, size := range sizes {
+			cands := bySize[size]
+
+			// Stage 2: group same-size files by partial hash. When the whole file
+			// fits in the partial read the partial hash is already the full hash.
+			stage := partialHash(partialSize)
+			if size <= partialSize {
+				stage = fullHash
+			}
+			groups, ok := groupByHash(cands, stage, yield)
+			if !ok {
+				goto end
+			}
+
+			for _, group := range groups {
+				if size > partialSize {
+					// Stage 3: group by full content hash.
+					var full [][]dupCandidate
+					full, ok = groupByHash(group, fullHash, yield)
+					if !ok {
+						goto end
+					}
+					for _, g := range full {
+						if !yieldDuplicateGroup(g, opts, yield) {
+							goto end
+						}
+					}
+					continue
+				}
+				if !yieldDuplicateGroup(group, opts, yield) {
+					goto end
+				}
+			}
+		}
+	end:
+		return
+	}
+}
+
+// dupCandidate is a regular file being considered by FindDuplicates.
+type dupCandidate struct {
+	fp   dt.Filepath
+	size int64
+	info os.FileInfo
+	hash string
+}
+
+func (c dupCandidate) sameFile(other dupCandidate) bool {
+	return os.SameFile(c.info, other.info)
+}
+
+// dupCandidates walks dps and yields every regular file of interest exactly
+// once per path.
+func (dps DirPaths) dupCandidates(opts *FindDuplicatesOptions) iter.Seq2[dupCandidate, error] {
+	return func(yield func(dupCandidate, error) bool) {
+		seen := make(map[dt.Filepath]struct{})
+		skip := ToLookupMap(opts.SkipPaths, func(ps dt.PathSegment) dt.PathSegment { return ps })
+		for _, dp := range dps.Unique() {
+			for de, err := range dp.Walk() {
+				if err != nil {
+					if !yield(dupCandidate{}, dt.WithErr(err, dp.ErrKV())) {
+						goto end
+					}
+					continue
+				}
+				if de.IsDir() {
+					_, ok := skip[de.Rel.Base()]
+					if ok {
+						de.SkipDir()
+					}
+					continue
+				}
+				if !de.IsFile() {
+					continue
+				}
+				info, err := de.Entry.Info()
+				if err != nil {
+					if !yield(dupCandidate{}, dt.WithErr(err, de.EntryPath().ErrKV())) {
+						goto end
+					}
+					continue
+				}
+				if info.Size() == 0 || info.Size() < opts.MinSize {
+					continue
+				}
+				fp := de.Filepath()
+				if _, ok := seen[fp]; ok {
+					continue
+				}
+				seen[fp] = struct{}{}
+				if !yield(dupCandidate{fp: fp, size: info.Size(), info: info}, nil) {
+					goto end
+				}
+			}
+		}
+	end:
+		return
+	}
+}
+
+type hashFunc func(dt.Filepath) (string, error)
+
+func partialHash(n int64) hashFunc {
+	return func(fp dt.Filepath) (string, error) {
+		return hashFile(fp, n)
+	}
+}
+
+func fullHash(fp dt.Filepath) (string, error) {
+	return hashFile(fp, -1)
+}
+
+// hashFile returns the hex SHA-256 of the first n bytes of fp, or of the whole
+// file when n is negative.
+func hashFile(fp dt.Filepath, n int64) (hash string, err error) {
+	var f *os.File
+	var r io.Reader
+
+	f, err = fp.Open()
+	if err != nil {
+		goto end
+	}
+	defer dt.CloseOrLog(f)
+
+	r = f
+	if n >= 0 {
+		r = io.LimitReader(f, n)
+	}
+	{
+		h := sha256.New()
+		_, err = io.Copy(h, r)
+		if err != nil {
+			goto end
+		}
+		hash = hex.EncodeToString(h.Sum(nil))
+	}
+end:
+	if err != nil {
+		err = dt.NewErr(ErrFailedToHashFile, fp.ErrKV(), err)
+	}
+	return hash, err
+}
+
+// groupByHash splits cands into groups of two or more that share the same
+// hash. Files that cannot be hashed are yielded as errors and dropped. It
+// returns false if the consumer stopped iteration.
+func groupByHash(cands []dupCandidate, hf hashFunc, yield func(DuplicateGroup, error) bool) (groups [][]dupCandidate, ok bool) {
+	var hashes []string
+
+	byHash := make(map[string][]dupCandidate, len(cands))
+	for _, c := range cands {
+		hash, err := hf(c.fp)
+		if err != nil {
+			if !yield(DuplicateGroup{}, err) {
+				goto end
+			}
+			continue
+		}
+		c.hash = hash
+		if _, found := byHash[hash]; !found {
+			hashes = append(hashes, hash)
+		}
+		byHash[hash] = append(byHash[hash], c)
+	}
+	for _, hash := range hashes {
+		if len(byHash[hash]) < 2 {
+			continue
+		}
+		groups = append(groups, byHash[hash])
+	}
+	ok = true
+end:
+	return groups, ok
+}
+
+// yieldDuplicateGroup applies opts.Action to a group of candidates sharing a
+// full hash and then yields it. It returns false if the consumer stopped
+// iteration.
+func yieldDuplicateGroup(cands []dupCandidate, opts *FindDuplicatesOptions, yield func(DuplicateGroup, error) bool) (ok bool) {
+	var errs []error
+
+	group := DuplicateGroup{
+		Size:      cands[0].size,
+		Hash:      cands[0].hash,
+		Filepaths: make([]dt.Filepath, len(cands)),
+	}
+	for i, c := range cands {
+		group.Filepaths[i] = c.fp
+	}
+	slices.Sort(group.Filepaths)
+
+	switch opts.Action {
+	case HardlinkDuplicates, RemoveDuplicates:
+		for _, dup := range group.Duplicates() {
+			errs = dt.AppendErr(errs, resolveDuplicate(group.Original(), dup, opts.Action))
+		}
+	case ReportDuplicates:
+		fallthrough
+	default:
+		// Nothing to do
+	}
+	return yield(group, dt.CombineErrs(errs))
+}
+
+// resolveDuplicate hardlinks or removes dup after verifying that it still has
+// exactly the same content as orig.
+func resolveDuplicate(orig, dup dt.Filepath, action DuplicateAction) (err error) {
+	var same bool
+	var tmp dt.Filepath
+
+	same, err = sameContent(orig, dup)
+	if err != nil {
+		goto end
+	}
+	if !same {
+		err = dt.NewErr(ErrDuplicateContentChanged)
+		goto end
+	}
+
+	if action == RemoveDuplicates {
+		err = dup.Remove()
+		if err != nil {
+			err = dt.NewErr(dt.ErrFailedToRemoveFile, err)
+		}
+		goto end
+	}
+
+	// Link under a temporary name in the same directory, then rename over the
+	// duplicate so that it is replaced atomically.
+	tmp = dt.Filepath(string(dup) + ".dtx-dup-tmp")
+	err = os.Link(string(orig), string(tmp))
+	if err != nil {
+		err = dt.NewErr(ErrFailedToHardlinkFile, err)
+		goto end
+	}
+	err = tmp.Rename(dup)
+	if err != nil {
+		dt.LogOnErr(tmp.Remove())
+		err = dt.NewErr(ErrFailedToHardlinkFile, err)
+		goto end
+	}
+end:
+	if err != nil {
+		err = dt.WithErr(err,
+			"original", orig,
+			"duplicate", dup,
+		)
+	}
+	return err
+}
+
+// sameContent compares two files byte-for-byte.
+func sameContent(fp1, fp2 dt.Filepath) (same bool, err error) {
+	var f1, f2 *os.File
+	var n1, n2 int
+	var err1, err2 error
+
+	buf1 := make([]byte, 32*1024)
+	buf2 := make([]byte, 32*1024)
+
+	f1, err = fp1.Open()
+	if err != nil {
+		goto end
+	}
+	defer dt.CloseOrLog(f1)
+
+	f2, err = fp2.Open()
+	if err != nil {
+		goto end
+	}
+	defer dt.CloseOrLog(f2)
+
+	for {
+		n1, err1 = io.ReadFull(f1, buf1)
+		n2, err2 = io.ReadFull(f2, buf2)
+		if n1 != n2 || !bytes.Equal(buf1[:n1], buf2[:n2]) {
+			goto end
+		}
+		if err1 != nil || err2 != nil {
+			break
+		}
+	}
+	switch {
+	case errors.Is(err1, io.EOF) && errors.Is(err2, io.EOF):
+		same = true
+	case errors.Is(err1, io.ErrUnexpectedEOF) && errors.Is(err2, io.ErrUnexpectedEOF):
+		same = true
+	case err1 != nil && !errors.Is(err1, io.EOF) && !errors.Is(err1, io.ErrUnexpectedEOF):
+		err = err1
+	case err2 != nil && !errors.Is(err2, io.EOF) && !errors.Is(err2, io.ErrUnexpectedEOF):
+		err = err2
+	}
+end:
+	return same, err
+}
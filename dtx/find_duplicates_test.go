@@ -0,0 +1,179 @@
+package dtx
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+func writeDupTestFiles(t *testing.T, root dt.DirPath, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		fp := dt.FilepathJoin(root, name)
+		if err := fp.Dir().MkdirAll(0o755); err != nil {
+			t.Fatalf("MkdirAll(%s) error = %v", fp.Dir(), err)
+		}
+		if err := fp.WriteFile([]byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile(%s) error = %v", fp, err)
+		}
+	}
+}
+
+func collectDuplicates(t *testing.T, dps DirPaths, opts *FindDuplicatesOptions) (groups []DuplicateGroup) {
+	t.Helper()
+	for g, err := range dps.FindDuplicates(opts) {
+		if err != nil {
+			t.Fatalf("FindDuplicates() error = %v", err)
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+func TestDirPaths_FindDuplicates(t *testing.T) {
+	if err := dt.EnsureUserHomeDir(); err != nil {
+		t.Fatalf("EnsureUserHomeDir() error = %v", err)
+	}
+	dir1 := TempTestDir(t)
+	dir2 := TempTestDir(t)
+
+	// Same size, same prefix, different tail: only stage 3 can tell them apart.
+	prefix := strings.Repeat("x", 64)
+	writeDupTestFiles(t, dir1, map[string]string{
+		"a.txt":        "hello world",
+		"sub/b.txt":    "hello world",
+		"c.txt":        "hello there",
+		"empty1.txt":   "",
+		"empty2.txt":   "",
+		"long1.bin":    prefix + "AAAA",
+		"long2.bin":    prefix + "BBBB",
+		".git/x.txt":   "hello world",
+		"unique.txt":   "nothing like it",
+		"long-dup.bin": prefix + "AAAA",
+	})
+	writeDupTestFiles(t, dir2, map[string]string{
+		"d.txt": "hello world",
+	})
+
+	groups := collectDuplicates(t, DirPaths{dir1, dir2, dir1}, &FindDuplicatesOptions{
+		PartialHashSize: 16,
+		SkipPaths:       []dt.PathSegment{".git"},
+	})
+
+	if len(groups) != 2 {
+		t.Fatalf("FindDuplicates() returned %d groups, want 2: %v", len(groups), groups)
+	}
+
+	wantHello := []dt.Filepath{
+		dt.FilepathJoin(dir1, "a.txt"),
+		dt.FilepathJoin(dir1, "sub/b.txt"),
+		dt.FilepathJoin(dir2, "d.txt"),
+	}
+	slices.Sort(wantHello)
+	if !slices.Equal(groups[0].Filepaths, wantHello) {
+		t.Errorf("groups[0] = %v, want %v", groups[0].Filepaths, wantHello)
+	}
+	if groups[0].Size != int64(len("hello world")) {
+		t.Errorf("groups[0].Size = %d, want %d", groups[0].Size, len("hello world"))
+	}
+
+	wantLong := []dt.Filepath{
+		dt.FilepathJoin(dir1, "long-dup.bin"),
+		dt.FilepathJoin(dir1, "long1.bin"),
+	}
+	if !slices.Equal(groups[1].Filepaths, wantLong) {
+		t.Errorf("groups[1] = %v, want %v", groups[1].Filepaths, wantLong)
+	}
+}
+
+func TestDirPaths_FindDuplicates_MinSize(t *testing.T) {
+	dir := TempTestDir(t)
+	writeDupTestFiles(t, dir, map[string]string{
+		"a.txt": "tiny",
+		"b.txt": "tiny",
+	})
+	groups := collectDuplicates(t, DirPaths{dir}, &FindDuplicatesOptions{MinSize: 5})
+	if len(groups) != 0 {
+		t.Errorf("FindDuplicates() returned %d groups, want 0", len(groups))
+	}
+}
+
+func TestDirPaths_FindDuplicates_Hardlink(t *testing.T) {
+	dir := TempTestDir(t)
+	writeDupTestFiles(t, dir, map[string]string{
+		"a.txt": "same content",
+		"b.txt": "same content",
+		"c.txt": "same content",
+	})
+
+	groups := collectDuplicates(t, DirPaths{dir}, &FindDuplicatesOptions{Action: HardlinkDuplicates})
+	if len(groups) != 1 || len(groups[0].Filepaths) != 3 {
+		t.Fatalf("FindDuplicates() = %v, want one group of 3", groups)
+	}
+
+	orig, err := groups[0].Original().Stat()
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	for _, dup := range groups[0].Duplicates() {
+		info, err := dup.Stat()
+		if err != nil {
+			t.Fatalf("Stat(%s) error = %v", dup, err)
+		}
+		if !os.SameFile(orig, info) {
+			t.Errorf("%s is not a hardlink of %s", dup, groups[0].Original())
+		}
+	}
+
+	// Once hardlinked, the paths are the same file and no longer duplicates.
+	groups = collectDuplicates(t, DirPaths{dir}, nil)
+	if len(groups) != 0 {
+		t.Errorf("FindDuplicates() after hardlinking returned %v, want none", groups)
+	}
+}
+
+func TestDirPaths_FindDuplicates_Remove(t *testing.T) {
+	dir := TempTestDir(t)
+	writeDupTestFiles(t, dir, map[string]string{
+		"a.txt": "same content",
+		"b.txt": "same content",
+	})
+
+	groups := collectDuplicates(t, DirPaths{dir}, &FindDuplicatesOptions{Action: RemoveDuplicates})
+	if len(groups) != 1 {
+		t.Fatalf("FindDuplicates() = %v, want one group", groups)
+	}
+	exists, err := dt.FilepathJoin(dir, "a.txt").Exists()
+	if err != nil || !exists {
+		t.Errorf("original a.txt should still exist; exists=%v err=%v", exists, err)
+	}
+	exists, err = dt.FilepathJoin(dir, "b.txt").Exists()
+	if err != nil || exists {
+		t.Errorf("duplicate b.txt should be removed; exists=%v err=%v", exists, err)
+	}
+}
+
+func TestResolveDuplicate_ContentChanged(t *testing.T) {
+	if err := dt.EnsureUserHomeDir(); err != nil {
+		t.Fatalf("EnsureUserHomeDir() error = %v", err)
+	}
+	dir := TempTestDir(t)
+	writeDupTestFiles(t, dir, map[string]string{
+		"a.txt": "same content",
+		"b.txt": "different!!!",
+	})
+	orig := dt.FilepathJoin(dir, "a.txt")
+	dup := dt.FilepathJoin(dir, "b.txt")
+
+	err := resolveDuplicate(orig, dup, RemoveDuplicates)
+	if err == nil {
+		t.Fatal("resolveDuplicate() error = nil, want ErrDuplicateContentChanged")
+	}
+	exists, _ := dup.Exists()
+	if !exists {
+		t.Error("resolveDuplicate() removed a file whose content changed")
+	}
+}
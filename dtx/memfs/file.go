@@ -0,0 +1,304 @@
+package memfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"syscall"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+// open implements Open and OpenFile.
+func (fsys *FS) open(op, name string, flag int, perm fs.FileMode) (f dt.WritableFile, err error) {
+	var n *node
+	var created bool
+
+	access := flag & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR)
+	canRead := access == os.O_RDONLY || access == os.O_RDWR
+	canWrite := access == os.O_WRONLY || access == os.O_RDWR
+
+	if !fs.ValidPath(name) {
+		err = fs.ErrInvalid
+		goto end
+	}
+
+	if canWrite || flag&(os.O_CREATE|os.O_TRUNC) != 0 {
+		fsys.mu.Lock()
+		defer fsys.mu.Unlock()
+	} else {
+		fsys.mu.RLock()
+		defer fsys.mu.RUnlock()
+	}
+
+	n, err = fsys.lookup(name, true)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && flag&os.O_CREATE != 0:
+		n, err = fsys.create(name, perm)
+		if err != nil {
+			goto end
+		}
+		created = true
+	case err != nil:
+		goto end
+	case flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		err = fs.ErrExist
+		goto end
+	}
+
+	if n.isDir() {
+		if canWrite {
+			err = syscall.EISDIR
+			goto end
+		}
+		if !fsys.canRead(n) {
+			err = fs.ErrPermission
+			goto end
+		}
+		f = &dirHandle{fsys: fsys, n: n, name: name}
+		goto end
+	}
+
+	// As with the OS, permissions of a file are only checked when it already
+	// existed; the creator gets the access it asked for.
+	if !created && canRead && !fsys.canRead(n) {
+		err = fs.ErrPermission
+		goto end
+	}
+	if !created && canWrite && !fsys.canWrite(n) {
+		err = fs.ErrPermission
+		goto end
+	}
+	if canWrite && flag&os.O_TRUNC != 0 {
+		n.data = nil
+		n.modTime = fsys.opts.Now()
+	}
+	f = &fileHandle{
+		fsys:     fsys,
+		n:        n,
+		name:     name,
+		canRead:  canRead,
+		canWrite: canWrite,
+		append:   flag&os.O_APPEND != 0,
+	}
+end:
+	if err != nil {
+		err = pathErr(op, name, err)
+	}
+	return f, err
+}
+
+// create adds an empty regular file. Callers must hold fsys.mu for writing.
+func (fsys *FS) create(name string, perm fs.FileMode) (n *node, err error) {
+	var dir *node
+	var base string
+
+	dir, base, err = fsys.lookupParent(name)
+	if err != nil {
+		goto end
+	}
+	if _, ok := dir.children[fsys.key(base)]; ok {
+		// A dangling symlink; creating through it is not supported.
+		err = fs.ErrNotExist
+		goto end
+	}
+	if !fsys.canWrite(dir) {
+		err = fs.ErrPermission
+		goto end
+	}
+	n = fsys.newNode(base, perm.Perm())
+	dir.children[fsys.key(base)] = n
+	dir.modTime = n.modTime
+end:
+	return n, err
+}
+
+var (
+	_ dt.WritableFile = (*fileHandle)(nil)
+	_ io.Seeker       = (*fileHandle)(nil)
+	_ fs.ReadDirFile  = (*dirHandle)(nil)
+)
+
+// fileHandle is an open regular file. Like *os.File, a single handle must not
+// be used from multiple goroutines at once, but separate handles may be.
+type fileHandle struct {
+	fsys     *FS
+	n        *node
+	name     string
+	offset   int64
+	canRead  bool
+	canWrite bool
+	append   bool
+	closed   bool
+}
+
+func (f *fileHandle) Stat() (info fs.FileInfo, err error) {
+	if f.closed {
+		err = pathErr("stat", f.name, fs.ErrClosed)
+		goto end
+	}
+	f.fsys.mu.RLock()
+	info = f.n.info()
+	f.fsys.mu.RUnlock()
+end:
+	return info, err
+}
+
+func (f *fileHandle) Read(p []byte) (n int, err error) {
+	if f.closed {
+		err = pathErr("read", f.name, fs.ErrClosed)
+		goto end
+	}
+	if !f.canRead {
+		err = pathErr("read", f.name, syscall.EBADF)
+		goto end
+	}
+	f.fsys.mu.RLock()
+	defer f.fsys.mu.RUnlock()
+	if f.offset >= int64(len(f.n.data)) {
+		err = io.EOF
+		goto end
+	}
+	n = copy(p, f.n.data[f.offset:])
+	f.offset += int64(n)
+end:
+	return n, err
+}
+
+func (f *fileHandle) Write(p []byte) (n int, err error) {
+	var end int64
+
+	if f.closed {
+		err = pathErr("write", f.name, fs.ErrClosed)
+		goto end
+	}
+	if !f.canWrite {
+		err = pathErr("write", f.name, syscall.EBADF)
+		goto end
+	}
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if f.append {
+		f.offset = int64(len(f.n.data))
+	}
+	end = f.offset + int64(len(p))
+	if end > int64(len(f.n.data)) {
+		f.n.data = slices.Grow(f.n.data, int(end)-len(f.n.data))[:end]
+	}
+	n = copy(f.n.data[f.offset:], p)
+	f.offset = end
+	f.n.modTime = f.fsys.opts.Now()
+end:
+	return n, err
+}
+
+func (f *fileHandle) Seek(offset int64, whence int) (pos int64, err error) {
+	if f.closed {
+		err = pathErr("seek", f.name, fs.ErrClosed)
+		goto end
+	}
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = f.offset + offset
+	case io.SeekEnd:
+		f.fsys.mu.RLock()
+		pos = int64(len(f.n.data)) + offset
+		f.fsys.mu.RUnlock()
+	default:
+		err = pathErr("seek", f.name, fs.ErrInvalid)
+		goto end
+	}
+	if pos < 0 {
+		pos, err = f.offset, pathErr("seek", f.name, fs.ErrInvalid)
+		goto end
+	}
+	f.offset = pos
+end:
+	return pos, err
+}
+
+func (f *fileHandle) Close() (err error) {
+	if f.closed {
+		err = pathErr("close", f.name, fs.ErrClosed)
+	}
+	f.closed = true
+	return err
+}
+
+// dirHandle is an open directory.
+type dirHandle struct {
+	fsys    *FS
+	n       *node
+	name    string
+	entries []fs.DirEntry
+	offset  int
+	read    bool
+	closed  bool
+}
+
+func (d *dirHandle) Stat() (info fs.FileInfo, err error) {
+	if d.closed {
+		err = pathErr("stat", d.name, fs.ErrClosed)
+		goto end
+	}
+	d.fsys.mu.RLock()
+	info = d.n.info()
+	d.fsys.mu.RUnlock()
+end:
+	return info, err
+}
+
+func (d *dirHandle) Read([]byte) (int, error) {
+	return 0, pathErr("read", d.name, syscall.EISDIR)
+}
+
+func (d *dirHandle) Write([]byte) (int, error) {
+	return 0, pathErr("write", d.name, syscall.EISDIR)
+}
+
+// ReadDir returns the directory's entries as described by fs.ReadDirFile.
+// Entries are snapshotted on the first call.
+func (d *dirHandle) ReadDir(count int) (entries []fs.DirEntry, err error) {
+	var rest []fs.DirEntry
+
+	if d.closed {
+		err = pathErr("readdir", d.name, fs.ErrClosed)
+		goto end
+	}
+	if !d.read {
+		d.fsys.mu.RLock()
+		d.entries, err = d.fsys.readDir(d.n)
+		d.fsys.mu.RUnlock()
+		if err != nil {
+			err = pathErr("readdir", d.name, err)
+			goto end
+		}
+		d.read = true
+	}
+	rest = d.entries[d.offset:]
+	if count <= 0 {
+		entries = rest
+		d.offset = len(d.entries)
+		goto end
+	}
+	if len(rest) == 0 {
+		err = io.EOF
+		goto end
+	}
+	entries = rest[:min(count, len(rest))]
+	d.offset += len(entries)
+end:
+	return entries, err
+}
+
+func (d *dirHandle) Close() (err error) {
+	if d.closed {
+		err = pathErr("close", d.name, fs.ErrClosed)
+	}
+	d.closed = true
+	return err
+}
@@ -0,0 +1,652 @@
+// Package memfs provides an in-memory filesystem that implements fs.FS,
+// fs.ReadDirFS, fs.ReadFileFS, fs.StatFS, fs.ReadLinkFS and dt.WritableFS.
+//
+// It supports directories, regular files, symlinks, permission bits and
+// modification times, and can be configured to be case-insensitive. All
+// methods are safe for concurrent use, so it can stand in for the real disk in
+// parallel tests.
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+// ErrTooManySymlinks is returned when resolving a name follows more than
+// MaxSymlinks symlinks, which usually indicates a symlink loop.
+var ErrTooManySymlinks = errors.New("too many levels of symbolic links")
+
+// MaxSymlinks is the maximum number of symlinks followed when resolving a name.
+const MaxSymlinks = 40
+
+// Options configures a new FS.
+type Options struct {
+	// CaseInsensitive makes name lookups ignore case, as on the default
+	// filesystems of Windows and macOS. Names keep the case they were created
+	// with.
+	CaseInsensitive bool
+
+	// IgnorePermissions disables enforcement of the owner read and write
+	// permission bits.
+	IgnorePermissions bool
+
+	// Now returns the time used for modification times. Defaults to time.Now.
+	Now func() time.Time
+}
+
+var (
+	_ fs.FS         = (*FS)(nil)
+	_ fs.ReadDirFS  = (*FS)(nil)
+	_ fs.ReadFileFS = (*FS)(nil)
+	_ fs.StatFS     = (*FS)(nil)
+	_ fs.ReadLinkFS = (*FS)(nil)
+	_ dt.WritableFS = (*FS)(nil)
+)
+
+// FS is an in-memory filesystem. The zero value is not usable; call New.
+type FS struct {
+	mu   sync.RWMutex
+	root *node
+	opts Options
+}
+
+// New returns an empty FS whose root directory has mode 0755.
+func New(opts *Options) *FS {
+	if opts == nil {
+		opts = new(Options)
+	}
+	fsys := &FS{opts: *opts}
+	if fsys.opts.Now == nil {
+		fsys.opts.Now = time.Now
+	}
+	fsys.root = fsys.newNode(".", fs.ModeDir|0o755)
+	return fsys
+}
+
+// node is a single directory, file or symlink.
+type node struct {
+	name     string
+	mode     fs.FileMode
+	modTime  time.Time
+	data     []byte
+	target   string
+	children map[string]*node
+}
+
+func (n *node) isDir() bool     { return n.mode.IsDir() }
+func (n *node) isSymlink() bool { return n.mode&fs.ModeSymlink != 0 }
+
+func (n *node) info() fs.FileInfo {
+	size := int64(len(n.data))
+	if n.isSymlink() {
+		size = int64(len(n.target))
+	}
+	return fileInfo{
+		name:    n.name,
+		size:    size,
+		mode:    n.mode,
+		modTime: n.modTime,
+	}
+}
+
+func (fsys *FS) newNode(name string, mode fs.FileMode) *node {
+	n := &node{
+		name:    name,
+		mode:    mode,
+		modTime: fsys.opts.Now(),
+	}
+	if mode.IsDir() {
+		n.children = make(map[string]*node)
+	}
+	return n
+}
+
+// key returns the map key used for a name within a directory.
+func (fsys *FS) key(name string) string {
+	if fsys.opts.CaseInsensitive {
+		name = strings.ToLower(name)
+	}
+	return name
+}
+
+func (fsys *FS) canRead(n *node) bool {
+	return fsys.opts.IgnorePermissions || n.mode&0o400 != 0
+}
+
+func (fsys *FS) canWrite(n *node) bool {
+	return fsys.opts.IgnorePermissions || n.mode&0o200 != 0
+}
+
+func splitName(name string) []string {
+	if name == "." {
+		return nil
+	}
+	return strings.Split(name, "/")
+}
+
+// lookup resolves name, following symlinks in every component and, when
+// follow is true, in the final component as well. Callers must hold fsys.mu.
+func (fsys *FS) lookup(name string, follow bool) (*node, error) {
+	return fsys.lookupDepth(name, follow, 0)
+}
+
+func (fsys *FS) lookupDepth(name string, follow bool, depth int) (n *node, err error) {
+	var dir []string
+
+	comps := splitName(name)
+	n = fsys.root
+	for i, comp := range comps {
+		if !n.isDir() {
+			n, err = nil, syscall.ENOTDIR
+			goto end
+		}
+		child, ok := n.children[fsys.key(comp)]
+		if !ok {
+			n, err = nil, fs.ErrNotExist
+			goto end
+		}
+		last := i == len(comps)-1
+		if child.isSymlink() && (follow || !last) {
+			if depth >= MaxSymlinks {
+				n, err = nil, ErrTooManySymlinks
+				goto end
+			}
+			rest := append([]string{resolveTarget(dir, child.target)}, comps[i+1:]...)
+			n, err = fsys.lookupDepth(path.Join(rest...), follow, depth+1)
+			goto end
+		}
+		dir = append(dir, child.name)
+		n = child
+	}
+end:
+	return n, err
+}
+
+// resolveTarget returns the FS name that a symlink target refers to, given the
+// components of the directory containing the symlink. Absolute targets are
+// relative to the FS root, and ".." never climbs above the root.
+func resolveTarget(dir []string, target string) (name string) {
+	if strings.HasPrefix(target, "/") {
+		name = path.Clean(target[1:])
+	} else {
+		name = path.Join(append(slices.Clone(dir), target)...)
+	}
+	for name == ".." || strings.HasPrefix(name, "../") {
+		name = strings.TrimPrefix(strings.TrimPrefix(name, ".."), "/")
+	}
+	if name == "" || name == "/" {
+		name = "."
+	}
+	return name
+}
+
+// lookupParent resolves the directory that contains name and returns it with
+// the base name of name. Callers must hold fsys.mu.
+func (fsys *FS) lookupParent(name string) (dir *node, base string, err error) {
+	if name == "." {
+		err = fs.ErrInvalid
+		goto end
+	}
+	dir, err = fsys.lookup(path.Dir(name), true)
+	if err != nil {
+		goto end
+	}
+	if !dir.isDir() {
+		dir, err = nil, syscall.ENOTDIR
+		goto end
+	}
+	base = path.Base(name)
+end:
+	return dir, base, err
+}
+
+func pathErr(op, name string, err error) error {
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// Open opens name for reading.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	return fsys.open("open", name, 0, 0)
+}
+
+// OpenFile opens name with the given os.O_* flags, creating it with perm when
+// os.O_CREATE is given and it does not exist.
+func (fsys *FS) OpenFile(name string, flag int, perm fs.FileMode) (dt.WritableFile, error) {
+	return fsys.open("open", name, flag, perm)
+}
+
+// Stat returns the FileInfo for name, following symlinks.
+func (fsys *FS) Stat(name string) (info fs.FileInfo, err error) {
+	var n *node
+	if !fs.ValidPath(name) {
+		err = pathErr("stat", name, fs.ErrInvalid)
+		goto end
+	}
+	fsys.mu.RLock()
+	defer fsys.mu.RUnlock()
+	n, err = fsys.lookup(name, true)
+	if err != nil {
+		err = pathErr("stat", name, err)
+		goto end
+	}
+	info = n.info()
+end:
+	return info, err
+}
+
+// Lstat returns the FileInfo for name without following a final symlink.
+func (fsys *FS) Lstat(name string) (info fs.FileInfo, err error) {
+	var n *node
+	if !fs.ValidPath(name) {
+		err = pathErr("lstat", name, fs.ErrInvalid)
+		goto end
+	}
+	fsys.mu.RLock()
+	defer fsys.mu.RUnlock()
+	n, err = fsys.lookup(name, false)
+	if err != nil {
+		err = pathErr("lstat", name, err)
+		goto end
+	}
+	info = n.info()
+end:
+	return info, err
+}
+
+// ReadLink returns the target of the symlink name.
+func (fsys *FS) ReadLink(name string) (target string, err error) {
+	var n *node
+	if !fs.ValidPath(name) {
+		err = pathErr("readlink", name, fs.ErrInvalid)
+		goto end
+	}
+	fsys.mu.RLock()
+	defer fsys.mu.RUnlock()
+	n, err = fsys.lookup(name, false)
+	if err != nil {
+		err = pathErr("readlink", name, err)
+		goto end
+	}
+	if !n.isSymlink() {
+		err = pathErr("readlink", name, fs.ErrInvalid)
+		goto end
+	}
+	target = n.target
+end:
+	return target, err
+}
+
+// ReadDir returns the entries of directory name sorted by name.
+func (fsys *FS) ReadDir(name string) (entries []fs.DirEntry, err error) {
+	var n *node
+	if !fs.ValidPath(name) {
+		err = pathErr("readdir", name, fs.ErrInvalid)
+		goto end
+	}
+	fsys.mu.RLock()
+	defer fsys.mu.RUnlock()
+	n, err = fsys.lookup(name, true)
+	if err != nil {
+		err = pathErr("readdir", name, err)
+		goto end
+	}
+	entries, err = fsys.readDir(n)
+	if err != nil {
+		err = pathErr("readdir", name, err)
+	}
+end:
+	return entries, err
+}
+
+// readDir lists n. Callers must hold fsys.mu.
+func (fsys *FS) readDir(n *node) (entries []fs.DirEntry, err error) {
+	if !n.isDir() {
+		err = syscall.ENOTDIR
+		goto end
+	}
+	if !fsys.canRead(n) {
+		err = fs.ErrPermission
+		goto end
+	}
+	entries = make([]fs.DirEntry, 0, len(n.children))
+	for _, child := range n.children {
+		entries = append(entries, fs.FileInfoToDirEntry(child.info()))
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+end:
+	return entries, err
+}
+
+// ReadFile returns the contents of name.
+func (fsys *FS) ReadFile(name string) (data []byte, err error) {
+	var n *node
+	if !fs.ValidPath(name) {
+		err = pathErr("read", name, fs.ErrInvalid)
+		goto end
+	}
+	fsys.mu.RLock()
+	defer fsys.mu.RUnlock()
+	n, err = fsys.lookup(name, true)
+	if err != nil {
+		err = pathErr("read", name, err)
+		goto end
+	}
+	if n.isDir() {
+		err = pathErr("read", name, syscall.EISDIR)
+		goto end
+	}
+	if !fsys.canRead(n) {
+		err = pathErr("read", name, fs.ErrPermission)
+		goto end
+	}
+	data = slices.Clone(n.data)
+end:
+	return data, err
+}
+
+// WriteFile writes data to name, creating it with perm if needed.
+func (fsys *FS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return dt.WriteFileFS(fsys, name, data, perm)
+}
+
+// MkdirAll creates directory name and any missing parents.
+func (fsys *FS) MkdirAll(name string, perm fs.FileMode) error {
+	return dt.MkdirAllFS(fsys, name, perm)
+}
+
+// Mkdir creates the directory name.
+func (fsys *FS) Mkdir(name string, perm fs.FileMode) (err error) {
+	var dir *node
+	var base string
+
+	if !fs.ValidPath(name) {
+		err = fs.ErrInvalid
+		goto end
+	}
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	dir, base, err = fsys.lookupParent(name)
+	if err != nil {
+		goto end
+	}
+	if _, ok := dir.children[fsys.key(base)]; ok {
+		err = fs.ErrExist
+		goto end
+	}
+	if !fsys.canWrite(dir) {
+		err = fs.ErrPermission
+		goto end
+	}
+	dir.children[fsys.key(base)] = fsys.newNode(base, fs.ModeDir|perm.Perm())
+	dir.modTime = fsys.opts.Now()
+end:
+	if err != nil {
+		err = pathErr("mkdir", name, err)
+	}
+	return err
+}
+
+// Symlink creates newname as a symlink to oldname. Relative targets are
+// resolved from the directory containing newname; absolute targets are
+// resolved from the root of the FS.
+func (fsys *FS) Symlink(oldname, newname string) (err error) {
+	var dir *node
+	var base string
+	var link *node
+
+	if !fs.ValidPath(newname) {
+		err = fs.ErrInvalid
+		goto end
+	}
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	dir, base, err = fsys.lookupParent(newname)
+	if err != nil {
+		goto end
+	}
+	if _, ok := dir.children[fsys.key(base)]; ok {
+		err = fs.ErrExist
+		goto end
+	}
+	if !fsys.canWrite(dir) {
+		err = fs.ErrPermission
+		goto end
+	}
+	link = fsys.newNode(base, fs.ModeSymlink|0o777)
+	link.target = oldname
+	dir.children[fsys.key(base)] = link
+	dir.modTime = fsys.opts.Now()
+end:
+	if err != nil {
+		err = pathErr("symlink", newname, err)
+	}
+	return err
+}
+
+// Remove removes the file, symlink or empty directory name.
+func (fsys *FS) Remove(name string) (err error) {
+	var dir *node
+	var base string
+	var child *node
+	var ok bool
+
+	if !fs.ValidPath(name) {
+		err = fs.ErrInvalid
+		goto end
+	}
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	dir, base, err = fsys.lookupParent(name)
+	if err != nil {
+		goto end
+	}
+	child, ok = dir.children[fsys.key(base)]
+	if !ok {
+		err = fs.ErrNotExist
+		goto end
+	}
+	if child.isDir() && len(child.children) > 0 {
+		err = syscall.ENOTEMPTY
+		goto end
+	}
+	if !fsys.canWrite(dir) {
+		err = fs.ErrPermission
+		goto end
+	}
+	delete(dir.children, fsys.key(base))
+	dir.modTime = fsys.opts.Now()
+end:
+	if err != nil {
+		err = pathErr("remove", name, err)
+	}
+	return err
+}
+
+// RemoveAll removes name and everything it contains. It returns nil if name
+// does not exist.
+func (fsys *FS) RemoveAll(name string) (err error) {
+	var dir *node
+	var base string
+
+	if !fs.ValidPath(name) || name == "." {
+		err = fs.ErrInvalid
+		goto end
+	}
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	dir, base, err = fsys.lookupParent(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		err = nil
+		goto end
+	}
+	if err != nil {
+		goto end
+	}
+	if _, ok := dir.children[fsys.key(base)]; !ok {
+		goto end
+	}
+	if !fsys.canWrite(dir) {
+		err = fs.ErrPermission
+		goto end
+	}
+	delete(dir.children, fsys.key(base))
+	dir.modTime = fsys.opts.Now()
+end:
+	if err != nil {
+		err = pathErr("removeall", name, err)
+	}
+	return err
+}
+
+// Rename moves oldname to newname, replacing newname if it is a file or an
+// empty directory.
+func (fsys *FS) Rename(oldname, newname string) (err error) {
+	var oldDir, newDir *node
+	var oldBase, newBase string
+	var src, dst *node
+	var ok bool
+
+	if !fs.ValidPath(oldname) || !fs.ValidPath(newname) {
+		err = fs.ErrInvalid
+		goto end
+	}
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	oldDir, oldBase, err = fsys.lookupParent(oldname)
+	if err != nil {
+		goto end
+	}
+	src, ok = oldDir.children[fsys.key(oldBase)]
+	if !ok {
+		err = fs.ErrNotExist
+		goto end
+	}
+	newDir, newBase, err = fsys.lookupParent(newname)
+	if err != nil {
+		goto end
+	}
+	if !fsys.canWrite(oldDir) || !fsys.canWrite(newDir) {
+		err = fs.ErrPermission
+		goto end
+	}
+	if src.isDir() && containsNode(src, newDir) {
+		// Cannot move a directory inside itself.
+		err = fs.ErrInvalid
+		goto end
+	}
+	dst, ok = newDir.children[fsys.key(newBase)]
+	if ok && dst != src {
+		switch {
+		case dst.isDir() && !src.isDir():
+			err = syscall.EISDIR
+		case !dst.isDir() && src.isDir():
+			err = syscall.ENOTDIR
+		case dst.isDir() && len(dst.children) > 0:
+			err = syscall.ENOTEMPTY
+		}
+		if err != nil {
+			goto end
+		}
+	}
+	delete(oldDir.children, fsys.key(oldBase))
+	src.name = newBase
+	newDir.children[fsys.key(newBase)] = src
+	oldDir.modTime = fsys.opts.Now()
+	newDir.modTime = oldDir.modTime
+end:
+	if err != nil {
+		err = &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+	return err
+}
+
+// containsNode reports whether n is dir or is found beneath dir.
+func containsNode(dir, n *node) bool {
+	if dir == n {
+		return true
+	}
+	for _, child := range dir.children {
+		if child.isDir() && containsNode(child, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// Chmod sets the permission bits of name, following symlinks.
+func (fsys *FS) Chmod(name string, mode fs.FileMode) (err error) {
+	var n *node
+
+	if !fs.ValidPath(name) {
+		err = fs.ErrInvalid
+		goto end
+	}
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	n, err = fsys.lookup(name, true)
+	if err != nil {
+		goto end
+	}
+	n.mode = n.mode.Type() | mode.Perm()
+end:
+	if err != nil {
+		err = pathErr("chmod", name, err)
+	}
+	return err
+}
+
+// Chtimes sets the modification time of name, following symlinks. Access
+// times are not tracked.
+func (fsys *FS) Chtimes(name string, _ time.Time, mtime time.Time) (err error) {
+	var n *node
+
+	if !fs.ValidPath(name) {
+		err = fs.ErrInvalid
+		goto end
+	}
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	n, err = fsys.lookup(name, true)
+	if err != nil {
+		goto end
+	}
+	n.modTime = mtime
+end:
+	if err != nil {
+		err = pathErr("chtimes", name, err)
+	}
+	return err
+}
+
+// fileInfo implements fs.FileInfo for a node snapshot.
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi fileInfo) Sys() any           { return nil }
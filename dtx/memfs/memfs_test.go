@@ -0,0 +1,311 @@
+package memfs_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/mikeschinkel/go-dt"
+	"github.com/mikeschinkel/go-dt/dtx/memfs"
+)
+
+func newTestFS(t *testing.T, opts *memfs.Options) *memfs.FS {
+	t.Helper()
+	fsys := memfs.New(opts)
+	for name, content := range map[string]string{
+		"a.txt":         "alpha",
+		"dir/b.txt":     "bravo",
+		"dir/sub/c.txt": "charlie",
+		"other/d.md":    "delta",
+	} {
+		if err := fsys.MkdirAll(path.Dir(name), 0o755); err != nil {
+			t.Fatalf("MkdirAll(%s) error = %v", name, err)
+		}
+		if err := fsys.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile(%s) error = %v", name, err)
+		}
+	}
+	return fsys
+}
+
+func TestFS_FSTest(t *testing.T) {
+	fsys := newTestFS(t, nil)
+	if err := fsys.Symlink("dir/b.txt", "link.txt"); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+	err := fstest.TestFS(fsys, "a.txt", "dir/b.txt", "dir/sub/c.txt", "other/d.md", "link.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFS_WalkFS(t *testing.T) {
+	fsys := newTestFS(t, nil)
+	var got []string
+	for de, err := range dt.DirPath(".").WalkFS(fsys) {
+		if err != nil {
+			t.Fatalf("WalkFS() error = %v", err)
+		}
+		got = append(got, string(de.Rel))
+	}
+	want := []string{"a.txt", "dir", "dir/b.txt", "dir/sub", "dir/sub/c.txt", "other", "other/d.md"}
+	if !slices.Equal(got, want) {
+		t.Errorf("WalkFS() = %v, want %v", got, want)
+	}
+
+	got = nil
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		got = append(got, name)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WalkDir() error = %v", err)
+	}
+	if !slices.Equal(got, append([]string{"."}, want...)) {
+		t.Errorf("WalkDir() = %v, want %v", got, want)
+	}
+
+	matches, err := fs.Glob(fsys, "dir/*/*.txt")
+	if err != nil || !slices.Equal(matches, []string{"dir/sub/c.txt"}) {
+		t.Errorf("Glob() = %v, %v; want [dir/sub/c.txt]", matches, err)
+	}
+}
+
+func TestFS_OpenFile(t *testing.T) {
+	fsys := newTestFS(t, nil)
+
+	f, err := fsys.OpenFile("a.txt", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	if _, err = io.WriteString(f, "-more"); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	got, _ := fsys.ReadFile("a.txt")
+	if string(got) != "alpha-more" {
+		t.Errorf("ReadFile() = %q, want %q", got, "alpha-more")
+	}
+
+	_, err = fsys.OpenFile("a.txt", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if !errors.Is(err, fs.ErrExist) {
+		t.Errorf("OpenFile(O_EXCL) error = %v, want %v", err, fs.ErrExist)
+	}
+	_, err = fsys.OpenFile("dir", os.O_WRONLY, 0)
+	if err == nil {
+		t.Error("OpenFile(dir, O_WRONLY) error = nil, want error")
+	}
+	_, err = fsys.OpenFile("missing/new.txt", os.O_WRONLY|os.O_CREATE, 0o644)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("OpenFile() in missing dir error = %v, want %v", err, fs.ErrNotExist)
+	}
+
+	rf, err := fsys.Open("a.txt")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer rf.Close()
+	if _, err = rf.(io.Writer).Write([]byte("x")); err == nil {
+		t.Error("Write() on read-only handle error = nil, want error")
+	}
+}
+
+func TestFS_Symlinks(t *testing.T) {
+	fsys := newTestFS(t, nil)
+	if err := fsys.Symlink("sub", "dir/sublink"); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+	if err := fsys.Symlink("/other", "dir/abs"); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+	got, err := fsys.ReadFile("dir/sublink/c.txt")
+	if err != nil || string(got) != "charlie" {
+		t.Errorf("ReadFile(through relative link) = %q, %v; want %q", got, err, "charlie")
+	}
+	got, err = fsys.ReadFile("dir/abs/d.md")
+	if err != nil || string(got) != "delta" {
+		t.Errorf("ReadFile(through absolute link) = %q, %v; want %q", got, err, "delta")
+	}
+	target, err := fsys.ReadLink("dir/sublink")
+	if err != nil || target != "sub" {
+		t.Errorf("ReadLink() = %q, %v; want %q", target, err, "sub")
+	}
+	info, err := fsys.Lstat("dir/sublink")
+	if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("Lstat() = %v, %v; want symlink", info, err)
+	}
+
+	if err = fsys.Symlink("loop2", "loop1"); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+	if err = fsys.Symlink("loop1", "loop2"); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+	_, err = fsys.Stat("loop1")
+	if !errors.Is(err, memfs.ErrTooManySymlinks) {
+		t.Errorf("Stat(loop) error = %v, want %v", err, memfs.ErrTooManySymlinks)
+	}
+}
+
+func TestFS_Permissions(t *testing.T) {
+	fsys := newTestFS(t, nil)
+	if err := fsys.Chmod("a.txt", 0o200); err != nil {
+		t.Fatalf("Chmod() error = %v", err)
+	}
+	_, err := fsys.ReadFile("a.txt")
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("ReadFile() of write-only file error = %v, want %v", err, fs.ErrPermission)
+	}
+	if err = fsys.Chmod("dir", 0o555); err != nil {
+		t.Fatalf("Chmod() error = %v", err)
+	}
+	err = fsys.WriteFile("dir/new.txt", nil, 0o644)
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("WriteFile() in read-only dir error = %v, want %v", err, fs.ErrPermission)
+	}
+	err = fsys.Remove("dir/b.txt")
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Remove() in read-only dir error = %v, want %v", err, fs.ErrPermission)
+	}
+
+	fsys = newTestFS(t, &memfs.Options{IgnorePermissions: true})
+	if err = fsys.Chmod("a.txt", 0); err != nil {
+		t.Fatalf("Chmod() error = %v", err)
+	}
+	if _, err = fsys.ReadFile("a.txt"); err != nil {
+		t.Errorf("ReadFile() with IgnorePermissions error = %v", err)
+	}
+}
+
+func TestFS_ModTime(t *testing.T) {
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	fsys := memfs.New(&memfs.Options{Now: func() time.Time { return now }})
+	if err := fsys.WriteFile("f.txt", []byte("x"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	info, err := fsys.Stat("f.txt")
+	if err != nil || !info.ModTime().Equal(now) {
+		t.Errorf("Stat().ModTime() = %v, %v; want %v", info.ModTime(), err, now)
+	}
+	mtime := now.Add(-time.Hour)
+	if err = fsys.Chtimes("f.txt", mtime, mtime); err != nil {
+		t.Fatalf("Chtimes() error = %v", err)
+	}
+	info, _ = fsys.Stat("f.txt")
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("ModTime() after Chtimes = %v, want %v", info.ModTime(), mtime)
+	}
+}
+
+func TestFS_CaseInsensitive(t *testing.T) {
+	fsys := newTestFS(t, &memfs.Options{CaseInsensitive: true})
+	got, err := fsys.ReadFile("DIR/B.TXT")
+	if err != nil || string(got) != "bravo" {
+		t.Errorf("ReadFile() = %q, %v; want %q", got, err, "bravo")
+	}
+	err = fsys.Mkdir("Dir", 0o755)
+	if !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Mkdir() of differently cased name error = %v, want %v", err, fs.ErrExist)
+	}
+	info, _ := fsys.Stat("DIR")
+	if info.Name() != "dir" {
+		t.Errorf("Stat().Name() = %q, want original case %q", info.Name(), "dir")
+	}
+
+	fsys = newTestFS(t, nil)
+	if _, err = fsys.Stat("DIR/B.TXT"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat() on case-sensitive FS error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestFS_RenameAndRemove(t *testing.T) {
+	fsys := newTestFS(t, nil)
+	if err := fsys.Rename("dir", "moved"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if _, err := fsys.Stat("moved/sub/c.txt"); err != nil {
+		t.Errorf("Stat() after Rename error = %v", err)
+	}
+	if err := fsys.Rename("moved", "moved/sub/inner"); err == nil {
+		t.Error("Rename() into itself error = nil, want error")
+	}
+	if err := fsys.Remove("moved"); err == nil {
+		t.Error("Remove() of non-empty dir error = nil, want error")
+	}
+	if err := fsys.RemoveAll("moved"); err != nil {
+		t.Fatalf("RemoveAll() error = %v", err)
+	}
+	if _, err := fsys.Stat("moved"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat() after RemoveAll error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestFS_DtMethods(t *testing.T) {
+	if err := dt.EnsureUserHomeDir(); err != nil {
+		t.Fatalf("EnsureUserHomeDir() error = %v", err)
+	}
+	fsys := memfs.New(nil)
+	if err := dt.DirPath("x/y").EnsureExistsFS(fsys, 0o755); err != nil {
+		t.Fatalf("EnsureExistsFS() error = %v", err)
+	}
+	fp := dt.Filepath("x/y/file.txt")
+	if err := fp.WriteFileFS(fsys, []byte("data"), 0o644); err != nil {
+		t.Fatalf("WriteFileFS() error = %v", err)
+	}
+	dest := dt.Filepath("x/copy.txt")
+	if err := fp.CopyToFS(fsys, dest, nil); err != nil {
+		t.Fatalf("CopyToFS() error = %v", err)
+	}
+	got, err := dest.ReadFile(fsys)
+	if err != nil || string(got) != "data" {
+		t.Errorf("ReadFile() = %q, %v; want %q", got, err, "data")
+	}
+}
+
+func TestFS_Concurrent(t *testing.T) {
+	fsys := memfs.New(nil)
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dir := fmt.Sprintf("d%d", i%2)
+			for j := range 50 {
+				name := fmt.Sprintf("%s/f%d-%d.txt", dir, i, j)
+				if err := fsys.MkdirAll(dir, 0o755); err != nil {
+					t.Errorf("MkdirAll() error = %v", err)
+					return
+				}
+				if err := fsys.WriteFile(name, []byte(name), 0o644); err != nil {
+					t.Errorf("WriteFile() error = %v", err)
+					return
+				}
+				if _, err := fsys.ReadDir(dir); err != nil {
+					t.Errorf("ReadDir() error = %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	n := 0
+	for _, err := range dt.DirPath(".").WalkFilesFS(fsys) {
+		if err != nil {
+			t.Fatalf("WalkFilesFS() error = %v", err)
+		}
+		n++
+	}
+	if n != 400 {
+		t.Errorf("WalkFilesFS() found %d files, want 400", n)
+	}
+}
@@ -0,0 +1,135 @@
+package overlayfs
+
+import (
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+// Commit flushes the overlay's changes to dest on disk: whiteouts and
+// recreated directories are removed from dest and then everything in the
+// upper layer is written over it, preserving modes and modification times.
+// Directories get theirs last, after their contents are written.
+//
+// Typically dest is the directory behind one of the lower layers, so that
+// after Commit that layer alone reflects what the overlay showed. dest must
+// not be the directory behind the upper layer itself. Commit does not reset
+// the upper layer or the whiteouts.
+func (ofs *FS) Commit(dest dt.DirPath) (err error) {
+	var names []string
+	var dirs []string
+
+	ofs.mu.RLock()
+	defer ofs.mu.RUnlock()
+
+	names = slices.Concat(
+		slices.Collect(maps.Keys(ofs.whiteouts)),
+		slices.Collect(maps.Keys(ofs.opaque)),
+	)
+	slices.Sort(names)
+	for _, name := range names {
+		err = dt.DirPathJoin(dest, filepath.FromSlash(name)).RemoveAll()
+		if err != nil {
+			err = dt.NewErr(ErrFailedToCommitOverlay, "name", name, dest.ErrKV(), err)
+			goto end
+		}
+	}
+
+	err = fs.WalkDir(ofs.upper, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		err = commitEntry(ofs.upper, dest, name, d)
+		if err != nil {
+			err = dt.NewErr(ErrFailedToCommitOverlay, "name", name, dest.ErrKV(), err)
+		}
+		if d.IsDir() && name != "." {
+			dirs = append(dirs, name)
+		}
+		return err
+	})
+	if err != nil {
+		goto end
+	}
+
+	// Deepest first, so a parent's mode cannot block reaching its children
+	slices.Reverse(dirs)
+	for _, name := range dirs {
+		err = commitDirMeta(ofs.upper, dest, name)
+		if err != nil {
+			err = dt.NewErr(ErrFailedToCommitOverlay, "name", name, dest.ErrKV(), err)
+			goto end
+		}
+	}
+end:
+	return err
+}
+
+// commitEntry writes the single upper-layer entry name to dest.
+func commitEntry(upper fs.FS, dest dt.DirPath, name string, d fs.DirEntry) (err error) {
+	var info fs.FileInfo
+	var target string
+	var data []byte
+
+	ep := dt.EntryPath(filepath.Join(string(dest), filepath.FromSlash(name)))
+	info, err = d.Info()
+	if err != nil {
+		goto end
+	}
+	switch {
+	case d.IsDir():
+		// Writable until commitDirMeta applies the mode, after the contents
+		err = dt.DirPath(ep).MkdirAll(info.Mode().Perm() | 0o700)
+		goto end
+	case d.Type()&fs.ModeSymlink != 0:
+		target, err = fs.ReadLink(upper, name)
+		if err != nil {
+			goto end
+		}
+		err = os.Remove(string(ep))
+		if err != nil && !os.IsNotExist(err) {
+			goto end
+		}
+		err = os.Symlink(filepath.FromSlash(target), string(ep))
+		goto end
+	}
+	data, err = fs.ReadFile(upper, name)
+	if err != nil {
+		goto end
+	}
+	err = dt.Filepath(ep).WriteFile(data, info.Mode().Perm())
+	if err != nil {
+		goto end
+	}
+	// WriteFile does not change the mode of an existing file.
+	err = os.Chmod(string(ep), info.Mode().Perm())
+	if err != nil {
+		goto end
+	}
+	err = os.Chtimes(string(ep), info.ModTime(), info.ModTime())
+end:
+	return err
+}
+
+// commitDirMeta gives the directory name in dest the mode and modification
+// time it has in the upper layer.
+func commitDirMeta(upper fs.FS, dest dt.DirPath, name string) (err error) {
+	var info fs.FileInfo
+
+	ep := dt.EntryPath(filepath.Join(string(dest), filepath.FromSlash(name)))
+	info, err = fs.Stat(upper, name)
+	if err != nil {
+		goto end
+	}
+	err = os.Chmod(string(ep), info.Mode().Perm())
+	if err != nil {
+		goto end
+	}
+	err = os.Chtimes(string(ep), info.ModTime(), info.ModTime())
+end:
+	return err
+}
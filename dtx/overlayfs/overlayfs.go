@@ -0,0 +1,664 @@
+// Package overlayfs stacks a writable upper layer over one or more read-only
+// fs.FS lower layers and presents them as a single dt.WritableFS.
+//
+// Reads are served by the highest layer that has a name, and directories are
+// merged across layers. Writes copy the affected entry up into the upper layer
+// first, and deletes of entries provided by a lower layer are recorded as
+// whiteouts so the lower entry stays hidden. Which reports the layer a name is
+// served from and Commit flushes the upper layer and whiteouts to a directory
+// on disk.
+package overlayfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+var ErrFailedToCommitOverlay = errors.New("failed to commit overlay")
+
+// UpperLayer is the layer name that Which reports for entries served from the
+// writable upper layer.
+const UpperLayer = "upper"
+
+// Layer is a named read-only lower layer, e.g. an embed.FS of defaults or the
+// DirFS of a user's config directory. The name is what Which reports.
+type Layer struct {
+	Name string
+	FS   fs.FS
+}
+
+var (
+	_ fs.FS         = (*FS)(nil)
+	_ fs.ReadDirFS  = (*FS)(nil)
+	_ fs.ReadFileFS = (*FS)(nil)
+	_ fs.StatFS     = (*FS)(nil)
+	_ fs.ReadLinkFS = (*FS)(nil)
+	_ dt.WritableFS = (*FS)(nil)
+)
+
+// FS is an overlay filesystem. It is safe for concurrent use if its layers are.
+type FS struct {
+	mu sync.RWMutex
+
+	// layers holds the upper layer at index 0 followed by the lower layers in
+	// priority order.
+	layers []Layer
+	upper  dt.WritableFS
+
+	// whiteouts are names deleted from the overlay that still exist in a
+	// lower layer.
+	whiteouts map[string]struct{}
+
+	// opaque are directories recreated in the upper layer after being deleted;
+	// lower layers contribute nothing beneath them.
+	opaque map[string]struct{}
+}
+
+// New returns an overlay of upper over lowers. Lower layers are consulted in
+// the order given, so earlier layers take priority over later ones.
+func New(upper dt.WritableFS, lowers ...Layer) *FS {
+	layers := make([]Layer, 0, len(lowers)+1)
+	layers = append(layers, Layer{Name: UpperLayer, FS: upper})
+	layers = append(layers, lowers...)
+	return &FS{
+		layers:    layers,
+		upper:     upper,
+		whiteouts: make(map[string]struct{}),
+		opaque:    make(map[string]struct{}),
+	}
+}
+
+func pathErr(op, name string, err error) error {
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// notFound reports whether err means a layer does not have a name.
+func notFound(err error) bool {
+	return errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR)
+}
+
+// hidden reports whether lower layers are hidden for name because it or one
+// of its parents was deleted or recreated in the upper layer. Callers must
+// hold ofs.mu.
+func (ofs *FS) hidden(name string) (hidden bool) {
+	for {
+		if _, ok := ofs.whiteouts[name]; ok {
+			hidden = true
+			goto end
+		}
+		if _, ok := ofs.opaque[name]; ok {
+			hidden = true
+			goto end
+		}
+		if name == "." {
+			goto end
+		}
+		name = path.Dir(name)
+	}
+end:
+	return hidden
+}
+
+// find returns the index of the layer that serves name along with its
+// FileInfo. Callers must hold ofs.mu.
+func (ofs *FS) find(op, name string, lstat bool) (i int, info fs.FileInfo, err error) {
+	if !fs.ValidPath(name) {
+		err = fs.ErrInvalid
+		goto end
+	}
+	for i = range ofs.layers {
+		if i > 0 && ofs.hidden(name) {
+			break
+		}
+		if lstat {
+			info, err = fs.Lstat(ofs.layers[i].FS, name)
+		} else {
+			info, err = fs.Stat(ofs.layers[i].FS, name)
+		}
+		if err == nil || !notFound(err) {
+			goto end
+		}
+	}
+	i, err = -1, fs.ErrNotExist
+end:
+	if err != nil {
+		err = pathErr(op, name, err)
+	}
+	return i, info, err
+}
+
+// inLower reports whether a visible lower layer has name. Callers must hold
+// ofs.mu.
+func (ofs *FS) inLower(name string) (found bool) {
+	if ofs.hidden(name) {
+		goto end
+	}
+	for _, l := range ofs.layers[1:] {
+		_, err := fs.Lstat(l.FS, name)
+		if err == nil {
+			found = true
+			goto end
+		}
+	}
+end:
+	return found
+}
+
+// Which returns the name of the layer that name is served from, UpperLayer or
+// the Name of a lower Layer.
+func (ofs *FS) Which(name string) (layer string, err error) {
+	var i int
+
+	ofs.mu.RLock()
+	defer ofs.mu.RUnlock()
+	i, _, err = ofs.find("which", name, true)
+	if err != nil {
+		goto end
+	}
+	layer = ofs.layers[i].Name
+end:
+	return layer, err
+}
+
+// Open opens name from the layer that serves it. Directories list the merged
+// entries of all layers.
+func (ofs *FS) Open(name string) (f fs.File, err error) {
+	var i int
+	var info fs.FileInfo
+	var entries []fs.DirEntry
+
+	ofs.mu.RLock()
+	defer ofs.mu.RUnlock()
+
+	i, info, err = ofs.find("open", name, false)
+	if err != nil {
+		goto end
+	}
+	if !info.IsDir() {
+		f, err = ofs.layers[i].FS.Open(name)
+		goto end
+	}
+	entries, err = ofs.readDir(name)
+	if err != nil {
+		err = pathErr("open", name, err)
+		goto end
+	}
+	f = &dirFile{name: name, info: info, entries: entries}
+end:
+	return f, err
+}
+
+// Stat returns the FileInfo for name, following symlinks.
+func (ofs *FS) Stat(name string) (info fs.FileInfo, err error) {
+	ofs.mu.RLock()
+	defer ofs.mu.RUnlock()
+	_, info, err = ofs.find("stat", name, false)
+	return info, err
+}
+
+// Lstat returns the FileInfo for name without following a final symlink.
+func (ofs *FS) Lstat(name string) (info fs.FileInfo, err error) {
+	ofs.mu.RLock()
+	defer ofs.mu.RUnlock()
+	_, info, err = ofs.find("lstat", name, true)
+	return info, err
+}
+
+// ReadLink returns the target of the symlink name.
+func (ofs *FS) ReadLink(name string) (target string, err error) {
+	var i int
+
+	ofs.mu.RLock()
+	defer ofs.mu.RUnlock()
+	i, _, err = ofs.find("readlink", name, true)
+	if err != nil {
+		goto end
+	}
+	target, err = fs.ReadLink(ofs.layers[i].FS, name)
+end:
+	return target, err
+}
+
+// ReadFile returns the contents of name from the layer that serves it.
+func (ofs *FS) ReadFile(name string) (data []byte, err error) {
+	var i int
+
+	ofs.mu.RLock()
+	defer ofs.mu.RUnlock()
+	i, _, err = ofs.find("read", name, false)
+	if err != nil {
+		goto end
+	}
+	data, err = fs.ReadFile(ofs.layers[i].FS, name)
+end:
+	return data, err
+}
+
+// ReadDir returns the merged entries of directory name sorted by name. An
+// entry in a higher layer shadows an entry of the same name in lower layers.
+func (ofs *FS) ReadDir(name string) (entries []fs.DirEntry, err error) {
+	if !fs.ValidPath(name) {
+		err = pathErr("readdir", name, fs.ErrInvalid)
+		goto end
+	}
+	ofs.mu.RLock()
+	defer ofs.mu.RUnlock()
+	entries, err = ofs.readDir(name)
+	if err != nil {
+		err = pathErr("readdir", name, err)
+	}
+end:
+	return entries, err
+}
+
+// readDir merges the entries of name across layers. Callers must hold ofs.mu.
+func (ofs *FS) readDir(name string) (entries []fs.DirEntry, err error) {
+	var found bool
+	var info fs.FileInfo
+	var ents []fs.DirEntry
+
+	seen := make(map[string]struct{})
+	for i, l := range ofs.layers {
+		if i > 0 && ofs.hidden(name) {
+			break
+		}
+		info, err = fs.Stat(l.FS, name)
+		if notFound(err) {
+			err = nil
+			continue
+		}
+		if err != nil {
+			goto end
+		}
+		if !info.IsDir() {
+			// A non-directory shadows the directories of lower layers.
+			if !found {
+				err = syscall.ENOTDIR
+				goto end
+			}
+			break
+		}
+		found = true
+		ents, err = fs.ReadDir(l.FS, name)
+		if err != nil {
+			goto end
+		}
+		for _, e := range ents {
+			if _, ok := seen[e.Name()]; ok {
+				continue
+			}
+			seen[e.Name()] = struct{}{}
+			if i > 0 && ofs.hidden(path.Join(name, e.Name())) {
+				continue
+			}
+			entries = append(entries, e)
+		}
+	}
+	if !found {
+		err = fs.ErrNotExist
+		goto end
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+end:
+	if err != nil {
+		entries = nil
+	}
+	return entries, err
+}
+
+// copyUp makes sure name exists in the upper layer by copying it, and any
+// missing parents, from the lower layer that serves it. Copied entries gain
+// owner write permission so that they can be modified. Callers must hold
+// ofs.mu for writing.
+func (ofs *FS) copyUp(op, name string) (err error) {
+	var i int
+	var info fs.FileInfo
+	var lower fs.FS
+	var target string
+	var data []byte
+
+	if name == "." {
+		goto end
+	}
+	i, info, err = ofs.find(op, name, true)
+	if err != nil || i == 0 {
+		goto end
+	}
+	err = ofs.copyUp(op, path.Dir(name))
+	if err != nil {
+		goto end
+	}
+	lower = ofs.layers[i].FS
+	switch {
+	case info.IsDir():
+		err = ofs.upper.Mkdir(name, info.Mode().Perm()|0o700)
+	case info.Mode()&fs.ModeSymlink != 0:
+		target, err = fs.ReadLink(lower, name)
+		if err != nil {
+			goto end
+		}
+		err = ofs.upper.Symlink(target, name)
+		goto end
+	default:
+		data, err = fs.ReadFile(lower, name)
+		if err != nil {
+			goto end
+		}
+		err = dt.WriteFileFS(ofs.upper, name, data, info.Mode().Perm()|0o200)
+	}
+	if err != nil {
+		goto end
+	}
+	err = ofs.upper.Chtimes(name, info.ModTime(), info.ModTime())
+end:
+	return err
+}
+
+// copyUpTree copies name and, for a directory, everything visible beneath it
+// into the upper layer. Callers must hold ofs.mu for writing.
+func (ofs *FS) copyUpTree(op, name string) (err error) {
+	var info fs.FileInfo
+	var entries []fs.DirEntry
+
+	err = ofs.copyUp(op, name)
+	if err != nil {
+		goto end
+	}
+	info, err = fs.Lstat(ofs.upper, name)
+	if err != nil || !info.IsDir() {
+		goto end
+	}
+	entries, err = ofs.readDir(name)
+	if err != nil {
+		goto end
+	}
+	for _, e := range entries {
+		err = ofs.copyUpTree(op, path.Join(name, e.Name()))
+		if err != nil {
+			goto end
+		}
+	}
+end:
+	return err
+}
+
+// created records that name now exists in the upper layer. Callers must hold
+// ofs.mu for writing.
+func (ofs *FS) created(name string, isDir bool) {
+	if _, ok := ofs.whiteouts[name]; !ok {
+		return
+	}
+	delete(ofs.whiteouts, name)
+	if isDir {
+		ofs.opaque[name] = struct{}{}
+	}
+}
+
+// OpenFile opens name with the given os.O_* flags. Opening for writing copies
+// the file up into the upper layer first.
+func (ofs *FS) OpenFile(name string, flag int, perm fs.FileMode) (wf dt.WritableFile, err error) {
+	var f fs.File
+
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) == 0 {
+		f, err = ofs.Open(name)
+		if err != nil {
+			goto end
+		}
+		wf = readOnlyFile{File: f, name: name}
+		goto end
+	}
+
+	ofs.mu.Lock()
+	defer ofs.mu.Unlock()
+
+	_, _, err = ofs.find("open", name, false)
+	switch {
+	case err == nil && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		err = pathErr("open", name, fs.ErrExist)
+		goto end
+	case err == nil:
+		err = ofs.copyUp("open", name)
+	case errors.Is(err, fs.ErrNotExist) && flag&os.O_CREATE != 0:
+		err = ofs.copyUp("open", path.Dir(name))
+		if err != nil {
+			goto end
+		}
+		ofs.created(name, false)
+	}
+	if err != nil {
+		goto end
+	}
+	wf, err = ofs.upper.OpenFile(name, flag, perm)
+end:
+	return wf, err
+}
+
+// Mkdir creates the directory name in the upper layer.
+func (ofs *FS) Mkdir(name string, perm fs.FileMode) (err error) {
+	ofs.mu.Lock()
+	defer ofs.mu.Unlock()
+
+	_, _, err = ofs.find("mkdir", name, true)
+	if err == nil {
+		err = pathErr("mkdir", name, fs.ErrExist)
+		goto end
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		goto end
+	}
+	err = ofs.copyUp("mkdir", path.Dir(name))
+	if err != nil {
+		goto end
+	}
+	err = ofs.upper.Mkdir(name, perm)
+	if err != nil {
+		goto end
+	}
+	ofs.created(name, true)
+end:
+	return err
+}
+
+// Symlink creates newname as a symlink to oldname in the upper layer.
+func (ofs *FS) Symlink(oldname, newname string) (err error) {
+	ofs.mu.Lock()
+	defer ofs.mu.Unlock()
+
+	_, _, err = ofs.find("symlink", newname, true)
+	if err == nil {
+		err = pathErr("symlink", newname, fs.ErrExist)
+		goto end
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		goto end
+	}
+	err = ofs.copyUp("symlink", path.Dir(newname))
+	if err != nil {
+		goto end
+	}
+	err = ofs.upper.Symlink(oldname, newname)
+	if err != nil {
+		goto end
+	}
+	ofs.created(newname, false)
+end:
+	return err
+}
+
+// Remove removes the file, symlink or empty directory name. If a lower layer
+// provides name it is hidden with a whiteout.
+func (ofs *FS) Remove(name string) (err error) {
+	var i int
+	var info fs.FileInfo
+	var entries []fs.DirEntry
+
+	ofs.mu.Lock()
+	defer ofs.mu.Unlock()
+
+	i, info, err = ofs.find("remove", name, true)
+	if err != nil {
+		goto end
+	}
+	if name == "." {
+		err = pathErr("remove", name, fs.ErrInvalid)
+		goto end
+	}
+	if info.IsDir() {
+		entries, err = ofs.readDir(name)
+		if err != nil {
+			err = pathErr("remove", name, err)
+			goto end
+		}
+		if len(entries) > 0 {
+			err = pathErr("remove", name, syscall.ENOTEMPTY)
+			goto end
+		}
+	}
+	if i == 0 {
+		err = ofs.upper.Remove(name)
+		if err != nil {
+			goto end
+		}
+	}
+	if ofs.inLower(name) {
+		ofs.whiteouts[name] = struct{}{}
+	}
+	delete(ofs.opaque, name)
+end:
+	return err
+}
+
+// Rename moves oldname to newname. The entry, and for a directory everything
+// beneath it, is copied up first and oldname is hidden with a whiteout if a
+// lower layer provides it.
+func (ofs *FS) Rename(oldname, newname string) (err error) {
+	var info fs.FileInfo
+	var entries []fs.DirEntry
+
+	ofs.mu.Lock()
+	defer ofs.mu.Unlock()
+
+	_, info, err = ofs.find("rename", oldname, true)
+	if err != nil {
+		goto end
+	}
+	entries, err = ofs.readDir(newname)
+	if err == nil && len(entries) > 0 {
+		err = pathErr("rename", newname, syscall.ENOTEMPTY)
+		goto end
+	}
+	err = ofs.copyUpTree("rename", oldname)
+	if err != nil {
+		goto end
+	}
+	err = ofs.copyUp("rename", path.Dir(newname))
+	if err != nil {
+		goto end
+	}
+	err = ofs.upper.Rename(oldname, newname)
+	if err != nil {
+		goto end
+	}
+	if ofs.inLower(oldname) {
+		ofs.whiteouts[oldname] = struct{}{}
+	}
+	delete(ofs.opaque, oldname)
+	delete(ofs.whiteouts, newname)
+	if info.IsDir() {
+		// The moved tree is complete in the upper layer.
+		ofs.opaque[newname] = struct{}{}
+	}
+end:
+	return err
+}
+
+// Chmod copies name up and changes its permission bits.
+func (ofs *FS) Chmod(name string, mode fs.FileMode) (err error) {
+	ofs.mu.Lock()
+	defer ofs.mu.Unlock()
+
+	err = ofs.copyUp("chmod", name)
+	if err != nil {
+		goto end
+	}
+	err = ofs.upper.Chmod(name, mode)
+end:
+	return err
+}
+
+// Chtimes copies name up and changes its access and modification times.
+func (ofs *FS) Chtimes(name string, atime time.Time, mtime time.Time) (err error) {
+	ofs.mu.Lock()
+	defer ofs.mu.Unlock()
+
+	err = ofs.copyUp("chtimes", name)
+	if err != nil {
+		goto end
+	}
+	err = ofs.upper.Chtimes(name, atime, mtime)
+end:
+	return err
+}
+
+// Whiteouts returns the sorted names deleted from the overlay that still exist
+// in a lower layer.
+func (ofs *FS) Whiteouts() []string {
+	ofs.mu.RLock()
+	defer ofs.mu.RUnlock()
+	return slices.Sorted(maps.Keys(ofs.whiteouts))
+}
+
+// readOnlyFile is a file opened without write flags.
+type readOnlyFile struct {
+	fs.File
+	name string
+}
+
+func (f readOnlyFile) Write([]byte) (int, error) {
+	return 0, pathErr("write", f.name, syscall.EBADF)
+}
+
+// dirFile is an open directory listing the merged entries of all layers.
+type dirFile struct {
+	name    string
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *dirFile) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *dirFile) Close() error               { return nil }
+
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, pathErr("read", d.name, syscall.EISDIR)
+}
+
+func (d *dirFile) ReadDir(count int) (entries []fs.DirEntry, err error) {
+	rest := d.entries[d.offset:]
+	if count <= 0 {
+		entries = rest
+		d.offset = len(d.entries)
+		goto end
+	}
+	if len(rest) == 0 {
+		err = io.EOF
+		goto end
+	}
+	entries = rest[:min(count, len(rest))]
+	d.offset += len(entries)
+end:
+	return entries, err
+}
@@ -0,0 +1,200 @@
+package overlayfs_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/mikeschinkel/go-dt"
+	"github.com/mikeschinkel/go-dt/dtx/memfs"
+	"github.com/mikeschinkel/go-dt/dtx/overlayfs"
+)
+
+// newTestOverlay returns a memfs upper layer over a "user" layer and a
+// read-only "defaults" layer like an embed.FS.
+func newTestOverlay(t *testing.T) *overlayfs.FS {
+	t.Helper()
+	user := fstest.MapFS{
+		"templates/page.html": {Data: []byte("user page"), Mode: 0o644},
+		"config.json":         {Data: []byte("{}"), Mode: 0o644},
+	}
+	defaults := fstest.MapFS{
+		"templates/page.html":   {Data: []byte("default page"), Mode: 0o444},
+		"templates/layout.html": {Data: []byte("default layout"), Mode: 0o444},
+		"templates/old/x.html":  {Data: []byte("old"), Mode: 0o444},
+		"README.md":             {Data: []byte("readme"), Mode: 0o444},
+	}
+	return overlayfs.New(memfs.New(nil),
+		overlayfs.Layer{Name: "user", FS: user},
+		overlayfs.Layer{Name: "defaults", FS: defaults},
+	)
+}
+
+func readString(t *testing.T, fsys fs.FS, name string) string {
+	t.Helper()
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		t.Fatalf("ReadFile(%s) error = %v", name, err)
+	}
+	return string(data)
+}
+
+func TestFS_MergedView(t *testing.T) {
+	ofs := newTestOverlay(t)
+	if got := readString(t, ofs, "templates/page.html"); got != "user page" {
+		t.Errorf("ReadFile() = %q, want %q", got, "user page")
+	}
+	tests := map[string]string{
+		"templates/page.html":   "user",
+		"templates/layout.html": "defaults",
+		"config.json":           "user",
+	}
+	for name, want := range tests {
+		got, err := ofs.Which(name)
+		if err != nil || got != want {
+			t.Errorf("Which(%s) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	err := fstest.TestFS(ofs, "README.md", "config.json", "templates/layout.html",
+		"templates/page.html", "templates/old/x.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFS_CopyUp(t *testing.T) {
+	ofs := newTestOverlay(t)
+	err := dt.Filepath("templates/layout.html").WriteFileFS(ofs, []byte("mine"), 0o644)
+	if err != nil {
+		t.Fatalf("WriteFileFS() error = %v", err)
+	}
+	if got := readString(t, ofs, "templates/layout.html"); got != "mine" {
+		t.Errorf("ReadFile() = %q, want %q", got, "mine")
+	}
+	if got, _ := ofs.Which("templates/layout.html"); got != overlayfs.UpperLayer {
+		t.Errorf("Which() = %q, want %q", got, overlayfs.UpperLayer)
+	}
+
+	// Chmod copies a read-only lower file up without changing its content.
+	if err = ofs.Chmod("README.md", 0o600); err != nil {
+		t.Fatalf("Chmod() error = %v", err)
+	}
+	if got := readString(t, ofs, "README.md"); got != "readme" {
+		t.Errorf("ReadFile() after Chmod = %q, want %q", got, "readme")
+	}
+
+	// The directory still merges entries from every layer.
+	entries, err := ofs.ReadDir("templates")
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"layout.html", "old", "page.html"}; !slices.Equal(names, want) {
+		t.Errorf("ReadDir() = %v, want %v", names, want)
+	}
+}
+
+func TestFS_Whiteouts(t *testing.T) {
+	ofs := newTestOverlay(t)
+	if err := ofs.Remove("templates/layout.html"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := ofs.Stat("templates/layout.html"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat() after Remove error = %v, want %v", err, fs.ErrNotExist)
+	}
+	if err := ofs.Remove("templates/old"); err == nil {
+		t.Error("Remove() of non-empty dir error = nil, want error")
+	}
+	if err := ofs.Remove("templates/old/x.html"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := ofs.Remove("templates/old"); err != nil {
+		t.Fatalf("Remove() of emptied dir error = %v", err)
+	}
+	want := []string{"templates/layout.html", "templates/old", "templates/old/x.html"}
+	if got := ofs.Whiteouts(); !slices.Equal(got, want) {
+		t.Errorf("Whiteouts() = %v, want %v", got, want)
+	}
+
+	// Recreating a deleted directory must not resurrect lower entries.
+	if err := ofs.Mkdir("templates/old", 0o755); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	entries, err := ofs.ReadDir("templates/old")
+	if err != nil || len(entries) != 0 {
+		t.Errorf("ReadDir() of recreated dir = %v, %v; want empty", entries, err)
+	}
+}
+
+func TestFS_Rename(t *testing.T) {
+	ofs := newTestOverlay(t)
+	if err := ofs.Rename("templates", "views"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if _, err := ofs.Stat("templates"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat() of old name error = %v, want %v", err, fs.ErrNotExist)
+	}
+	if got := readString(t, ofs, "views/page.html"); got != "user page" {
+		t.Errorf("ReadFile() = %q, want %q", got, "user page")
+	}
+	if got := readString(t, ofs, "views/old/x.html"); got != "old" {
+		t.Errorf("ReadFile() = %q, want %q", got, "old")
+	}
+}
+
+func TestFS_Commit(t *testing.T) {
+	if err := dt.EnsureUserHomeDir(); err != nil {
+		t.Fatalf("EnsureUserHomeDir() error = %v", err)
+	}
+	dest := dt.DirPath(t.TempDir())
+	if err := dt.FilepathJoin(dest, "templates/layout.html").Dir().MkdirAll(0o755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err := dt.FilepathJoin(dest, "templates/layout.html").WriteFile([]byte("stale"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	ofs := overlayfs.New(memfs.New(nil), overlayfs.Layer{Name: "user", FS: dest.DirFS()})
+	if err := ofs.Remove("templates/layout.html"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := dt.WriteFileFS(ofs, "templates/new.html", []byte("new"), 0o600); err != nil {
+		t.Fatalf("WriteFileFS() error = %v", err)
+	}
+	if err := ofs.Mkdir("private", 0o750); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	if err := dt.WriteFileFS(ofs, "private/key", []byte("key"), 0o600); err != nil {
+		t.Fatalf("WriteFileFS() error = %v", err)
+	}
+	mtime := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	if err := ofs.Chtimes("private", mtime, mtime); err != nil {
+		t.Fatalf("Chtimes() error = %v", err)
+	}
+	if err := ofs.Commit(dest); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	if exists, _ := dt.FilepathJoin(dest, "templates/layout.html").Exists(); exists {
+		t.Error("Commit() did not apply whiteout")
+	}
+	got, err := dt.FilepathJoin(dest, "templates/new.html").ReadFile()
+	if err != nil || string(got) != "new" {
+		t.Errorf("ReadFile() = %q, %v; want %q", got, err, "new")
+	}
+	info, err := os.Stat(string(dt.FilepathJoin(dest, "templates/new.html")))
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Stat() mode = %v, %v; want %v", info.Mode().Perm(), err, os.FileMode(0o600))
+	}
+	info, err = os.Stat(string(dt.DirPathJoin(dest, "private")))
+	if err != nil || info.Mode().Perm() != 0o750 || !info.ModTime().Equal(mtime) {
+		t.Errorf("Stat(private) = %v, %v, %v; want %v, %v", info.Mode().Perm(), info.ModTime(), err, os.FileMode(0o750), mtime)
+	}
+}
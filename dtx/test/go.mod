@@ -7,6 +7,6 @@ replace github.com/mikeschinkel/go-dt/dtx => ..
 replace github.com/mikeschinkel/go-dt => ../..
 
 require (
-	github.com/mikeschinkel/go-dt v0.3.2
+	github.com/mikeschinkel/go-dt v0.5.0
 	github.com/mikeschinkel/go-dt/dtx v0.0.0-00010101000000-000000000000
 )
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/mikeschinkel/go-dt"
+	"github.com/mikeschinkel/go-dt/dtx/memfs"
 )
 
 func TempTestDir(t *testing.T) dt.DirPath {
@@ -12,6 +13,13 @@ func TempTestDir(t *testing.T) dt.DirPath {
 	return dt.DirPath(t.TempDir())
 }
 
+// TempTestFS returns an empty in-memory filesystem for tests that do not need
+// the real disk.
+func TempTestFS(t *testing.T) *memfs.FS {
+	t.Helper()
+	return memfs.New(nil)
+}
+
 func SetTestEnv(t *testing.T, name string, value fmt.Stringer) {
 	t.Helper()
 	t.Setenv(name, value.String())
@@ -0,0 +1,172 @@
+package dtx
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+var (
+	ErrXDGRuntimeDirNotSet = errors.New("XDG_RUNTIME_DIR is not set to an absolute path")
+	ErrXDGFileNotFound     = errors.New("file not found in XDG directories")
+)
+
+// XDG resolves locations per the XDG Base Directory Specification. The zero
+// value reads the real environment and the package-wide dt.HomeDirProvider;
+// set LookupEnv and HomeDirProvider for deterministic tests.
+//
+// As the specification requires, relative values in XDG_* variables are
+// ignored, falling back to the defaults. The defaults are applied on every
+// OS, which suits CLI tools that follow XDG on macOS too.
+type XDG struct {
+	LookupEnv       dt.EnvLookupFunc
+	HomeDirProvider dt.HomeDirProvider
+}
+
+func (x XDG) lookup(name string) (string, bool) {
+	if x.LookupEnv == nil {
+		return os.LookupEnv(name)
+	}
+	return x.LookupEnv(name)
+}
+
+// env returns the value of name when it is an absolute path.
+func (x XDG) env(name string) (value string, ok bool) {
+	value, ok = x.lookup(name)
+	ok = ok && filepath.IsAbs(value)
+	return value, ok
+}
+
+// home returns the user directory named by env, or def under the home
+// directory when env is empty or not usable.
+func (x XDG) home(env string, def dt.PathSegments) (dp dt.DirPath, err error) {
+	var home dt.DirPath
+	var hdp dt.HomeDirProvider
+	var value string
+	var ok bool
+
+	value, ok = x.env(env)
+	if env != "" && ok {
+		dp = dt.DirPath(filepath.Clean(value))
+		goto end
+	}
+	hdp = x.HomeDirProvider
+	if hdp == nil {
+		hdp = dt.GetHomeDirProvider()
+	}
+	home, err = hdp.HomeDir()
+	if err != nil {
+		err = dt.NewErr(dt.ErrAccessingUserHomeDir, "xdg_var", env, err)
+		goto end
+	}
+	dp = dt.DirPathJoin(home, def)
+end:
+	return dp, err
+}
+
+// dirs returns the search list named by env, or def.
+func (x XDG) dirs(env string, def DirPaths) (dps DirPaths) {
+	var value string
+
+	value, _ = x.lookup(env)
+	for _, dir := range strings.Split(value, string(os.PathListSeparator)) {
+		if filepath.IsAbs(dir) {
+			dps = append(dps, dt.DirPath(filepath.Clean(dir)))
+		}
+	}
+	if len(dps) == 0 {
+		dps = def
+	}
+	return dps.Unique()
+}
+
+// ConfigHome returns $XDG_CONFIG_HOME, defaulting to ~/.config.
+func (x XDG) ConfigHome() (dt.DirPath, error) {
+	return x.home("XDG_CONFIG_HOME", ".config")
+}
+
+// DataHome returns $XDG_DATA_HOME, defaulting to ~/.local/share.
+func (x XDG) DataHome() (dt.DirPath, error) {
+	return x.home("XDG_DATA_HOME", dt.PathSegments(filepath.Join(".local", "share")))
+}
+
+// StateHome returns $XDG_STATE_HOME, defaulting to ~/.local/state.
+func (x XDG) StateHome() (dt.DirPath, error) {
+	return x.home("XDG_STATE_HOME", dt.PathSegments(filepath.Join(".local", "state")))
+}
+
+// CacheHome returns $XDG_CACHE_HOME, defaulting to ~/.cache.
+func (x XDG) CacheHome() (dt.DirPath, error) {
+	return x.home("XDG_CACHE_HOME", ".cache")
+}
+
+// BinHome returns ~/.local/bin, where the specification places user
+// executables.
+func (x XDG) BinHome() (dt.DirPath, error) {
+	return x.home("", dt.PathSegments(filepath.Join(".local", "bin")))
+}
+
+// RuntimeDir returns $XDG_RUNTIME_DIR. The specification gives it no default,
+// so it fails with ErrXDGRuntimeDirNotSet when the variable is unset or
+// relative.
+func (x XDG) RuntimeDir() (dp dt.DirPath, err error) {
+	value, ok := x.env("XDG_RUNTIME_DIR")
+	if !ok {
+		err = dt.NewErr(ErrXDGRuntimeDirNotSet)
+		goto end
+	}
+	dp = dt.DirPath(filepath.Clean(value))
+end:
+	return dp, err
+}
+
+// ConfigDirs returns the system configuration search list from
+// $XDG_CONFIG_DIRS, in order of preference, defaulting to /etc/xdg.
+func (x XDG) ConfigDirs() DirPaths {
+	return x.dirs("XDG_CONFIG_DIRS", DirPaths{"/etc/xdg"})
+}
+
+// DataDirs returns the system data search list from $XDG_DATA_DIRS, in order
+// of preference, defaulting to /usr/local/share and /usr/share.
+func (x XDG) DataDirs() DirPaths {
+	return x.dirs("XDG_DATA_DIRS", DirPaths{"/usr/local/share", "/usr/share"})
+}
+
+// FindConfigFile returns the first existing rel in ConfigHome and then in
+// each of ConfigDirs, failing with ErrXDGFileNotFound.
+func (x XDG) FindConfigFile(rel dt.RelFilepath) (dt.Filepath, error) {
+	return x.find(rel, x.ConfigHome, x.ConfigDirs)
+}
+
+// FindDataFile returns the first existing rel in DataHome and then in each of
+// DataDirs, failing with ErrXDGFileNotFound.
+func (x XDG) FindDataFile(rel dt.RelFilepath) (dt.Filepath, error) {
+	return x.find(rel, x.DataHome, x.DataDirs)
+}
+
+func (x XDG) find(rel dt.RelFilepath, home func() (dt.DirPath, error), system func() DirPaths) (fp dt.Filepath, err error) {
+	var dps DirPaths
+	var dp dt.DirPath
+	var info fs.FileInfo
+
+	dp, err = home()
+	if err == nil {
+		dps = append(dps, dp)
+	}
+	dps = append(dps, system()...)
+	for _, dp = range dps {
+		fp = dt.FilepathJoin(dp, rel)
+		info, err = fp.Stat()
+		if err == nil && !info.IsDir() {
+			goto end
+		}
+	}
+	fp = ""
+	err = dt.NewErr(ErrXDGFileNotFound, "file", rel, "search_dirs", dps.Join(string(os.PathListSeparator)))
+end:
+	return fp, err
+}
@@ -0,0 +1,120 @@
+package dtx
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+func testXDG(env map[string]string, home dt.DirPath) XDG {
+	return XDG{
+		LookupEnv: func(name string) (string, bool) {
+			v, ok := env[name]
+			return v, ok
+		},
+		HomeDirProvider: dt.StaticHomeDirs{Current: home},
+	}
+}
+
+func TestXDG_Dirs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses Unix absolute paths")
+	}
+	t.Run("defaults", func(t *testing.T) {
+		x := testXDG(nil, "/home/me")
+		tests := map[string]func() (dt.DirPath, error){
+			"/home/me/.config":      x.ConfigHome,
+			"/home/me/.local/share": x.DataHome,
+			"/home/me/.local/state": x.StateHome,
+			"/home/me/.cache":       x.CacheHome,
+			"/home/me/.local/bin":   x.BinHome,
+		}
+		for want, fn := range tests {
+			if got, err := fn(); err != nil || got != dt.DirPath(want) {
+				t.Errorf("got %q, %v; want %q", got, err, want)
+			}
+		}
+		if got := x.ConfigDirs(); !slices.Equal(got, DirPaths{"/etc/xdg"}) {
+			t.Errorf("ConfigDirs() = %v", got)
+		}
+		if got := x.DataDirs(); !slices.Equal(got, DirPaths{"/usr/local/share", "/usr/share"}) {
+			t.Errorf("DataDirs() = %v", got)
+		}
+		if _, err := x.RuntimeDir(); !errors.Is(err, ErrXDGRuntimeDirNotSet) {
+			t.Errorf("RuntimeDir() error = %v, want %v", err, ErrXDGRuntimeDirNotSet)
+		}
+	})
+
+	t.Run("environment", func(t *testing.T) {
+		x := testXDG(map[string]string{
+			"XDG_CONFIG_HOME": "/cfg/",
+			"XDG_DATA_HOME":   "relative/data",
+			"XDG_RUNTIME_DIR": "/run/user/1000",
+			"XDG_CONFIG_DIRS": "/a:relative:/b:/a",
+			"XDG_DATA_DIRS":   "only/relative",
+		}, "/home/me")
+		if got, _ := x.ConfigHome(); got != "/cfg" {
+			t.Errorf("ConfigHome() = %q, want %q", got, "/cfg")
+		}
+		if got, _ := x.DataHome(); got != "/home/me/.local/share" {
+			t.Errorf("DataHome() with relative value = %q, want the default", got)
+		}
+		if got, err := x.RuntimeDir(); err != nil || got != "/run/user/1000" {
+			t.Errorf("RuntimeDir() = %q, %v", got, err)
+		}
+		if got := x.ConfigDirs(); !slices.Equal(got, DirPaths{"/a", "/b"}) {
+			t.Errorf("ConfigDirs() = %v, want [/a /b]", got)
+		}
+		if got := x.DataDirs(); !slices.Equal(got, DirPaths{"/usr/local/share", "/usr/share"}) {
+			t.Errorf("DataDirs() with only relative values = %v, want the defaults", got)
+		}
+	})
+}
+
+func TestXDG_FindConfigFile(t *testing.T) {
+	root := t.TempDir()
+	dir := func(name string) string {
+		return filepath.Join(root, name)
+	}
+	write := func(name string) {
+		fp := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(fp), 0o755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(fp, []byte(name), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	write("sys1/app/only-sys.toml")
+	write("sys2/app/only-sys.toml")
+	write("sys2/app/config.toml")
+	write("user/app/config.toml")
+	if err := os.MkdirAll(dir("user/app/dir.toml"), 0o755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	write("sys1/app/dir.toml")
+
+	x := testXDG(map[string]string{
+		"XDG_CONFIG_HOME": dir("user"),
+		"XDG_CONFIG_DIRS": dir("sys1") + string(os.PathListSeparator) + dir("sys2"),
+	}, dt.DirPath(root))
+	tests := map[dt.RelFilepath]string{
+		"app/config.toml":   dir("user/app/config.toml"),
+		"app/only-sys.toml": dir("sys1/app/only-sys.toml"),
+		"app/dir.toml":      dir("sys1/app/dir.toml"),
+	}
+	for rel, want := range tests {
+		got, err := x.FindConfigFile(rel)
+		if err != nil || got != dt.Filepath(want) {
+			t.Errorf("FindConfigFile(%s) = %q, %v; want %q", rel, got, err, want)
+		}
+	}
+	if _, err := x.FindConfigFile("app/missing.toml"); !errors.Is(err, ErrXDGFileNotFound) {
+		t.Errorf("FindConfigFile(missing) error = %v, want %v", err, ErrXDGFileNotFound)
+	}
+}
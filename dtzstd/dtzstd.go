@@ -0,0 +1,31 @@
+// Package dtzstd adds Zstandard support to dt's archive functions. Import it
+// for its side effect:
+//
+//	import _ "github.com/mikeschinkel/go-dt/dtzstd"
+//
+// It lives in its own module so that dt itself stays free of third-party
+// dependencies.
+package dtzstd
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/mikeschinkel/go-dt"
+)
+
+func init() {
+	dt.RegisterDecompressor(dt.TarZstArchive, Decompress)
+}
+
+// Decompress returns a reader that decompresses the Zstandard stream r.
+func Decompress(r io.Reader) (rc io.ReadCloser, err error) {
+	var zr *zstd.Decoder
+	zr, err = zstd.NewReader(r)
+	if err != nil {
+		goto end
+	}
+	rc = zr.IOReadCloser()
+end:
+	return rc, err
+}
@@ -0,0 +1,49 @@
+package dtzstd_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/mikeschinkel/go-dt"
+	_ "github.com/mikeschinkel/go-dt/dtzstd"
+)
+
+func TestExtractTo_TarZst(t *testing.T) {
+	if err := dt.EnsureUserHomeDir(); err != nil {
+		t.Fatalf("EnsureUserHomeDir() error = %v", err)
+	}
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	tw := tar.NewWriter(zw)
+	body := []byte("hello zstd")
+	if err = tw.WriteHeader(&tar.Header{Name: "dir/hello.txt", Mode: 0o644, Size: int64(len(body))}); err != nil {
+		t.Fatalf("WriteHeader() error = %v", err)
+	}
+	if _, err = tw.Write(body); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err = tw.Close(); err != nil {
+		t.Fatalf("tar Close() error = %v", err)
+	}
+	if err = zw.Close(); err != nil {
+		t.Fatalf("zstd Close() error = %v", err)
+	}
+
+	fp := dt.FilepathJoin(t.TempDir(), "archive.tar.zst")
+	if err = fp.WriteFile(buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	dest := dt.DirPath(t.TempDir())
+	if err = fp.ExtractTo(dest, nil); err != nil {
+		t.Fatalf("ExtractTo() error = %v", err)
+	}
+	got, err := dt.FilepathJoin(dest, "dir/hello.txt").ReadFile()
+	if err != nil || !bytes.Equal(got, body) {
+		t.Errorf("ReadFile() = %q, %v; want %q", got, err, body)
+	}
+}
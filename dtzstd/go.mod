@@ -0,0 +1,10 @@
+module github.com/mikeschinkel/go-dt/dtzstd
+
+go 1.25.3
+
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/mikeschinkel/go-dt v0.5.0
+)
+
+replace github.com/mikeschinkel/go-dt => ../
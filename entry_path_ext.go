@@ -119,10 +119,27 @@ func (ep EntryPath) HasDotDotPrefix() bool {
 	return ep == ".." || strings.HasPrefix(string(ep), ".."+string(os.PathSeparator))
 }
 
-func (ep EntryPath) Expand() (out EntryPath, err error) {
+// Expand returns ep as a clean absolute path, expanding a leading "~" to the
+// current user's home directory and "~user" to that user's, as resolved by
+// the HomeDirProvider. With ExpandOptions.ExpandEnv set, environment variables
+// are expanded first.
+func (ep EntryPath) Expand(opts ...ExpandOptions) (out EntryPath, err error) {
 	var home DirPath
+	var o ExpandOptions
+	var username, rest string
+	var n int
 	s := string(ep)
 
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.ExpandEnv {
+		s, err = expandEnv(s, o)
+		if err != nil {
+			goto end
+		}
+	}
+
 	switch {
 	case len(s) == 0:
 		err = ErrEmpty
@@ -139,12 +156,7 @@ func (ep EntryPath) Expand() (out EntryPath, err error) {
 		goto end
 
 	case s == "~":
-		home, err = UserHomeDir()
-		if err != nil {
-			goto end
-		}
-		out = EntryPath(home)
-		goto end
+		// Go on to be handled by the tilde expansion
 
 	case s[0] == '/':
 		// We are an absolute path already, works on Windows, macOS and Linux.
@@ -174,9 +186,27 @@ func (ep EntryPath) Expand() (out EntryPath, err error) {
 		if runtime.GOOS == "windows" {
 			s = filepath.FromSlash(s)
 		}
+		rest = s[2:]
 		// Go on to be handled by the tilde expansion
 
 	case s[:2] == "~\\" && runtime.GOOS == "windows":
+		rest = s[2:]
+		// Go on to be handled by the tilde expansion
+
+	case s[0] == '~':
+		// We are a ~user path
+		if runtime.GOOS == "windows" {
+			s = filepath.FromSlash(s)
+		}
+		username = s[1:]
+		n = strings.IndexByte(username, os.PathSeparator)
+		if n >= 0 {
+			username, rest = username[:n], username[n+1:]
+		}
+		if !isValidUsername(username) {
+			out, err = EntryPath(s).Clean().Abs()
+			goto end
+		}
 		// Go on to be handled by the tilde expansion
 
 	default:
@@ -186,17 +216,22 @@ func (ep EntryPath) Expand() (out EntryPath, err error) {
 
 	}
 
-	home, err = UserHomeDir()
+	home, err = o.homeDir(username)
+	if username != "" && errors.Is(err, ErrUnknownUser) {
+		// Like a shell, leave "~name" alone when there is no such user
+		out, err = EntryPath(s).Clean().Abs()
+		goto end
+	}
 	if err != nil {
 		goto end
 	}
 
-	if len(s) == 2 {
+	if rest == "" {
 		out = EntryPath(home)
 		goto end
 	}
 
-	out = EntryPathJoin(home, s[2:]).Clean()
+	out = EntryPathJoin(home, rest).Clean()
 
 end:
 	if err != nil {
@@ -216,8 +251,8 @@ end:
 	return exists, err
 }
 
-func (ep EntryPath) ToTilde(opt TildeOption) (tep TildeEntryPath) {
-	return ToTilde[EntryPath, TildeEntryPath](ep, opt)
+func (ep EntryPath) ToTilde(opt TildeOption, hdps ...HomeDirProvider) (tep TildeEntryPath) {
+	return ToTilde[EntryPath, TildeEntryPath](ep, opt, hdps...)
 }
 
 func (ep EntryPath) TrimTilde() (tdp PathSegments) {
@@ -0,0 +1,225 @@
+package dt
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// EnvLookupFunc looks up an environment variable, with the same contract as
+// os.LookupEnv.
+type EnvLookupFunc func(name string) (value string, ok bool)
+
+// ExpandOptions controls the optional steps of Expand and Normalize.
+type ExpandOptions struct {
+	// ExpandEnv expands $VAR, ${VAR} and ${VAR:-default} before any leading
+	// "~" is handled, and %VAR% on Windows or when PercentVars is set.
+	ExpandEnv bool
+
+	// LookupEnv resolves variables. Nil means os.LookupEnv; tests can pass a
+	// map-backed function to avoid reading the real environment.
+	LookupEnv EnvLookupFunc
+
+	// Strict fails with ErrUndefinedEnvVar, carrying the variable under the
+	// "env_var" key, when a variable without a default is not set. Otherwise
+	// undefined $VAR and ${VAR} expand to "" and undefined %VAR% is kept.
+	Strict bool
+
+	// PercentVars enables %VAR% outside Windows.
+	PercentVars bool
+
+	// HomeDirProvider resolves "~" and "~user". Nil means the package-wide
+	// provider; see SetHomeDirProvider.
+	HomeDirProvider HomeDirProvider
+}
+
+// homeDir returns the home directory of username, or of the current user
+// when username is empty.
+func (opts ExpandOptions) homeDir(username string) (dp DirPath, err error) {
+	p := pickHomeDirProvider([]HomeDirProvider{opts.HomeDirProvider})
+	if username == "" {
+		dp, err = p.HomeDir()
+	} else {
+		dp, err = p.UserHomeDir(username)
+	}
+	if err != nil {
+		err = NewErr(ErrAccessingUserHomeDir, "username", username, err)
+	}
+	return dp, err
+}
+
+func (opts ExpandOptions) lookup(name string) (string, bool) {
+	if opts.LookupEnv == nil {
+		return os.LookupEnv(name)
+	}
+	return opts.LookupEnv(name)
+}
+
+func (opts ExpandOptions) percentVars() bool {
+	return opts.PercentVars || runtime.GOOS == "windows"
+}
+
+// ExpandEnv expands the environment variables in ep as described for
+// ExpandOptions.ExpandEnv, leaving the rest of the path untouched.
+func (ep EntryPath) ExpandEnv(opts ExpandOptions) (out EntryPath, err error) {
+	var s string
+	s, err = expandEnv(string(ep), opts)
+	if err != nil {
+		err = WithErr(err, ErrFailedToExpandPath, ep.ErrKV())
+	}
+	return EntryPath(s), err
+}
+
+// Collapse replaces the longest leading part of ep that equals the absolute
+// value of one of the named variables with ${NAME}, the reverse of
+// ExpandEnv. Values that are unset, empty or relative are ignored, and a
+// prefix only matches whole path segments.
+func (ep EntryPath) Collapse(names []string, opts ...ExpandOptions) (out EntryPath) {
+	var o ExpandOptions
+	var best string
+	var bestName string
+
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	out = ep
+	for _, name := range names {
+		value, ok := o.lookup(name)
+		if !ok || !filepath.IsAbs(value) {
+			continue
+		}
+		value = filepath.Clean(value)
+		if len(value) <= len(best) || !hasPathPrefix(string(ep), value) {
+			continue
+		}
+		best, bestName = value, name
+	}
+	if bestName != "" {
+		out = EntryPath("${" + bestName + "}" + strings.TrimPrefix(string(ep), best))
+	}
+	return out
+}
+
+// hasPathPrefix reports whether prefix is s or a leading run of its segments.
+func hasPathPrefix(s, prefix string) bool {
+	if !strings.HasPrefix(s, prefix) {
+		return false
+	}
+	return len(s) == len(prefix) ||
+		os.IsPathSeparator(s[len(prefix)]) ||
+		os.IsPathSeparator(prefix[len(prefix)-1])
+}
+
+// expandEnv expands the variables in s.
+func expandEnv(s string, opts ExpandOptions) (out string, err error) {
+	var sb strings.Builder
+	var value string
+	var n int
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			value, n, err = expandBraced(s[i:], opts)
+		case s[i] == '$' && i+1 < len(s) && isEnvNameStart(s[i+1]):
+			n = 1 + envNameLen(s[i+1:])
+			value, err = lookupEnvVar(s[i+1:i+n], opts)
+		case s[i] == '%' && opts.percentVars():
+			value, n, err = expandPercent(s[i:], opts)
+		default:
+			value, n = s[i:i+1], 1
+		}
+		if err != nil {
+			goto end
+		}
+		sb.WriteString(value)
+		i += n - 1
+	}
+	out = sb.String()
+end:
+	return out, err
+}
+
+// expandBraced expands the ${...} at the start of s, returning its value and
+// length.
+func expandBraced(s string, opts ExpandOptions) (value string, n int, err error) {
+	var depth int
+	var name, def string
+	var hasDef bool
+	var ok bool
+
+	for n = 2; n < len(s); n++ {
+		switch {
+		case s[n] == '{' && s[n-1] == '$':
+			depth++
+		case s[n] == '}' && depth > 0:
+			depth--
+		case s[n] == '}':
+			goto found
+		}
+	}
+	err = NewErr(ErrInvalidEnvVar, "env_var", s)
+	goto end
+found:
+	n++
+	name, def, hasDef = strings.Cut(s[2:n-1], ":-")
+	if envNameLen(name) != len(name) || name == "" || !isEnvNameStart(name[0]) {
+		err = NewErr(ErrInvalidEnvVar, "env_var", s[:n])
+		goto end
+	}
+	if !hasDef {
+		value, err = lookupEnvVar(name, opts)
+		goto end
+	}
+	value, ok = opts.lookup(name)
+	if !ok || value == "" {
+		value, err = expandEnv(def, opts)
+	}
+end:
+	return value, n, err
+}
+
+// expandPercent expands the %NAME% at the start of s. A '%' that does not
+// start a well-formed reference is kept as is.
+func expandPercent(s string, opts ExpandOptions) (value string, n int, err error) {
+	var ok bool
+
+	value, n = "%", 1
+	closing := strings.IndexByte(s[1:], '%')
+	if closing <= 0 || !isEnvNameStart(s[1]) || envNameLen(s[1:]) != closing {
+		goto end
+	}
+	n = closing + 2
+	value, ok = opts.lookup(s[1 : n-1])
+	switch {
+	case ok:
+	case opts.Strict:
+		err = NewErr(ErrUndefinedEnvVar, "env_var", s[1:n-1])
+	default:
+		value = s[:n]
+	}
+end:
+	return value, n, err
+}
+
+func lookupEnvVar(name string, opts ExpandOptions) (value string, err error) {
+	var ok bool
+
+	value, ok = opts.lookup(name)
+	if !ok && opts.Strict {
+		err = NewErr(ErrUndefinedEnvVar, "env_var", name)
+	}
+	return value, err
+}
+
+func isEnvNameStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// envNameLen returns the length of the variable name at the start of s.
+func envNameLen(s string) (n int) {
+	for n < len(s) && (isEnvNameStart(s[n]) || '0' <= s[n] && s[n] <= '9') {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,123 @@
+package dt_test
+
+import (
+	"errors"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+func testLookupEnv(env map[string]string) dt.EnvLookupFunc {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+func TestEntryPath_ExpandEnv(t *testing.T) {
+	if err := dt.EnsureUserHomeDir(); err != nil {
+		t.Fatalf("EnsureUserHomeDir() error = %v", err)
+	}
+	lookup := testLookupEnv(map[string]string{
+		"HOME":          "/home/alice",
+		"XDG_DATA_HOME": "/data",
+		"APPDATA":       "/appdata",
+		"EMPTY":         "",
+	})
+	tests := []struct {
+		name   string
+		in     dt.EntryPath
+		opts   dt.ExpandOptions
+		want   dt.EntryPath
+		errIs  error
+		errVar string
+	}{
+		{name: "dollar", in: "$HOME/x", want: "/home/alice/x"},
+		{name: "braced", in: "${XDG_DATA_HOME}/app", want: "/data/app"},
+		{name: "default used", in: "${MISSING:-/opt}/app", want: "/opt/app"},
+		{name: "default when empty", in: "${EMPTY:-/opt}/app", want: "/opt/app"},
+		{name: "default not used", in: "${HOME:-/opt}", want: "/home/alice"},
+		{name: "nested default", in: "${MISSING:-${HOME}/d}", want: "/home/alice/d"},
+		{name: "undefined", in: "$MISSING/x", want: "/x"},
+		{name: "lone dollar", in: "a$/b", want: "a$/b"},
+		{name: "percent", in: "%APPDATA%/app", opts: dt.ExpandOptions{PercentVars: true}, want: "/appdata/app"},
+		{name: "percent undefined kept", in: "%NOPE%/a", opts: dt.ExpandOptions{PercentVars: true}, want: "%NOPE%/a"},
+		{name: "percent literal", in: "50%/a", opts: dt.ExpandOptions{PercentVars: true}, want: "50%/a"},
+		{name: "strict", in: "$HOME/$MISSING", opts: dt.ExpandOptions{Strict: true}, errIs: dt.ErrUndefinedEnvVar, errVar: "MISSING"},
+		{name: "strict braced", in: "${MISSING}", opts: dt.ExpandOptions{Strict: true}, errIs: dt.ErrUndefinedEnvVar, errVar: "MISSING"},
+		{name: "strict default ok", in: "${MISSING:-/opt}", opts: dt.ExpandOptions{Strict: true}, want: "/opt"},
+		{name: "unterminated", in: "${HOME/x", errIs: dt.ErrInvalidEnvVar},
+		{name: "bad name", in: "${1A}", errIs: dt.ErrInvalidEnvVar},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.LookupEnv = lookup
+			got, err := tt.in.ExpandEnv(tt.opts)
+			if tt.errIs != nil {
+				if !errors.Is(err, tt.errIs) {
+					t.Fatalf("ExpandEnv() error = %v, want %v", err, tt.errIs)
+				}
+				if tt.errVar != "" {
+					if v, _ := dt.ErrValue[string](err, "env_var"); v != tt.errVar {
+						t.Errorf("ErrValue(env_var) = %q, want %q", v, tt.errVar)
+					}
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ExpandEnv() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDirPath_NormalizeWithEnv(t *testing.T) {
+	if err := dt.EnsureUserHomeDir(); err != nil {
+		t.Fatalf("EnsureUserHomeDir() error = %v", err)
+	}
+	base := t.TempDir()
+	opts := dt.ExpandOptions{
+		ExpandEnv: true,
+		LookupEnv: testLookupEnv(map[string]string{"BASE": base}),
+	}
+	got, err := dt.DirPath("$BASE/a/../b").Normalize(opts)
+	if want := dt.DirPath(filepath.Join(base, "b")); err != nil || got != want {
+		t.Errorf("Normalize() = %q, %v; want %q", got, err, want)
+	}
+	// Without ExpandEnv the variable is left alone.
+	got, err = dt.DirPath("$BASE").Normalize()
+	if err != nil || got == dt.DirPath(base) {
+		t.Errorf("Normalize() without ExpandEnv = %q, %v; want no expansion", got, err)
+	}
+}
+
+func TestEntryPath_Collapse(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses Unix absolute paths")
+	}
+	opts := dt.ExpandOptions{LookupEnv: testLookupEnv(map[string]string{
+		"HOME":          "/home/alice",
+		"XDG_DATA_HOME": "/home/alice/.local/share",
+		"RELATIVE":      "rel",
+	})}
+	names := []string{"HOME", "XDG_DATA_HOME", "RELATIVE", "UNSET"}
+	tests := map[dt.EntryPath]dt.EntryPath{
+		"/home/alice":                     "${HOME}",
+		"/home/alice/notes":               "${HOME}/notes",
+		"/home/alice/.local/share/app/db": "${XDG_DATA_HOME}/app/db",
+		"/home/alicex/notes":              "/home/alicex/notes",
+		"/etc/passwd":                     "/etc/passwd",
+		"rel/x":                           "rel/x",
+	}
+	for in, want := range tests {
+		if got := in.Collapse(names, opts); got != want {
+			t.Errorf("Collapse(%q) = %q, want %q", in, got, want)
+		}
+		back, err := want.ExpandEnv(opts)
+		if err != nil || back != in {
+			t.Errorf("ExpandEnv(Collapse(%q)) = %q, %v", in, back, err)
+		}
+	}
+}
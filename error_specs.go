@@ -0,0 +1,129 @@
+package dt
+
+import (
+	"io/fs"
+)
+
+// dtErrSpecs gives dt's sentinels stable codes and categories. Generic
+// "failed to ..." wrappers are internal so that a more specific cause decides
+// how an error is classified. Exit codes follow sysexits.h.
+var dtErrSpecs = []struct {
+	sentinel error
+	spec     ErrSpec
+}{
+	{fs.ErrNotExist, ErrSpec{Code: "fs.not_exist", Category: ErrCategoryNotFound}},
+	{fs.ErrExist, ErrSpec{Code: "fs.exist", Category: ErrCategoryInvalidInput, HTTPStatus: 409, ExitCode: 73}},
+	{fs.ErrPermission, ErrSpec{Code: "fs.permission", Category: ErrCategoryPermission}},
+	{fs.ErrInvalid, ErrSpec{Code: "fs.invalid", Category: ErrCategoryInvalidInput}},
+	{ErrPathIsDir, ErrSpec{Code: "dt.path_is_dir", Category: ErrCategoryInvalidInput}},
+	{ErrPathIsFile, ErrSpec{Code: "dt.path_is_file", Category: ErrCategoryInvalidInput}},
+	{ErrNotExist, ErrSpec{Code: "dt.not_exist", Category: ErrCategoryNotFound}},
+	{ErrExists, ErrSpec{Code: "dt.exists", Category: ErrCategoryInvalidInput, HTTPStatus: 409, ExitCode: 73}},
+	{ErrDirNotExist, ErrSpec{Code: "dt.dir_not_exist", Category: ErrCategoryNotFound}},
+	{ErrDirExists, ErrSpec{Code: "dt.dir_exists", Category: ErrCategoryInvalidInput, HTTPStatus: 409, ExitCode: 73}},
+	{ErrFileNotExist, ErrSpec{Code: "dt.file_not_exist", Category: ErrCategoryNotFound}},
+	{ErrFileExists, ErrSpec{Code: "dt.file_exists", Category: ErrCategoryInvalidInput, HTTPStatus: 409, ExitCode: 73}},
+	{ErrInvalidEntryStatus, ErrSpec{Code: "dt.invalid_entry_status", Category: ErrCategoryInternal}},
+	{ErrUnclassifiedEntryStatus, ErrSpec{Code: "dt.unclassified_entry_status", Category: ErrCategoryInternal}},
+	{ErrUnsupportedEntryType, ErrSpec{Code: "dt.unsupported_entry_type", Category: ErrCategoryInvalidInput}},
+	{ErrInvalidFilepath, ErrSpec{Code: "dt.invalid_filepath", Category: ErrCategoryInvalidInput}},
+	{ErrInvalidRelFilepath, ErrSpec{Code: "dt.invalid_rel_filepath", Category: ErrCategoryInvalidInput}},
+	{ErrInvalidRelDirPath, ErrSpec{Code: "dt.invalid_rel_dir_path", Category: ErrCategoryInvalidInput}},
+	{ErrInvalidPathSegment, ErrSpec{Code: "dt.invalid_path_segment", Category: ErrCategoryInvalidInput}},
+	{ErrInvalidURLSegment, ErrSpec{Code: "dt.invalid_url_segment", Category: ErrCategoryInvalidInput}},
+	{ErrInvalidURLSegments, ErrSpec{Code: "dt.invalid_url_segments", Category: ErrCategoryInvalidInput}},
+	{ErrInvalidIdentifier, ErrSpec{Code: "dt.invalid_identifier", Category: ErrCategoryInvalidInput}},
+	{ErrFailedTypeAssertion, ErrSpec{Code: "dt.failed_type_assertion", Category: ErrCategoryInternal}},
+	{ErrInvalidForOpen, ErrSpec{Code: "dt.invalid_for_open", Category: ErrCategoryInvalidInput}},
+	{ErrFileSystem, ErrSpec{Code: "dt.file_system", Category: ErrCategoryInternal}},
+	{ErrFileStat, ErrSpec{Code: "dt.file_stat", Category: ErrCategoryInternal}},
+	{ErrFailedReadingSymlink, ErrSpec{Code: "dt.failed_reading_symlink", Category: ErrCategoryInternal}},
+	{ErrFailedToLoadFile, ErrSpec{Code: "dt.failed_to_load_file", Category: ErrCategoryInternal}},
+	{ErrFailedToCopyFile, ErrSpec{Code: "dt.failed_to_copy_file", Category: ErrCategoryInternal}},
+	{ErrFailedToSaveFile, ErrSpec{Code: "dt.failed_to_save_file", Category: ErrCategoryInternal}},
+	{ErrFailedToRemoveFile, ErrSpec{Code: "dt.failed_to_remove_file", Category: ErrCategoryInternal}},
+	{ErrFailedtoRemoveFile, ErrSpec{Code: "dt.failed_to_remove_file", Category: ErrCategoryInternal}},
+	{ErrFailedToReadFile, ErrSpec{Code: "dt.failed_to_read_file", Category: ErrCategoryInternal}},
+	{ErrFailedToOpenFile, ErrSpec{Code: "dt.failed_to_open_file", Category: ErrCategoryInternal}},
+	{ErrFailedToWriteToFile, ErrSpec{Code: "dt.failed_to_write_to_file", Category: ErrCategoryInternal}},
+	{ErrFailedToMakeDirectory, ErrSpec{Code: "dt.failed_to_make_directory", Category: ErrCategoryInternal}},
+	{ErrFailedtoCreateTempFile, ErrSpec{Code: "dt.failed_to_create_temp_file", Category: ErrCategoryInternal}},
+	{ErrFailedtoCreateFile, ErrSpec{Code: "dt.failed_to_create_file", Category: ErrCategoryInternal}},
+	{ErrFailedtoCreateDir, ErrSpec{Code: "dt.failed_to_create_dir", Category: ErrCategoryInternal}},
+	{ErrContainsBackslash, ErrSpec{Code: "dt.contains_backslash", Category: ErrCategoryInvalidInput}},
+	{ErrContainsSlash, ErrSpec{Code: "dt.contains_slash", Category: ErrCategoryInvalidInput}},
+	{ErrEmpty, ErrSpec{Code: "dt.empty", Category: ErrCategoryInvalidInput}},
+	{ErrInvalidPercentEncoding, ErrSpec{Code: "dt.invalid_percent_encoding", Category: ErrCategoryInvalidInput}},
+	{ErrTooShort, ErrSpec{Code: "dt.too_short", Category: ErrCategoryInvalidInput}},
+	{ErrTooLong, ErrSpec{Code: "dt.too_long", Category: ErrCategoryInvalidInput}},
+	{ErrUnspecified, ErrSpec{Code: "dt.unspecified", Category: ErrCategoryInvalidInput}},
+	{ErrInvalid, ErrSpec{Code: "dt.invalid", Category: ErrCategoryInvalidInput}},
+	{ErrInvalidDirectory, ErrSpec{Code: "dt.invalid_directory", Category: ErrCategoryInvalidInput}},
+	{ErrInvalidfileSystemEntryType, ErrSpec{Code: "dt.invalid_file_system_entry_type", Category: ErrCategoryInvalidInput}},
+	{ErrControlCharacter, ErrSpec{Code: "dt.control_character", Category: ErrCategoryInvalidInput}},
+	{ErrInvalidCharacter, ErrSpec{Code: "dt.invalid_character", Category: ErrCategoryInvalidInput}},
+	{ErrTrailingSpace, ErrSpec{Code: "dt.trailing_space", Category: ErrCategoryInvalidInput}},
+	{ErrTrailingPeriod, ErrSpec{Code: "dt.trailing_period", Category: ErrCategoryInvalidInput}},
+	{ErrReservedDeviceName, ErrSpec{Code: "dt.reserved_device_name", Category: ErrCategoryInvalidInput}},
+	{ErrNotFileOrDirectory, ErrSpec{Code: "dt.not_file_or_directory", Category: ErrCategoryInvalidInput}},
+	{ErrNotDirectory, ErrSpec{Code: "dt.not_directory", Category: ErrCategoryInvalidInput}},
+	{ErrIsAFile, ErrSpec{Code: "dt.is_a_file", Category: ErrCategoryInvalidInput}},
+	{ErrIsADirectory, ErrSpec{Code: "dt.is_a_directory", Category: ErrCategoryInvalidInput}},
+	{ErrCannotDetermineWorkingDirectory, ErrSpec{Code: "dt.cannot_determine_working_directory", Category: ErrCategoryInternal}},
+	{ErrFailedToExpandPath, ErrSpec{Code: "dt.failed_to_expand_path", Category: ErrCategoryInternal}},
+	{ErrFailedToEnsureDir, ErrSpec{Code: "dt.failed_to_ensure_dir", Category: ErrCategoryInternal}},
+	{ErrFailedToExtractArchive, ErrSpec{Code: "dt.failed_to_extract_archive", Category: ErrCategoryInternal}},
+	{ErrFailedToOpenArchive, ErrSpec{Code: "dt.failed_to_open_archive", Category: ErrCategoryInternal}},
+	{ErrFailedToCreateArchive, ErrSpec{Code: "dt.failed_to_create_archive", Category: ErrCategoryInternal}},
+	{ErrFailedToExtractFS, ErrSpec{Code: "dt.failed_to_extract_fs", Category: ErrCategoryInternal}},
+	{ErrInvalidExtractManifest, ErrSpec{Code: "dt.invalid_extract_manifest", Category: ErrCategoryInvalidInput}},
+	{ErrUnsupportedArchiveFormat, ErrSpec{Code: "dt.unsupported_archive_format", Category: ErrCategoryInvalidInput}},
+	{ErrArchiveEntryEscapes, ErrSpec{Code: "dt.archive_entry_escapes", Category: ErrCategoryInvalidInput}},
+	{ErrArchiveTooLarge, ErrSpec{Code: "dt.archive_too_large", Category: ErrCategoryInvalidInput, HTTPStatus: 413}},
+	{ErrArchiveTooManyEntries, ErrSpec{Code: "dt.archive_too_many_entries", Category: ErrCategoryInvalidInput, HTTPStatus: 413}},
+	{ErrValueIsNil, ErrSpec{Code: "dt.value_is_nil", Category: ErrCategoryInternal}},
+	{ErrInterfaceValueIsNil, ErrSpec{Code: "dt.interface_value_is_nil", Category: ErrCategoryInternal}},
+	{ErrConnectFailed, ErrSpec{Code: "dt.connect_failed", Category: ErrCategoryInternal, HTTPStatus: 503, ExitCode: 69}},
+	{ErrInvalidConnectString, ErrSpec{Code: "dt.invalid_connect_string", Category: ErrCategoryInvalidInput, ExitCode: 78}},
+	{ErrFailedToPingDatabase, ErrSpec{Code: "dt.failed_to_ping_database", Category: ErrCategoryInternal, HTTPStatus: 503, ExitCode: 69}},
+	{ErrFailedToOpenDatabase, ErrSpec{Code: "dt.failed_to_open_database", Category: ErrCategoryInternal}},
+	{ErrFailedToExecuteQueries, ErrSpec{Code: "dt.failed_to_execute_queries", Category: ErrCategoryInternal}},
+	{ErrFlagIsRequired, ErrSpec{Code: "dt.flag_is_required", Category: ErrCategoryInvalidInput, ExitCode: 64}},
+	{ErrInvalidDuplicateFlag, ErrSpec{Code: "dt.invalid_duplicate_flag", Category: ErrCategoryInvalidInput, ExitCode: 64}},
+	{ErrInvalidFlagName, ErrSpec{Code: "dt.invalid_flag_name", Category: ErrCategoryInvalidInput, ExitCode: 64}},
+	{ErrFlagValidationFailed, ErrSpec{Code: "dt.flag_validation_failed", Category: ErrCategoryInvalidInput, ExitCode: 64}},
+	{ErrUnexpectedError, ErrSpec{Code: "dt.unexpected_error", Category: ErrCategoryInternal}},
+	{ErrInternalError, ErrSpec{Code: "dt.internal_error", Category: ErrCategoryInternal}},
+	{ErrNotImplemented, ErrSpec{Code: "dt.not_implemented", Category: ErrCategoryInternal, HTTPStatus: 501}},
+	{ErrAccessingWorkingDir, ErrSpec{Code: "dt.accessing_working_dir", Category: ErrCategoryInternal}},
+	{ErrAccessingUserConfigDir, ErrSpec{Code: "dt.accessing_user_config_dir", Category: ErrCategoryInternal}},
+	{ErrAccessingCLIConfigDir, ErrSpec{Code: "dt.accessing_cli_config_dir", Category: ErrCategoryInternal}},
+	{ErrAccessingUserHomeDir, ErrSpec{Code: "dt.accessing_user_home_dir", Category: ErrCategoryInternal}},
+	{ErrAccessingUserCacheDir, ErrSpec{Code: "dt.accessing_user_cache_dir", Category: ErrCategoryInternal}},
+	{ErrUndefinedEnvVar, ErrSpec{Code: "dt.undefined_env_var", Category: ErrCategoryInvalidInput, ExitCode: 78}},
+	{ErrInvalidEnvVar, ErrSpec{Code: "dt.invalid_env_var", Category: ErrCategoryInvalidInput}},
+	{ErrInvalidPathTemplate, ErrSpec{Code: "dt.invalid_path_template", Category: ErrCategoryInvalidInput}},
+	{ErrUnknownPlaceholder, ErrSpec{Code: "dt.unknown_placeholder", Category: ErrCategoryInvalidInput}},
+	{ErrMissingPathVar, ErrSpec{Code: "dt.missing_path_var", Category: ErrCategoryInvalidInput}},
+	{ErrInvalidPathVar, ErrSpec{Code: "dt.invalid_path_var", Category: ErrCategoryInvalidInput}},
+	{ErrPathTemplateMismatch, ErrSpec{Code: "dt.path_template_mismatch", Category: ErrCategoryInvalidInput}},
+	{ErrNotTildePath, ErrSpec{Code: "dt.not_tilde_path", Category: ErrCategoryInvalidInput}},
+	{ErrUnknownUser, ErrSpec{Code: "dt.unknown_user", Category: ErrCategoryNotFound}},
+	{ErrInvalidPathSeparator, ErrSpec{Code: "dt.invalid_path_separator", Category: ErrCategoryInvalidInput}},
+	{ErrInvalidErrTemplate, ErrSpec{Code: "dt.invalid_err_template", Category: ErrCategoryInvalidInput}},
+	{ErrFailedToUnmarshalJSON, ErrSpec{Code: "dt.failed_to_unmarshal_json", Category: ErrCategoryInvalidInput}},
+	{ErrFailedToMarshalJSON, ErrSpec{Code: "dt.failed_to_marshal_json", Category: ErrCategoryInternal}},
+	{ErrMissingSentinel, ErrSpec{Code: "dt.missing_sentinel", Category: ErrCategoryInternal}},
+	{ErrTrailingKey, ErrSpec{Code: "dt.trailing_key", Category: ErrCategoryInternal}},
+	{ErrMisplacedError, ErrSpec{Code: "dt.misplaced_error", Category: ErrCategoryInternal}},
+	{ErrInvalidArgumentType, ErrSpec{Code: "dt.invalid_argument_type", Category: ErrCategoryInternal}},
+	{ErrOddKeyValueCount, ErrSpec{Code: "dt.odd_key_value_count", Category: ErrCategoryInternal}},
+	{ErrCrossPackageError, ErrSpec{Code: "dt.cross_package_error", Category: ErrCategoryInternal}},
+}
+
+var _ = func() bool {
+	for _, s := range dtErrSpecs {
+		RegisterErr(s.sentinel, s.spec)
+	}
+	return true
+}()
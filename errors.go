@@ -48,7 +48,7 @@ var (
 	ErrFailedtoCreateFile              = errors.New("failed to create file")
 	ErrFailedtoRemoveFile              = errors.New("failed to remove file")
 	ErrFailedtoCreateDir               = errors.New("failed to create directory")
-	ErrContainsBackslash               = errors.New("contains slash ('\\')")
+	ErrContainsBackslash               = errors.New("contains backslash ('\\')")
 	ErrContainsSlash                   = errors.New("contains slash ('/')")
 	ErrEmpty                           = errors.New("cannot be empty")
 	ErrInvalidPercentEncoding          = errors.New("invalid percent encoding")
@@ -59,7 +59,7 @@ var (
 	ErrInvalidDirectory                = errors.New("invalid directory")
 	ErrInvalidfileSystemEntryType      = errors.New("invalid file system entry type")
 	ErrControlCharacter                = errors.New("control character")
-	ErrInvalidCharacter                = errors.New("invalid charnacter")
+	ErrInvalidCharacter                = errors.New("invalid character")
 	ErrTrailingSpace                   = errors.New("trailing space")
 	ErrTrailingPeriod                  = errors.New("trailing period")
 	ErrReservedDeviceName              = errors.New("reserved device name")
@@ -71,6 +71,17 @@ var (
 	ErrFailedToExpandPath              = errors.New("failed to expand path")
 	ErrFailedToEnsureDir               = errors.New("failed to ensure directory")
 )
+var (
+	ErrFailedToExtractArchive   = errors.New("failed to extract archive")
+	ErrFailedToOpenArchive      = errors.New("failed to open archive")
+	ErrFailedToCreateArchive    = errors.New("failed to create archive")
+	ErrFailedToExtractFS        = errors.New("failed to extract file system")
+	ErrInvalidExtractManifest   = errors.New("invalid extract manifest")
+	ErrUnsupportedArchiveFormat = errors.New("unsupported archive format")
+	ErrArchiveEntryEscapes      = errors.New("archive entry escapes destination")
+	ErrArchiveTooLarge          = errors.New("archive exceeds maximum extracted size")
+	ErrArchiveTooManyEntries    = errors.New("archive exceeds maximum number of entries")
+)
 var (
 	ErrValueIsNil          = errors.New("value is nil")
 	ErrInterfaceValueIsNil = errors.New("interface value is nil")
@@ -104,6 +115,22 @@ var (
 )
 
 var ErrNotTildePath = errors.New("not a tilde-prefixed path")
+var ErrUnknownUser = errors.New("unknown user")
+
+var (
+	ErrUndefinedEnvVar = errors.New("undefined environment variable")
+	ErrInvalidEnvVar   = errors.New("invalid environment variable reference")
+)
+
+var (
+	ErrInvalidPathTemplate  = errors.New("invalid path template")
+	ErrUnknownPlaceholder   = errors.New("unknown path template placeholder")
+	ErrMissingPathVar       = errors.New("missing path template variable")
+	ErrInvalidPathVar       = errors.New("invalid path template variable")
+	ErrPathTemplateMismatch = errors.New("path does not match template")
+)
+
 var ErrInvalidPathSeparator = errors.New("invalid path separator")
+var ErrInvalidErrTemplate = errors.New("invalid error message template")
 var ErrFailedToUnmarshalJSON = errors.New("failed to unmarshal JSON")
 var ErrFailedToMarshalJSON = errors.New("failed to marshal JSON")
@@ -0,0 +1,340 @@
+package dt
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"math/rand/v2"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// DefaultExtractManifestName is the name of the checksum manifest ExtractFS
+// writes into the destination directory.
+const DefaultExtractManifestName Filename = ".dt-manifest.sha256"
+
+// ExtractOutcome is what ExtractFS did with one file.
+type ExtractOutcome uint8
+
+const (
+	// ExtractCreated means the file did not exist and was written.
+	ExtractCreated ExtractOutcome = iota + 1
+
+	// ExtractUpdated means the file was unmodified since it was last
+	// extracted and was replaced by the new contents.
+	ExtractUpdated
+
+	// ExtractUnchanged means the file already had the new contents.
+	ExtractUnchanged
+
+	// ExtractKeptModified means the file was modified by the user, or was not
+	// written by ExtractFS, and was left alone.
+	ExtractKeptModified
+
+	// ExtractRemoved means the file is no longer in the source, was
+	// unmodified, and was removed.
+	ExtractRemoved
+)
+
+func (o ExtractOutcome) String() (s string) {
+	switch o {
+	case ExtractCreated:
+		s = "created"
+	case ExtractUpdated:
+		s = "updated"
+	case ExtractUnchanged:
+		s = "unchanged"
+	case ExtractKeptModified:
+		s = "kept-modified"
+	case ExtractRemoved:
+		s = "removed"
+	default:
+		s = "unknown"
+	}
+	return s
+}
+
+// ExtractResult reports the outcome for one file, relative to the destination.
+type ExtractResult struct {
+	File    RelFilepath
+	Outcome ExtractOutcome
+}
+
+// ExtractFSOptions controls ExtractFS.
+type ExtractFSOptions struct {
+	// ManifestName is the name of the checksum manifest in the destination.
+	// Empty means DefaultExtractManifestName.
+	ManifestName Filename
+
+	// CopyOptions.Overwrite replaces user-modified files too, though they are
+	// never removed, and DestModeFunc, when set, chooses the mode of written
+	// files and directories in place of 0644 and 0755.
+	CopyOptions
+}
+
+// ExtractFS copies the regular files in src, such as an embed.FS of default
+// configuration, into dest without clobbering the user's changes.
+//
+// The SHA-256 of every file written is recorded in a manifest in dest, in the
+// format of sha256sum. On later runs a file whose checksum still matches the
+// manifest is unmodified and is updated to the new contents; a file that
+// differs, or that exists but is not in the manifest, is kept. Unmodified
+// files that are no longer in src are removed.
+//
+// ExtractFS carries on past errors for individual files, returning their
+// combined errors along with the results for every file it handled.
+func ExtractFS(src fs.FS, dest DirPath, opts *ExtractFSOptions) (results []ExtractResult, err error) {
+	var x *fsExtractor
+	var errs []error
+
+	if opts == nil {
+		opts = &ExtractFSOptions{}
+	}
+	err = dest.MkdirAll(0o755)
+	if err != nil {
+		goto end
+	}
+	x = &fsExtractor{dest: dest, opts: opts, seen: make(map[string]bool)}
+	x.root, err = dest.OpenRoot()
+	if err != nil {
+		goto end
+	}
+	defer CloseOrLog(x.root)
+
+	x.manifest, err = x.readManifest()
+	if err != nil {
+		goto end
+	}
+	err = fs.WalkDir(src, ".", func(name string, d fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			errs = AppendErr(errs, WithErr(err, "source", name))
+		case name == string(x.manifestName()) || !d.Type().IsRegular():
+		default:
+			errs = AppendErr(errs, x.extract(src, name))
+		}
+		return nil
+	})
+	errs = AppendErr(errs, err)
+	errs = AppendErr(errs, x.removeStale())
+	errs = AppendErr(errs, x.writeManifest())
+	err = CombineErrs(errs)
+	results = x.results
+end:
+	if err != nil {
+		err = NewErr(ErrFailedToExtractFS, dest.ErrKV(), err)
+	}
+	return results, err
+}
+
+// fsExtractor holds the state of one ExtractFS call.
+type fsExtractor struct {
+	root     *DirRoot
+	dest     DirPath
+	opts     *ExtractFSOptions
+	manifest map[string]string
+	seen     map[string]bool
+	results  []ExtractResult
+}
+
+func (x *fsExtractor) manifestName() Filename {
+	if x.opts.ManifestName == "" {
+		return DefaultExtractManifestName
+	}
+	return x.opts.ManifestName
+}
+
+func (x *fsExtractor) report(name string, outcome ExtractOutcome) {
+	x.results = append(x.results, ExtractResult{File: RelFilepath(name), Outcome: outcome})
+}
+
+// mode returns the mode for the destination name.
+func (x *fsExtractor) mode(name string, mode fs.FileMode) fs.FileMode {
+	if x.opts.DestModeFunc != nil {
+		m := x.opts.DestModeFunc(EntryPath(FilepathJoin(x.dest, name)))
+		if m != 0 {
+			mode = m
+		}
+	}
+	return mode.Perm()
+}
+
+// extract handles the file name in src.
+func (x *fsExtractor) extract(src fs.FS, name string) (err error) {
+	var data []byte
+	var current []byte
+	var sum string
+	var recorded string
+	var known bool
+	var outcome ExtractOutcome
+
+	x.seen[name] = true
+	data, err = fs.ReadFile(src, name)
+	if err != nil {
+		goto end
+	}
+	sum = sha256Hex(data)
+	recorded, known = x.manifest[name]
+
+	current, err = x.root.ReadFile(RelFilepath(name))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		outcome = ExtractCreated
+	case err != nil:
+		goto end
+	case sha256Hex(current) == sum:
+		outcome = ExtractUnchanged
+	case known && sha256Hex(current) == recorded, x.opts.Overwrite:
+		outcome = ExtractUpdated
+	default:
+		outcome = ExtractKeptModified
+	}
+	err = nil
+	if outcome == ExtractCreated || outcome == ExtractUpdated {
+		err = x.write(name, data)
+		if err != nil {
+			goto end
+		}
+	}
+	if outcome != ExtractKeptModified {
+		x.manifest[name] = sum
+	}
+	x.report(name, outcome)
+end:
+	if err != nil {
+		err = WithErr(err, "file", name)
+	}
+	return err
+}
+
+func (x *fsExtractor) write(name string, data []byte) (err error) {
+	var mode fs.FileMode
+	var f *os.File
+	var tmp RelFilepath
+
+	dir := path.Dir(name)
+	if dir != "." {
+		err = x.root.MkdirAll(PathSegments(dir), x.mode(dir, fs.ModeDir|0o755))
+		if err != nil {
+			goto end
+		}
+	}
+	mode = x.mode(name, 0o644)
+	f, tmp, err = x.createTemp(name, mode)
+	if err != nil {
+		goto end
+	}
+	_, err = f.Write(data)
+	err = CombineErrs([]error{err, f.Close()})
+	if err == nil {
+		err = x.root.Chmod(RelPath(tmp), mode)
+	}
+	if err == nil {
+		err = x.root.Rename(RelPath(tmp), RelPath(name))
+	}
+	if err != nil {
+		err = CombineErrs([]error{err, x.root.Remove(RelPath(tmp))})
+	}
+end:
+	return err
+}
+
+// createTemp creates a new, uniquely named file beside name, like
+// os.CreateTemp but through the root, so that no existing file is clobbered.
+func (x *fsExtractor) createTemp(name string, mode fs.FileMode) (f *os.File, tmp RelFilepath, err error) {
+	for range 10000 {
+		tmp = RelFilepath(fmt.Sprintf("%s.%d.tmp~", name, rand.Uint32()))
+		f, err = x.root.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+		if !errors.Is(err, fs.ErrExist) {
+			break
+		}
+	}
+	return f, tmp, err
+}
+
+// removeStale removes unmodified files that are in the manifest but no longer
+// in the source.
+func (x *fsExtractor) removeStale() (err error) {
+	var errs []error
+	var current []byte
+
+	for _, name := range slices.Sorted(maps.Keys(x.manifest)) {
+		if x.seen[name] {
+			continue
+		}
+		current, err = x.root.ReadFile(RelFilepath(name))
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			errs = AppendErr(errs, WithErr(err, "file", name))
+			continue
+		case sha256Hex(current) != x.manifest[name]:
+			x.report(name, ExtractKeptModified)
+		default:
+			err = x.root.Remove(RelPath(name))
+			if err != nil {
+				errs = AppendErr(errs, WithErr(err, "file", name))
+				continue
+			}
+			x.report(name, ExtractRemoved)
+		}
+		delete(x.manifest, name)
+	}
+	return CombineErrs(errs)
+}
+
+// readManifest loads the manifest, which is empty on the first run.
+func (x *fsExtractor) readManifest() (manifest map[string]string, err error) {
+	var data []byte
+	var line int
+
+	manifest = make(map[string]string)
+	data, err = x.root.ReadFile(RelFilepath(x.manifestName()))
+	if errors.Is(err, fs.ErrNotExist) {
+		err = nil
+		goto end
+	}
+	if err != nil {
+		goto end
+	}
+	for scanner := bufio.NewScanner(bytes.NewReader(data)); scanner.Scan(); {
+		line++
+		sum, name, ok := strings.Cut(scanner.Text(), "  ")
+		if !ok || len(sum) != sha256.Size*2 || !fs.ValidPath(name) {
+			err = NewErr(ErrInvalidExtractManifest, "line", line)
+			goto end
+		}
+		manifest[name] = sum
+	}
+end:
+	if err != nil {
+		err = WithErr(err, "manifest", x.manifestName())
+	}
+	return manifest, err
+}
+
+// writeManifest saves the manifest, sorted by name.
+func (x *fsExtractor) writeManifest() (err error) {
+	var buf bytes.Buffer
+
+	for _, name := range slices.Sorted(maps.Keys(x.manifest)) {
+		_, _ = fmt.Fprintf(&buf, "%s  %s\n", x.manifest[name], name)
+	}
+	err = x.write(string(x.manifestName()), buf.Bytes())
+	if err != nil {
+		err = WithErr(err, "manifest", x.manifestName())
+	}
+	return err
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
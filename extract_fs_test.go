@@ -0,0 +1,143 @@
+package dt_test
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+func TestExtractFS(t *testing.T) {
+	if err := dt.EnsureUserHomeDir(); err != nil {
+		t.Fatalf("EnsureUserHomeDir() error = %v", err)
+	}
+	dest := dt.DirPath(t.TempDir())
+	outcomes := func(results []dt.ExtractResult) map[dt.RelFilepath]dt.ExtractOutcome {
+		m := make(map[dt.RelFilepath]dt.ExtractOutcome, len(results))
+		for _, r := range results {
+			m[r.File] = r.Outcome
+		}
+		return m
+	}
+	check := func(t *testing.T, got map[dt.RelFilepath]dt.ExtractOutcome, want map[dt.RelFilepath]dt.ExtractOutcome) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Errorf("results = %v, want %v", got, want)
+		}
+		for file, outcome := range want {
+			if got[file] != outcome {
+				t.Errorf("outcome for %s = %v, want %v", file, got[file], outcome)
+			}
+		}
+	}
+	read := func(name string) string {
+		data, _ := dt.FilepathJoin(dest, name).ReadFile()
+		return string(data)
+	}
+
+	v1 := fstest.MapFS{
+		"config.toml":        {Data: []byte("v1 config")},
+		"templates/a.tmpl":   {Data: []byte("v1 a")},
+		"templates/b.tmpl":   {Data: []byte("v1 b")},
+		"templates/old.tmpl": {Data: []byte("v1 old")},
+	}
+	results, err := dt.ExtractFS(v1, dest, nil)
+	if err != nil {
+		t.Fatalf("ExtractFS(v1) error = %v", err)
+	}
+	check(t, outcomes(results), map[dt.RelFilepath]dt.ExtractOutcome{
+		"config.toml":        dt.ExtractCreated,
+		"templates/a.tmpl":   dt.ExtractCreated,
+		"templates/b.tmpl":   dt.ExtractCreated,
+		"templates/old.tmpl": dt.ExtractCreated,
+	})
+
+	// The user edits the config; a.tmpl and old.tmpl are left alone.
+	if err = dt.FilepathJoin(dest, "config.toml").WriteFile([]byte("mine"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	v2 := fstest.MapFS{
+		"config.toml":      {Data: []byte("v2 config")},
+		"templates/a.tmpl": {Data: []byte("v2 a")},
+		"templates/b.tmpl": {Data: []byte("v1 b")},
+		"templates/c.tmpl": {Data: []byte("v2 c")},
+	}
+	results, err = dt.ExtractFS(v2, dest, nil)
+	if err != nil {
+		t.Fatalf("ExtractFS(v2) error = %v", err)
+	}
+	check(t, outcomes(results), map[dt.RelFilepath]dt.ExtractOutcome{
+		"config.toml":        dt.ExtractKeptModified,
+		"templates/a.tmpl":   dt.ExtractUpdated,
+		"templates/b.tmpl":   dt.ExtractUnchanged,
+		"templates/c.tmpl":   dt.ExtractCreated,
+		"templates/old.tmpl": dt.ExtractRemoved,
+	})
+	if got := read("config.toml"); got != "mine" {
+		t.Errorf("config.toml = %q, want %q", got, "mine")
+	}
+	if got := read("templates/a.tmpl"); got != "v2 a" {
+		t.Errorf("a.tmpl = %q, want %q", got, "v2 a")
+	}
+	if exists, _ := dt.FilepathJoin(dest, "templates/old.tmpl").Exists(); exists {
+		t.Error("old.tmpl still exists")
+	}
+
+	// The config stays user-modified until Overwrite is set.
+	results, err = dt.ExtractFS(v2, dest, nil)
+	if err != nil {
+		t.Fatalf("ExtractFS(v2) again error = %v", err)
+	}
+	if got := outcomes(results)["config.toml"]; got != dt.ExtractKeptModified {
+		t.Errorf("outcome for config.toml = %v, want %v", got, dt.ExtractKeptModified)
+	}
+	opts := &dt.ExtractFSOptions{CopyOptions: dt.CopyOptions{Overwrite: true}}
+	results, err = dt.ExtractFS(v2, dest, opts)
+	if err != nil {
+		t.Fatalf("ExtractFS(v2, Overwrite) error = %v", err)
+	}
+	if got := outcomes(results)["config.toml"]; got != dt.ExtractUpdated {
+		t.Errorf("outcome for config.toml = %v, want %v", got, dt.ExtractUpdated)
+	}
+	if got := read("config.toml"); got != "v2 config" {
+		t.Errorf("config.toml = %q, want %q", got, "v2 config")
+	}
+
+	t.Run("invalid manifest", func(t *testing.T) {
+		dir := dt.DirPath(t.TempDir())
+		err := dt.FilepathJoin(dir, dt.DefaultExtractManifestName).WriteFile([]byte("garbage\n"), 0o644)
+		if err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		_, err = dt.ExtractFS(v1, dir, nil)
+		if !errors.Is(err, dt.ErrInvalidExtractManifest) {
+			t.Errorf("ExtractFS() error = %v, want %v", err, dt.ErrInvalidExtractManifest)
+		}
+	})
+	t.Run("temp file names", func(t *testing.T) {
+		dir := dt.DirPath(t.TempDir())
+		names := []dt.Filepath{
+			dt.FilepathJoin(dir, "config.toml.tmp~"),
+			dt.FilepathJoin(dir, string(dt.DefaultExtractManifestName)+".tmp~"),
+		}
+		for _, fp := range names {
+			if err := fp.WriteFile([]byte("keep"), 0o644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+		}
+		if _, err := dt.ExtractFS(v1, dir, nil); err != nil {
+			t.Fatalf("ExtractFS() error = %v", err)
+		}
+		for _, fp := range names {
+			if got, err := fp.ReadFile(); err != nil || string(got) != "keep" {
+				t.Errorf("ReadFile(%s) = %q, %v; want %q", fp, got, err, "keep")
+			}
+		}
+		entries, err := dir.ReadDir()
+		if err != nil || len(entries) != 5 {
+			t.Errorf("ReadDir() = %d entries, %v; want 5", len(entries), err)
+		}
+	})
+}
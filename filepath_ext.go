@@ -1,6 +1,8 @@
 package dt
 
 import (
+	"io"
+	"io/fs"
 	"os"
 )
 
@@ -48,9 +50,10 @@ end:
 }
 
 // CopyTo copies the file to the destination filepath with optional permission
-// control
-func (fp Filepath) CopyTo(dest Filepath, opts *CopyOptions) (err error) {
-	var srcFile *os.File
+// control. When fileSys is given the source is read from it rather than from
+// the OS filesystem, e.g. from an archive opened with OpenArchiveFS.
+func (fp Filepath) CopyTo(dest Filepath, opts *CopyOptions, fileSys ...fs.FS) (err error) {
+	var srcFile fs.File
 	var destFile *os.File
 	var srcInfo os.FileInfo
 	var destMode os.FileMode
@@ -62,7 +65,7 @@ func (fp Filepath) CopyTo(dest Filepath, opts *CopyOptions) (err error) {
 	}
 
 	// Read source file info
-	srcInfo, err = fp.Stat()
+	srcInfo, err = fp.Stat(fileSys...)
 	if err != nil {
 		goto end
 	}
@@ -101,7 +104,11 @@ func (fp Filepath) CopyTo(dest Filepath, opts *CopyOptions) (err error) {
 	}
 
 	// Open source file
-	srcFile, err = fp.Open()
+	if len(fileSys) == 0 {
+		srcFile, err = fp.Open()
+	} else {
+		srcFile, err = fileSys[0].Open(fsName(fp))
+	}
 	if err != nil {
 		goto end
 	}
@@ -115,7 +122,7 @@ func (fp Filepath) CopyTo(dest Filepath, opts *CopyOptions) (err error) {
 	defer CloseOrLog(destFile)
 
 	// Copy contents
-	_, err = srcFile.WriteTo(destFile)
+	_, err = io.Copy(destFile, srcFile)
 	if err != nil {
 		goto end
 	}
@@ -128,14 +135,24 @@ func (fp Filepath) HasDotDotPrefix() bool {
 	return EntryPath(fp).HasDotDotPrefix()
 }
 
-func (fp Filepath) Expand() (_ Filepath, err error) {
+func (fp Filepath) Expand(opts ...ExpandOptions) (_ Filepath, err error) {
+	var ep EntryPath
+	ep, err = EntryPath(fp).Expand(opts...)
+	return Filepath(ep), err
+}
+
+func (fp Filepath) ExpandEnv(opts ExpandOptions) (_ Filepath, err error) {
 	var ep EntryPath
-	ep, err = EntryPath(fp).Expand()
+	ep, err = EntryPath(fp).ExpandEnv(opts)
 	return Filepath(ep), err
 }
 
-func (fp Filepath) ToTilde(opt TildeOption) TildeFilepath {
-	return ToTilde[Filepath, TildeFilepath](fp, opt)
+func (fp Filepath) Collapse(names []string, opts ...ExpandOptions) Filepath {
+	return Filepath(EntryPath(fp).Collapse(names, opts...))
+}
+
+func (fp Filepath) ToTilde(opt TildeOption, hdps ...HomeDirProvider) TildeFilepath {
+	return ToTilde[Filepath, TildeFilepath](fp, opt, hdps...)
 }
 
 func (fp Filepath) TrimTilde() (tdp PathSegments) {
@@ -153,3 +170,117 @@ func (fp Filepath) Touch(mode os.FileMode) (err error) {
 	}
 	return err
 }
+
+// OpenFileFS opens fp within wfs with the given flag and mode, like OpenFile.
+func (fp Filepath) OpenFileFS(wfs WritableFS, flag int, mode os.FileMode) (WritableFile, error) {
+	return wfs.OpenFile(fsName(fp), flag, mode)
+}
+
+// CreateFS creates or truncates fp within wfs, like Create.
+func (fp Filepath) CreateFS(wfs WritableFS) (WritableFile, error) {
+	return fp.OpenFileFS(wfs, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+// WriteFileFS writes data to fp within wfs, like WriteFile.
+func (fp Filepath) WriteFileFS(wfs WritableFS, data []byte, mode os.FileMode) error {
+	return WriteFileFS(wfs, fsName(fp), data, mode)
+}
+
+// RemoveFS removes fp from wfs, like Remove.
+func (fp Filepath) RemoveFS(wfs WritableFS) error {
+	return wfs.Remove(fsName(fp))
+}
+
+// RenameFS renames fp to newFile within wfs, like Rename.
+func (fp Filepath) RenameFS(wfs WritableFS, newFile Filepath) error {
+	return wfs.Rename(fsName(fp), fsName(newFile))
+}
+
+// ChmodFS changes the mode of fp within wfs.
+func (fp Filepath) ChmodFS(wfs WritableFS, mode os.FileMode) error {
+	return wfs.Chmod(fsName(fp), mode)
+}
+
+// TouchFS creates fp as an empty file within wfs, like Touch.
+func (fp Filepath) TouchFS(wfs WritableFS, mode os.FileMode) (err error) {
+	err = fp.WriteFileFS(wfs, []byte{}, mode)
+	if err != nil {
+		err = NewErr(ErrFailedtoCreateFile, fp.ErrKV(), err)
+	}
+	return err
+}
+
+// CopyToFS copies fp to dest where both are within wfs, with the same
+// semantics as CopyTo.
+func (fp Filepath) CopyToFS(wfs WritableFS, dest Filepath, opts *CopyOptions) (err error) {
+	var srcFile fs.File
+	var destFile WritableFile
+	var srcInfo os.FileInfo
+	var destMode os.FileMode
+	var destExists bool
+
+	// Normalize opts
+	if opts == nil {
+		opts = new(CopyOptions)
+	}
+
+	// Read source file info
+	srcInfo, err = fp.Stat(wfs)
+	if err != nil {
+		goto end
+	}
+
+	if srcInfo.IsDir() {
+		err = NewErr(ErrIsADirectory)
+		goto end
+	}
+
+	// Check if destination exists
+	_, err = dest.Stat(wfs)
+	destExists = err == nil
+
+	// If dest exists and Overwrite is false, error
+	if destExists && !opts.Overwrite {
+		err = os.ErrExist
+		goto end
+	}
+
+	// Determine destination permissions
+	destMode = srcInfo.Mode()
+	if opts.DestModeFunc != nil {
+		destMode = opts.DestModeFunc(EntryPath(dest))
+		if destMode == 0 {
+			// 0 means preserve source permissions
+			destMode = srcInfo.Mode()
+		}
+	}
+
+	// Create parent directory if needed
+	err = dest.Dir().MkdirAllFS(wfs, 0755)
+	if err != nil {
+		goto end
+	}
+
+	// Open source file
+	srcFile, err = wfs.Open(fsName(fp))
+	if err != nil {
+		goto end
+	}
+	defer CloseOrLog(srcFile)
+
+	// Create destination file
+	destFile, err = dest.OpenFileFS(wfs, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, destMode)
+	if err != nil {
+		goto end
+	}
+	defer CloseOrLog(destFile)
+
+	// Copy contents
+	_, err = io.Copy(destFile, srcFile)
+	if err != nil {
+		goto end
+	}
+
+end:
+	return err
+}
@@ -22,8 +22,10 @@ func RemoveAll(dp DirPath) error {
 	return os.RemoveAll(string(dp))
 }
 
+// UserHomeDir returns the current user's home directory from the package-wide
+// HomeDirProvider, which defaults to os.UserHomeDir.
 func UserHomeDir() (DirPath, error) {
-	dp, err := os.UserHomeDir()
+	dp, err := GetHomeDirProvider().HomeDir()
 	if err != nil {
 		err = NewErr(ErrAccessingUserHomeDir, err)
 	}
@@ -0,0 +1,317 @@
+package dt
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BuiltinPathVars are the placeholders every PathTemplate accepts. Their
+// values come from the application; see appinfo.PathVars.
+var BuiltinPathVars = []string{"config", "cache", "home", "tmp", "app"}
+
+// DefaultPathTimeLayout formats time.Time values for placeholders that do not
+// give a layout.
+const DefaultPathTimeLayout = "2006-01-02"
+
+// PathVars holds the values for the placeholders of a PathTemplate. Values
+// may be strings or string-based types such as DirPath, time.Time,
+// fmt.Stringers or integers.
+type PathVars map[string]any
+
+// PathTemplate is a parsed path pattern with named placeholders, such as
+// "{config}/{app}/logs/{date:2006-01-02}.log". A placeholder may give a Go
+// time layout after a colon for time.Time values. "{{" and "}}" stand for
+// literal braces. Templates are written with "/" and rendered with the OS
+// separator.
+type PathTemplate struct {
+	raw   string
+	parts []templatePart
+
+	// patterns caches the compiled Match patterns by their source, which
+	// differs only in the values of the known placeholders.
+	mu       sync.Mutex
+	patterns map[string]*regexp.Regexp
+}
+
+// maxPathTemplatePatterns bounds the Match patterns a PathTemplate caches.
+const maxPathTemplatePatterns = 16
+
+// templatePart is a literal, when name is empty, or a placeholder.
+type templatePart struct {
+	literal string
+	name    string
+	layout  string
+}
+
+// ParsePathTemplate parses s, accepting placeholders named in vars or in
+// BuiltinPathVars; any other placeholder fails with ErrUnknownPlaceholder.
+func ParsePathTemplate(s string, vars ...string) (pt *PathTemplate, err error) {
+	var parts []templatePart
+	var lit strings.Builder
+	var name, layout string
+	var end int
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "{{"), strings.HasPrefix(s[i:], "}}"):
+			lit.WriteByte(s[i])
+			i++
+		case s[i] == '}':
+			err = NewErr(ErrInvalidPathTemplate, "template", s, "position", i)
+			goto end
+		case s[i] == '{':
+			end = strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				err = NewErr(ErrInvalidPathTemplate, "template", s, "position", i)
+				goto end
+			}
+			name, layout, _ = strings.Cut(s[i+1:i+end], ":")
+			if name == "" || envNameLen(name) != len(name) || !isEnvNameStart(name[0]) {
+				err = NewErr(ErrInvalidPathTemplate, "template", s, "position", i)
+				goto end
+			}
+			if !slices.Contains(vars, name) && !slices.Contains(BuiltinPathVars, name) {
+				err = NewErr(ErrUnknownPlaceholder, "template", s, "placeholder", name)
+				goto end
+			}
+			if lit.Len() > 0 {
+				parts = append(parts, templatePart{literal: lit.String()})
+				lit.Reset()
+			}
+			parts = append(parts, templatePart{name: name, layout: layout})
+			i += end
+		default:
+			lit.WriteByte(s[i])
+		}
+	}
+	if lit.Len() > 0 {
+		parts = append(parts, templatePart{literal: lit.String()})
+	}
+	pt = &PathTemplate{raw: s, parts: parts}
+	// Compile the pattern for matching without known values now.
+	_, _, err = pt.pattern(nil)
+end:
+	return pt, err
+}
+
+// String returns the template as it was parsed.
+func (pt *PathTemplate) String() string {
+	return pt.raw
+}
+
+// Placeholders returns the names of the placeholders in order of appearance,
+// without duplicates.
+func (pt *PathTemplate) Placeholders() (names []string) {
+	for _, p := range pt.parts {
+		if p.name != "" && !slices.Contains(names, p.name) {
+			names = append(names, p.name)
+		}
+	}
+	return names
+}
+
+// Render substitutes vars into the template. Every placeholder must have a
+// value, else it fails with ErrMissingPathVar. Only a placeholder that starts
+// the template, such as {config}, may render to several path segments; other
+// values containing a separator, or that are "..", fail with
+// ErrInvalidPathVar so that they cannot escape the template's directory.
+func (pt *PathTemplate) Render(vars PathVars) (ep EntryPath, err error) {
+	var sb strings.Builder
+	var s string
+
+	for i, p := range pt.parts {
+		if p.name == "" {
+			sb.WriteString(filepath.FromSlash(p.literal))
+			continue
+		}
+		s, err = pt.formatVar(i, vars)
+		if err != nil {
+			err = WithErr(err, "template", pt.raw)
+			goto end
+		}
+		sb.WriteString(s)
+	}
+	ep = EntryPath(filepath.Clean(sb.String()))
+end:
+	return ep, err
+}
+
+// Filepath renders the template as a Filepath.
+func (pt *PathTemplate) Filepath(vars PathVars) (Filepath, error) {
+	ep, err := pt.Render(vars)
+	return Filepath(ep), err
+}
+
+// DirPath renders the template as a DirPath.
+func (pt *PathTemplate) DirPath(vars PathVars) (DirPath, error) {
+	ep, err := pt.Render(vars)
+	return DirPath(ep), err
+}
+
+// Match is the reverse of Render: it returns the placeholder values that
+// render to path, failing with ErrPathTemplateMismatch. Placeholders with a
+// value in known must render exactly to it; others match within a single
+// path segment and are returned as strings, or as time.Time when the
+// placeholder gives a layout.
+func (pt *PathTemplate) Match(path EntryPath, known PathVars) (vars PathVars, err error) {
+	var re *regexp.Regexp
+	var s string
+	var m []string
+	var names []templatePart
+	var raw map[string]string
+	var t time.Time
+
+	re, names, err = pt.pattern(known)
+	if err != nil {
+		goto end
+	}
+	m = re.FindStringSubmatch(filepath.Clean(string(path)))
+	if m == nil {
+		err = NewErr(ErrPathTemplateMismatch)
+		goto end
+	}
+	vars = make(PathVars, len(names)+len(known))
+	for k, v := range known {
+		vars[k] = v
+	}
+	raw = make(map[string]string, len(names))
+	for i, p := range names {
+		s = m[i+1]
+		if prev, ok := raw[p.name]; ok && prev != s {
+			// A repeated placeholder must match the same text each time.
+			err = NewErr(ErrPathTemplateMismatch, "placeholder", p.name)
+			goto end
+		}
+		raw[p.name] = s
+		vars[p.name] = s
+		if p.layout == "" {
+			continue
+		}
+		t, err = time.Parse(p.layout, s)
+		if err != nil {
+			err = NewErr(ErrPathTemplateMismatch, "placeholder", p.name, err)
+			goto end
+		}
+		vars[p.name] = t
+	}
+end:
+	if err != nil {
+		vars = nil
+		err = WithErr(err, "template", pt.raw, "path", path)
+	}
+	return vars, err
+}
+
+// pattern returns the compiled Match pattern for the placeholders with a
+// value in known, and the other placeholders in the order of its groups.
+func (pt *PathTemplate) pattern(known PathVars) (re *regexp.Regexp, names []templatePart, err error) {
+	var sb strings.Builder
+	var s string
+	var ok bool
+
+	sb.WriteString("^")
+	for i, p := range pt.parts {
+		if p.name == "" {
+			sb.WriteString(regexp.QuoteMeta(filepath.FromSlash(p.literal)))
+			continue
+		}
+		if _, ok = known[p.name]; ok {
+			s, err = pt.formatVar(i, known)
+			if err != nil {
+				goto end
+			}
+			sb.WriteString(regexp.QuoteMeta(filepath.Clean(s)))
+			continue
+		}
+		if p.layout != "" {
+			// Layouts may contain separators, and time.Parse checks the text.
+			sb.WriteString(`(.+?)`)
+		} else {
+			sb.WriteString(segmentPattern)
+		}
+		names = append(names, p)
+	}
+	sb.WriteString("$")
+
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	re, ok = pt.patterns[sb.String()]
+	if ok {
+		goto end
+	}
+	re = regexp.MustCompile(sb.String())
+	if pt.patterns == nil || len(pt.patterns) >= maxPathTemplatePatterns {
+		pt.patterns = make(map[string]*regexp.Regexp)
+	}
+	pt.patterns[sb.String()] = re
+end:
+	return re, names, err
+}
+
+// segmentPattern matches the text of a placeholder within one path segment.
+var segmentPattern = `([^` + regexp.QuoteMeta(`/`+string(filepath.Separator)) + `]+?)`
+
+// formatVar returns the text for the placeholder at index i of pt.parts,
+// which must stay within one path segment unless it starts the template or
+// is a time.Time formatted with the template's layout.
+func (pt *PathTemplate) formatVar(i int, vars PathVars) (s string, err error) {
+	p := pt.parts[i]
+	s, err = formatPathVar(p, vars)
+	if err != nil || i == 0 {
+		goto end
+	}
+	if _, isTime := vars[p.name].(time.Time); isTime {
+		goto end
+	}
+	if s == ".." || strings.ContainsAny(s, `/`+string(filepath.Separator)) {
+		err = NewErr(ErrInvalidPathVar, "placeholder", p.name, "value", s)
+	}
+end:
+	return s, err
+}
+
+// formatPathVar returns the text for the placeholder p.
+func formatPathVar(p templatePart, vars PathVars) (s string, err error) {
+	var v any
+	var ok bool
+	var rv reflect.Value
+
+	v, ok = vars[p.name]
+	if !ok {
+		err = NewErr(ErrMissingPathVar, "placeholder", p.name)
+		goto end
+	}
+	if t, isTime := v.(time.Time); isTime {
+		s = t.Format(cmp.Or(p.layout, DefaultPathTimeLayout))
+		goto end
+	}
+	if p.layout != "" {
+		err = NewErr(ErrInvalidPathVar, "placeholder", p.name, "type", fmt.Sprintf("%T", v))
+		goto end
+	}
+	if str, isStringer := v.(fmt.Stringer); isStringer {
+		s = str.String()
+		goto end
+	}
+	rv = reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		s = rv.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = strconv.FormatUint(rv.Uint(), 10)
+	default:
+		err = NewErr(ErrInvalidPathVar, "placeholder", p.name, "type", fmt.Sprintf("%T", v))
+	}
+end:
+	return s, err
+}
@@ -0,0 +1,164 @@
+package dt_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+func TestParsePathTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tmpl    string
+		vars    []string
+		want    []string
+		wantErr error
+	}{
+		{name: "builtins", tmpl: "{config}/{app}/app.json", want: []string{"config", "app"}},
+		{name: "declared", tmpl: "{cache}/{app}/{date:2006-01-02}.log", vars: []string{"date"}, want: []string{"cache", "app", "date"}},
+		{name: "repeated", tmpl: "{app}/{app}.json", want: []string{"app"}},
+		{name: "escaped braces", tmpl: "{home}/{{x}}", want: []string{"home"}},
+		{name: "no placeholders", tmpl: "a/b", want: nil},
+		{name: "unknown", tmpl: "{config}/{user}", wantErr: dt.ErrUnknownPlaceholder},
+		{name: "unclosed", tmpl: "{config/x", wantErr: dt.ErrInvalidPathTemplate},
+		{name: "stray close", tmpl: "a}b", wantErr: dt.ErrInvalidPathTemplate},
+		{name: "empty name", tmpl: "{}/x", wantErr: dt.ErrInvalidPathTemplate},
+		{name: "bad name", tmpl: "{1x}/x", vars: []string{"1x"}, wantErr: dt.ErrInvalidPathTemplate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt, err := dt.ParsePathTemplate(tt.tmpl, tt.vars...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParsePathTemplate(%q) error = %v, want %v", tt.tmpl, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got := pt.Placeholders()
+			if len(got) != len(tt.want) {
+				t.Fatalf("Placeholders() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Placeholders() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPathTemplate_Render(t *testing.T) {
+	date := time.Date(2025, 3, 14, 9, 26, 0, 0, time.UTC)
+	vars := dt.PathVars{
+		"config": dt.DirPath(filepath.FromSlash("/home/alice/.config")),
+		"app":    dt.PathSegment("myapp"),
+		"date":   date,
+		"n":      7,
+		"up":     "..",
+		"sub":    "a/b",
+	}
+	tests := []struct {
+		name    string
+		tmpl    string
+		want    string
+		wantErr error
+	}{
+		{name: "layout", tmpl: "{config}/{app}/logs/{date:2006-01-02}.log", want: "/home/alice/.config/myapp/logs/2025-03-14.log"},
+		{name: "default layout", tmpl: "{app}/{date}.log", want: "myapp/2025-03-14.log"},
+		{name: "integer", tmpl: "{app}/run-{n}", want: "myapp/run-7"},
+		{name: "escaped braces", tmpl: "{app}/{{x}}", want: "myapp/{x}"},
+		{name: "cleaned", tmpl: "{config}//{app}/", want: "/home/alice/.config/myapp"},
+		{name: "missing", tmpl: "{cache}/{app}", wantErr: dt.ErrMissingPathVar},
+		{name: "layout on string", tmpl: "{app:2006}", wantErr: dt.ErrInvalidPathVar},
+		{name: "parent segment", tmpl: "{config}/{up}/x", wantErr: dt.ErrInvalidPathVar},
+		{name: "separator in value", tmpl: "{config}/{sub}.log", wantErr: dt.ErrInvalidPathVar},
+		{name: "separator in layout", tmpl: "{app}/{date:2006/01}.log", want: "myapp/2025/03.log"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt, err := dt.ParsePathTemplate(tt.tmpl, "date", "n", "up", "sub")
+			if err != nil {
+				t.Fatalf("ParsePathTemplate(%q) error = %v", tt.tmpl, err)
+			}
+			got, err := pt.Filepath(vars)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Filepath() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got != dt.Filepath(filepath.FromSlash(tt.want)) {
+				t.Errorf("Filepath() = %q, want %q", got, filepath.FromSlash(tt.want))
+			}
+		})
+	}
+}
+
+func TestPathTemplate_Match(t *testing.T) {
+	config := dt.DirPath(filepath.FromSlash("/home/alice/.config"))
+	pt, err := dt.ParsePathTemplate("{config}/{app}/logs/{date:2006-01-02}.log", "date")
+	if err != nil {
+		t.Fatalf("ParsePathTemplate() error = %v", err)
+	}
+
+	path := dt.EntryPath(filepath.FromSlash("/home/alice/.config/myapp/logs/2025-03-14.log"))
+	vars, err := pt.Match(path, dt.PathVars{"config": config})
+	if err != nil {
+		t.Fatalf("Match(%q) error = %v", path, err)
+	}
+	if vars["app"] != "myapp" {
+		t.Errorf("Match() app = %v, want myapp", vars["app"])
+	}
+	if vars["config"] != config {
+		t.Errorf("Match() config = %v, want %v", vars["config"], config)
+	}
+	if d, ok := vars["date"].(time.Time); !ok || !d.Equal(time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Match() date = %v, want 2025-03-14", vars["date"])
+	}
+
+	// Rendering the matched values gives back the path.
+	got, err := pt.Render(vars)
+	if err != nil || got != path {
+		t.Errorf("Render(Match()) = %q, %v; want %q", got, err, path)
+	}
+
+	mismatches := []string{
+		"/home/bob/.config/myapp/logs/2025-03-14.log",
+		"/home/alice/.config/my/app/logs/2025-03-14.log",
+		"/home/alice/.config/myapp/logs/yesterday.log",
+		"/home/alice/.config/myapp/logs/2025-03-14.txt",
+	}
+	for _, m := range mismatches {
+		_, err = pt.Match(dt.EntryPath(filepath.FromSlash(m)), dt.PathVars{"config": config})
+		if !errors.Is(err, dt.ErrPathTemplateMismatch) {
+			t.Errorf("Match(%q) error = %v, want ErrPathTemplateMismatch", m, err)
+		}
+	}
+
+	repeated, err := dt.ParsePathTemplate("{app}/{app}.json")
+	if err != nil {
+		t.Fatalf("ParsePathTemplate() error = %v", err)
+	}
+	if _, err = repeated.Match("a/a.json", nil); err != nil {
+		t.Errorf("Match(a/a.json) error = %v", err)
+	}
+	if _, err = repeated.Match("a/b.json", nil); !errors.Is(err, dt.ErrPathTemplateMismatch) {
+		t.Errorf("Match(a/b.json) error = %v, want ErrPathTemplateMismatch", err)
+	}
+	dated, err := dt.ParsePathTemplate("{date:2006-01-02}/{date:2006-01-02}.log", "date")
+	if err != nil {
+		t.Fatalf("ParsePathTemplate() error = %v", err)
+	}
+	path = dt.EntryPath(filepath.FromSlash("2025-03-14/2025-03-14.log"))
+	vars, err = dated.Match(path, nil)
+	if err != nil {
+		t.Fatalf("Match(%q) error = %v", path, err)
+	}
+	if d, ok := vars["date"].(time.Time); !ok || !d.Equal(time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Match() date = %v, want 2025-03-14", vars["date"])
+	}
+	path = dt.EntryPath(filepath.FromSlash("2025-03-14/2025-03-15.log"))
+	if _, err = dated.Match(path, nil); !errors.Is(err, dt.ErrPathTemplateMismatch) {
+		t.Errorf("Match(%q) error = %v, want ErrPathTemplateMismatch", path, err)
+	}
+}
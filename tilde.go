@@ -7,16 +7,16 @@ import (
 	"strings"
 )
 
-func DirPathToTilde(path DirPath, option TildeOption) (tdp TildeDirPath) {
-	return ToTilde[DirPath, TildeDirPath](path, option)
+func DirPathToTilde(path DirPath, option TildeOption, hdps ...HomeDirProvider) (tdp TildeDirPath) {
+	return ToTilde[DirPath, TildeDirPath](path, option, hdps...)
 }
 
-func FilepathToTilde(path Filepath, option TildeOption) (tdp TildeFilepath) {
-	return ToTilde[Filepath, TildeFilepath](path, option)
+func FilepathToTilde(path Filepath, option TildeOption, hdps ...HomeDirProvider) (tdp TildeFilepath) {
+	return ToTilde[Filepath, TildeFilepath](path, option, hdps...)
 }
 
-func EntryPathToTilde(path EntryPath, option TildeOption) (tdp TildeEntryPath) {
-	return ToTilde[EntryPath, TildeEntryPath](path, option)
+func EntryPathToTilde(path EntryPath, option TildeOption, hdps ...HomeDirProvider) (tdp TildeEntryPath) {
+	return ToTilde[EntryPath, TildeEntryPath](path, option, hdps...)
 }
 
 type tildable interface {
@@ -51,7 +51,10 @@ const (
 	OrPanic                TildeOption = 3
 )
 
-func ToTilde[P tildable, TP tilde](path P, option TildeOption) (tp TP) {
+// ToTilde replaces the current user's home directory at the start of path
+// with "~". The home directory comes from the HomeDirProvider when one is
+// passed, and otherwise from GetUserHomeDir.
+func ToTilde[P tildable, TP tilde](path P, option TildeOption, hdps ...HomeDirProvider) (tp TP) {
 	var rel string
 	var err error
 	var homeDir DirPath
@@ -80,8 +83,14 @@ func ToTilde[P tildable, TP tilde](path P, option TildeOption) (tp TP) {
 		goto end
 	}
 
-	homeDir = GetUserHomeDir()
-	rel, err = filepath.Rel(string(homeDir), string(path))
+	if len(hdps) > 0 && hdps[0] != nil {
+		homeDir, err = hdps[0].HomeDir()
+	} else {
+		homeDir = GetUserHomeDir()
+	}
+	if err == nil {
+		rel, err = filepath.Rel(string(homeDir), string(path))
+	}
 
 	switch {
 	case err != nil:
@@ -13,8 +13,8 @@ end:
 	return tdp, err
 }
 
-func (tdp TildeDirPath) Expand() (_ DirPath, err error) {
+func (tdp TildeDirPath) Expand(opts ...ExpandOptions) (_ DirPath, err error) {
 	var ep EntryPath
-	ep, err = EntryPath(tdp).Expand()
+	ep, err = EntryPath(tdp).Expand(opts...)
 	return DirPath(ep), err
 }
@@ -1,13 +1,17 @@
 package dt
 
 import (
+	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 )
 
 type TildeEntryPath string
 
 func ParseTildeEntryPath(s string) (tdp TildeEntryPath, err error) {
+	var username string
+	var found bool
 
 	if len(s) == 0 {
 		err = ErrEmpty
@@ -24,13 +28,23 @@ func ParseTildeEntryPath(s string) (tdp TildeEntryPath, err error) {
 	}
 
 	if runtime.GOOS == "windows" {
-		if s[1] != '/' && s[1] != '\\' {
-			err = ErrNotTildePath
-			goto end
-		}
 		s = filepath.FromSlash(s)
-	} else {
-		if s[1] != '/' {
+	}
+	// Either "~/...", "~user" or "~user/..."
+	if strings.IndexByte(s[1:], os.PathSeparator) == 0 {
+		tdp = TildeEntryPath(s)
+		goto end
+	}
+	username, _, found = strings.Cut(s[1:], string(os.PathSeparator))
+	if !isValidUsername(username) {
+		err = ErrNotTildePath
+		goto end
+	}
+	if !found {
+		// A bare "~name" may just be a file name, so it is only a ~user path
+		// when the user exists
+		_, err = GetHomeDirProvider().UserHomeDir(username)
+		if err != nil {
 			err = ErrNotTildePath
 			goto end
 		}
@@ -44,8 +58,23 @@ end:
 	return tdp, err
 }
 
-func (tdp TildeEntryPath) Expand() (dp EntryPath, err error) {
+func (tdp TildeEntryPath) Expand(opts ...ExpandOptions) (dp EntryPath, err error) {
 	var ep EntryPath
-	ep, err = EntryPath(tdp).Expand()
+	ep, err = EntryPath(tdp).Expand(opts...)
 	return ep, err
 }
+
+// isValidUsername reports whether name can follow "~" as an account name.
+func isValidUsername(name string) (valid bool) {
+	if name == "" {
+		goto end
+	}
+	for _, r := range name {
+		if r <= ' ' || strings.ContainsRune(`~$%:*?"<>|/\\`, r) {
+			goto end
+		}
+	}
+	valid = true
+end:
+	return valid
+}
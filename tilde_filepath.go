@@ -13,8 +13,8 @@ end:
 	return tdp, err
 }
 
-func (tdp TildeFilepath) Expand() (_ Filepath, err error) {
+func (tdp TildeFilepath) Expand(opts ...ExpandOptions) (_ Filepath, err error) {
 	var ep EntryPath
-	ep, err = EntryPath(tdp).Expand()
+	ep, err = EntryPath(tdp).Expand(opts...)
 	return Filepath(ep), err
 }
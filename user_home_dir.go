@@ -1,15 +1,132 @@
 package dt
 
+import (
+	"errors"
+	"os"
+	"os/user"
+	"sync"
+)
+
 var userHomeDir DirPath
 
 func EnsureUserHomeDir() (err error) {
-	userHomeDir, err = UserHomeDir()
+	var dp DirPath
+
+	dp, err = UserHomeDir()
+	homeDirProviderMu.Lock()
+	userHomeDir = dp
+	homeDirProviderMu.Unlock()
 	return err
 }
 
 func GetUserHomeDir() DirPath {
-	if userHomeDir == "" {
+	homeDirProviderMu.RLock()
+	dp := userHomeDir
+	homeDirProviderMu.RUnlock()
+	if dp == "" {
 		panic("Must call dt.EnsureUserHomeDir() before dt.GetUserHomeDir() can be called")
 	}
-	return userHomeDir
+	return dp
+}
+
+// HomeDirProvider resolves home directories for "~" and "~user" expansion and
+// for ToTilde. Tests and daemons acting for other accounts can supply their
+// own, either package-wide with SetHomeDirProvider or per call.
+type HomeDirProvider interface {
+	// HomeDir returns the current user's home directory.
+	HomeDir() (DirPath, error)
+
+	// UserHomeDir returns the home directory of the named user, failing with
+	// ErrUnknownUser when there is no such user.
+	UserHomeDir(username string) (DirPath, error)
+}
+
+// OSHomeDirProvider is the default HomeDirProvider, backed by os.UserHomeDir
+// and os/user.Lookup.
+type OSHomeDirProvider struct{}
+
+func (OSHomeDirProvider) HomeDir() (DirPath, error) {
+	dp, err := os.UserHomeDir()
+	return DirPath(dp), err
+}
+
+func (OSHomeDirProvider) UserHomeDir(username string) (dp DirPath, err error) {
+	var u *user.User
+
+	u, err = user.Lookup(username)
+	if errors.As(err, new(user.UnknownUserError)) {
+		err = NewErr(ErrUnknownUser, "username", username, err)
+	}
+	if err != nil {
+		goto end
+	}
+	dp = DirPath(u.HomeDir)
+end:
+	return dp, err
+}
+
+// StaticHomeDirs is a HomeDirProvider with fixed answers, for deterministic
+// tests.
+type StaticHomeDirs struct {
+	Current DirPath
+	Users   map[string]DirPath
+}
+
+func (s StaticHomeDirs) HomeDir() (dp DirPath, err error) {
+	dp = s.Current
+	if dp == "" {
+		err = NewErr(ErrUnknownUser)
+	}
+	return dp, err
+}
+
+func (s StaticHomeDirs) UserHomeDir(username string) (dp DirPath, err error) {
+	var ok bool
+
+	dp, ok = s.Users[username]
+	if !ok {
+		err = NewErr(ErrUnknownUser, "username", username)
+	}
+	return dp, err
+}
+
+// homeDirProviderMu guards homeDirProvider and the userHomeDir cache.
+var (
+	homeDirProviderMu sync.RWMutex
+	homeDirProvider   HomeDirProvider = OSHomeDirProvider{}
+)
+
+// SetHomeDirProvider replaces the package-wide HomeDirProvider and returns the
+// previous one; nil restores OSHomeDirProvider. The cached directory used by
+// GetUserHomeDir is refreshed from the new provider, or cleared when the
+// provider cannot resolve it, so GetUserHomeDir panics until
+// EnsureUserHomeDir succeeds.
+func SetHomeDirProvider(p HomeDirProvider) (prev HomeDirProvider) {
+	if p == nil {
+		p = OSHomeDirProvider{}
+	}
+	dp, err := p.HomeDir()
+	if err != nil {
+		dp = ""
+	}
+	homeDirProviderMu.Lock()
+	prev, homeDirProvider = homeDirProvider, p
+	userHomeDir = dp
+	homeDirProviderMu.Unlock()
+	return prev
+}
+
+// GetHomeDirProvider returns the package-wide HomeDirProvider.
+func GetHomeDirProvider() HomeDirProvider {
+	homeDirProviderMu.RLock()
+	defer homeDirProviderMu.RUnlock()
+	return homeDirProvider
+}
+
+// pickHomeDirProvider returns the first of hdps, or the package-wide provider.
+func pickHomeDirProvider(hdps []HomeDirProvider) HomeDirProvider {
+	if len(hdps) > 0 && hdps[0] != nil {
+		return hdps[0]
+	}
+	return GetHomeDirProvider()
 }
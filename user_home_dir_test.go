@@ -0,0 +1,110 @@
+package dt_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+func TestHomeDirProvider(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses Unix absolute paths")
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd() error = %v", err)
+	}
+	homes := dt.StaticHomeDirs{
+		Current: "/home/me",
+		Users:   map[string]dt.DirPath{"alice": "/home/alice"},
+	}
+	opts := dt.ExpandOptions{HomeDirProvider: homes}
+
+	t.Run("per-call Expand", func(t *testing.T) {
+		tests := map[dt.EntryPath]dt.EntryPath{
+			"~":             "/home/me",
+			"~/notes":       "/home/me/notes",
+			"~alice":        "/home/alice",
+			"~alice/a/../b": "/home/alice/b",
+			"~nobody/x":     dt.EntryPath(filepath.Join(wd, "~nobody/x")),
+		}
+		for in, want := range tests {
+			got, err := in.Expand(opts)
+			if err != nil || got != want {
+				t.Errorf("Expand(%q) = %q, %v; want %q", in, got, err, want)
+			}
+		}
+	})
+
+	t.Run("ParseTildeDirPath accepts ~user", func(t *testing.T) {
+		tdp, err := dt.ParseTildeDirPath("~alice/projects")
+		if err != nil {
+			t.Fatalf("ParseTildeDirPath() error = %v", err)
+		}
+		got, err := tdp.Expand(opts)
+		if err != nil || got != "/home/alice/projects" {
+			t.Errorf("Expand() = %q, %v; want %q", got, err, "/home/alice/projects")
+		}
+	})
+
+	t.Run("ParseTildeEntryPath accepts bare ~user", func(t *testing.T) {
+		prev := dt.SetHomeDirProvider(homes)
+		defer func() {
+			dt.SetHomeDirProvider(prev)
+		}()
+		tep, err := dt.ParseTildeEntryPath("~alice")
+		if err != nil {
+			t.Fatalf("ParseTildeEntryPath() error = %v", err)
+		}
+		got, err := tep.Expand(opts)
+		if err != nil || got != "/home/alice" {
+			t.Errorf("Expand() = %q, %v; want %q", got, err, "/home/alice")
+		}
+		for _, in := range []string{"~nobody", "~al:ice"} {
+			if _, err = dt.ParseTildeEntryPath(in); !errors.Is(err, dt.ErrNotTildePath) {
+				t.Errorf("ParseTildeEntryPath(%q) error = %v, want %v", in, err, dt.ErrNotTildePath)
+			}
+		}
+	})
+
+	t.Run("per-call ToTilde", func(t *testing.T) {
+		got := dt.DirPath("/home/me/src").ToTilde(dt.OrFullPath, homes)
+		if got != "~/src" {
+			t.Errorf("ToTilde() = %q, want %q", got, "~/src")
+		}
+		got = dt.DirPath("/srv/data").ToTilde(dt.OrFullPath, homes)
+		if got != "/srv/data" {
+			t.Errorf("ToTilde() = %q, want %q", got, "/srv/data")
+		}
+	})
+
+	t.Run("package-wide provider", func(t *testing.T) {
+		prev := dt.SetHomeDirProvider(homes)
+		defer func() {
+			dt.SetHomeDirProvider(prev)
+		}()
+		got, err := dt.EntryPath("~/x").Expand()
+		if err != nil || got != "/home/me/x" {
+			t.Errorf("Expand() = %q, %v; want %q", got, err, "/home/me/x")
+		}
+		if tp := dt.Filepath("/home/me/x.txt").ToTilde(dt.OrPanic); tp != "~/x.txt" {
+			t.Errorf("ToTilde() = %q, want %q", tp, "~/x.txt")
+		}
+	})
+	t.Run("failing provider clears cache", func(t *testing.T) {
+		prev := dt.SetHomeDirProvider(dt.StaticHomeDirs{})
+		defer func() {
+			dt.SetHomeDirProvider(prev)
+		}()
+		defer func() {
+			if recover() == nil {
+				t.Error("GetUserHomeDir() did not panic after the provider failed")
+			}
+		}()
+		dt.GetUserHomeDir()
+	})
+}
@@ -0,0 +1,219 @@
+package dt
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// WritableFile is a file opened for writing through a WritableFS.
+type WritableFile interface {
+	fs.File
+	io.Writer
+}
+
+// WritableFS is an fs.FS that can also be mutated. It lets code written against
+// dt types target filesystems other than the OS, e.g. an in-memory filesystem
+// in tests.
+//
+// Names follow the fs.FS conventions: they are slash-separated, unrooted paths
+// that satisfy fs.ValidPath. Errors should be *fs.PathError values wrapping
+// the fs.Err* sentinels so that errors.Is works the same as it does for os.
+type WritableFS interface {
+	fs.FS
+	OpenFile(name string, flag int, perm fs.FileMode) (WritableFile, error)
+	Mkdir(name string, perm fs.FileMode) error
+	Remove(name string) error
+	Rename(oldname, newname string) error
+	Chmod(name string, mode fs.FileMode) error
+	Chtimes(name string, atime time.Time, mtime time.Time) error
+	Symlink(oldname, newname string) error
+}
+
+// WritableDirFS returns a WritableFS for the OS filesystem rooted at dp, the
+// writable counterpart of DirFS.
+//
+// Like os.DirFS it is not a security boundary: symlinks inside dp may point
+// outside of it. Use DirPath.OpenRoot and DirRoot.WritableFS when names come
+// from an untrusted source.
+func WritableDirFS(dp DirPath) WritableFS {
+	return osWritableFS{
+		osDirFS: os.DirFS(string(dp)).(osDirFS),
+		root:    string(dp),
+	}
+}
+
+// WritableFS returns a WritableFS for the OS filesystem rooted at dp.
+func (dp DirPath) WritableFS() WritableFS {
+	return WritableDirFS(dp)
+}
+
+var _ WritableFS = osWritableFS{}
+
+// osDirFS is the set of optional fs interfaces implemented by os.DirFS.
+type osDirFS interface {
+	fs.StatFS
+	fs.ReadDirFS
+	fs.ReadFileFS
+	fs.ReadLinkFS
+}
+
+// osWritableFS implements WritableFS with the os package.
+type osWritableFS struct {
+	osDirFS
+	root string
+}
+
+// osPath validates name and converts it into an OS path under fsys.root.
+func (fsys osWritableFS) osPath(op, name string) (_ string, err error) {
+	if !fs.ValidPath(name) {
+		err = &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+		goto end
+	}
+	name = filepath.Join(fsys.root, filepath.FromSlash(name))
+end:
+	return name, err
+}
+
+func (fsys osWritableFS) OpenFile(name string, flag int, perm fs.FileMode) (wf WritableFile, err error) {
+	var f *os.File
+	name, err = fsys.osPath("open", name)
+	if err != nil {
+		goto end
+	}
+	f, err = os.OpenFile(name, flag, perm)
+	if err != nil {
+		goto end
+	}
+	wf = f
+end:
+	return wf, err
+}
+
+func (fsys osWritableFS) Mkdir(name string, perm fs.FileMode) (err error) {
+	name, err = fsys.osPath("mkdir", name)
+	if err != nil {
+		goto end
+	}
+	err = os.Mkdir(name, perm)
+end:
+	return err
+}
+
+func (fsys osWritableFS) Remove(name string) (err error) {
+	name, err = fsys.osPath("remove", name)
+	if err != nil {
+		goto end
+	}
+	err = os.Remove(name)
+end:
+	return err
+}
+
+func (fsys osWritableFS) Rename(oldname, newname string) (err error) {
+	oldname, err = fsys.osPath("rename", oldname)
+	if err != nil {
+		goto end
+	}
+	newname, err = fsys.osPath("rename", newname)
+	if err != nil {
+		goto end
+	}
+	err = os.Rename(oldname, newname)
+end:
+	return err
+}
+
+func (fsys osWritableFS) Chmod(name string, mode fs.FileMode) (err error) {
+	name, err = fsys.osPath("chmod", name)
+	if err != nil {
+		goto end
+	}
+	err = os.Chmod(name, mode)
+end:
+	return err
+}
+
+func (fsys osWritableFS) Chtimes(name string, atime time.Time, mtime time.Time) (err error) {
+	name, err = fsys.osPath("chtimes", name)
+	if err != nil {
+		goto end
+	}
+	err = os.Chtimes(name, atime, mtime)
+end:
+	return err
+}
+
+// Symlink creates newname as a symbolic link to oldname. As with os.Symlink,
+// oldname is stored as given (after conversion from slash form) and is
+// interpreted relative to the directory containing newname.
+func (fsys osWritableFS) Symlink(oldname, newname string) (err error) {
+	newname, err = fsys.osPath("symlink", newname)
+	if err != nil {
+		goto end
+	}
+	err = os.Symlink(filepath.FromSlash(oldname), newname)
+end:
+	return err
+}
+
+// fsName converts a dt path into the slash-separated form used for names in
+// an fs.FS.
+func fsName[T ~string](p T) string {
+	return filepath.ToSlash(string(p))
+}
+
+// MkdirAllFS creates the directory name in wfs along with any missing parents,
+// like os.MkdirAll. It works with any WritableFS using only Mkdir and Stat.
+func MkdirAllFS(wfs WritableFS, name string, perm fs.FileMode) (err error) {
+	var info fs.FileInfo
+	var parent string
+
+	info, err = fs.Stat(wfs, name)
+	if err == nil {
+		if !info.IsDir() {
+			err = &fs.PathError{Op: "mkdir", Path: name, Err: syscall.ENOTDIR}
+		}
+		goto end
+	}
+
+	parent = path.Dir(name)
+	if parent != name && parent != "." {
+		err = MkdirAllFS(wfs, parent, perm)
+		if err != nil {
+			goto end
+		}
+	}
+
+	err = wfs.Mkdir(name, perm)
+	if err == nil {
+		goto end
+	}
+
+	// Handle a race where another writer created name after our Stat.
+	info, _ = fs.Stat(wfs, name)
+	if info != nil && info.IsDir() {
+		err = nil
+	}
+end:
+	return err
+}
+
+// WriteFileFS writes data to name in wfs, creating it if necessary, like
+// os.WriteFile.
+func WriteFileFS(wfs WritableFS, name string, data []byte, perm fs.FileMode) (err error) {
+	var f WritableFile
+
+	f, err = wfs.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		goto end
+	}
+	_, err = f.Write(data)
+	err = CombineErrs([]error{err, f.Close()})
+end:
+	return err
+}
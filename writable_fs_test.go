@@ -0,0 +1,158 @@
+package dt_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mikeschinkel/go-dt"
+)
+
+func TestWritableDirFS(t *testing.T) {
+	root := dt.DirPath(t.TempDir())
+	wfs := root.WritableFS()
+
+	t.Run("MkdirAllFS and WriteFileFS", func(t *testing.T) {
+		dir := dt.DirPath("a/b/c")
+		if err := dir.MkdirAllFS(wfs, 0o755); err != nil {
+			t.Fatalf("MkdirAllFS() error = %v", err)
+		}
+		// A second call is a no-op.
+		if err := dir.MkdirAllFS(wfs, 0o755); err != nil {
+			t.Fatalf("MkdirAllFS() second call error = %v", err)
+		}
+		fp := dt.FilepathJoin(dir, "file.txt")
+		if err := fp.WriteFileFS(wfs, []byte("hello"), 0o644); err != nil {
+			t.Fatalf("WriteFileFS() error = %v", err)
+		}
+		got, err := dt.FilepathJoin(root, fp).ReadFile()
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		if string(got) != "hello" {
+			t.Errorf("ReadFile() = %q, want %q", got, "hello")
+		}
+		got, err = fp.ReadFile(wfs)
+		if err != nil || string(got) != "hello" {
+			t.Errorf("ReadFile(wfs) = %q, %v; want %q", got, err, "hello")
+		}
+	})
+
+	t.Run("MkdirAllFS over a file", func(t *testing.T) {
+		if err := dt.Filepath("plain").WriteFileFS(wfs, nil, 0o644); err != nil {
+			t.Fatalf("WriteFileFS() error = %v", err)
+		}
+		if err := dt.DirPath("plain/sub").MkdirAllFS(wfs, 0o755); err == nil {
+			t.Error("MkdirAllFS() under a file error = nil, want error")
+		}
+	})
+
+	t.Run("EnsureExistsFS", func(t *testing.T) {
+		if err := dt.EnsureUserHomeDir(); err != nil {
+			t.Fatalf("EnsureUserHomeDir() error = %v", err)
+		}
+		if err := dt.DirPath("ensure/me").EnsureExistsFS(wfs, 0o755); err != nil {
+			t.Fatalf("EnsureExistsFS() error = %v", err)
+		}
+		if !dt.EntryPath(dt.DirPathJoin(root, "ensure/me")).IsDir() {
+			t.Error("EnsureExistsFS() did not create the directory")
+		}
+		err := dt.DirPath("plain").EnsureExistsFS(wfs, 0o755)
+		if !errors.Is(err, dt.ErrPathIsFile) {
+			t.Errorf("EnsureExistsFS() on a file error = %v, want %v", err, dt.ErrPathIsFile)
+		}
+	})
+
+	t.Run("CopyToFS", func(t *testing.T) {
+		src := dt.Filepath("a/b/c/file.txt")
+		dest := dt.Filepath("copy/dest.txt")
+		if err := src.CopyToFS(wfs, dest, nil); err != nil {
+			t.Fatalf("CopyToFS() error = %v", err)
+		}
+		got, err := dest.ReadFile(wfs)
+		if err != nil || string(got) != "hello" {
+			t.Errorf("ReadFile(wfs) = %q, %v; want %q", got, err, "hello")
+		}
+		err = src.CopyToFS(wfs, dest, nil)
+		if !errors.Is(err, os.ErrExist) {
+			t.Errorf("CopyToFS() without Overwrite error = %v, want %v", err, os.ErrExist)
+		}
+		err = src.CopyToFS(wfs, dest, &dt.CopyOptions{
+			Overwrite:    true,
+			DestModeFunc: func(dt.EntryPath) os.FileMode { return 0o600 },
+		})
+		if err != nil {
+			t.Fatalf("CopyToFS() with Overwrite error = %v", err)
+		}
+	})
+
+	t.Run("RenameFS and RemoveFS", func(t *testing.T) {
+		fp := dt.Filepath("copy/dest.txt")
+		renamed := dt.Filepath("copy/renamed.txt")
+		if err := fp.RenameFS(wfs, renamed); err != nil {
+			t.Fatalf("RenameFS() error = %v", err)
+		}
+		if _, err := fp.Stat(wfs); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Stat() after rename error = %v, want %v", err, fs.ErrNotExist)
+		}
+		if err := renamed.RemoveFS(wfs); err != nil {
+			t.Fatalf("RemoveFS() error = %v", err)
+		}
+		if err := dt.DirPath("copy").RemoveFS(wfs); err != nil {
+			t.Fatalf("DirPath.RemoveFS() error = %v", err)
+		}
+	})
+
+	t.Run("Chtimes and Chmod", func(t *testing.T) {
+		fp := dt.Filepath("a/b/c/file.txt")
+		mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		if err := wfs.Chtimes(string(fp), mtime, mtime); err != nil {
+			t.Fatalf("Chtimes() error = %v", err)
+		}
+		if err := fp.ChmodFS(wfs, 0o600); err != nil {
+			t.Fatalf("ChmodFS() error = %v", err)
+		}
+		info, err := fp.Stat(wfs)
+		if err != nil {
+			t.Fatalf("Stat() error = %v", err)
+		}
+		if !info.ModTime().Equal(mtime) {
+			t.Errorf("ModTime() = %v, want %v", info.ModTime(), mtime)
+		}
+		if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+			t.Errorf("Mode().Perm() = %v, want %v", info.Mode().Perm(), os.FileMode(0o600))
+		}
+	})
+
+	t.Run("Symlink", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("symlinks require privileges on Windows")
+		}
+		if err := wfs.Symlink("a/b/c/file.txt", "link.txt"); err != nil {
+			t.Fatalf("Symlink() error = %v", err)
+		}
+		got, err := dt.Filepath("link.txt").ReadFile(wfs)
+		if err != nil || string(got) != "hello" {
+			t.Errorf("ReadFile(link) = %q, %v; want %q", got, err, "hello")
+		}
+		info, err := dt.EntryPath("link.txt").Lstat(wfs)
+		if err != nil {
+			t.Fatalf("Lstat() error = %v", err)
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			t.Errorf("Lstat() mode = %v, want symlink", info.Mode())
+		}
+	})
+
+	t.Run("invalid names", func(t *testing.T) {
+		for _, name := range []string{"/abs", "../escape", "a/../b", ""} {
+			err := wfs.Mkdir(name, 0o755)
+			if !errors.Is(err, fs.ErrInvalid) {
+				t.Errorf("Mkdir(%q) error = %v, want %v", name, err, fs.ErrInvalid)
+			}
+		}
+	})
+}